@@ -19,12 +19,23 @@ type API struct {
 	Root   *fiber.App
 	Store  *store.Store
 
-	APIRoot      fiber.Router
-	Users        fiber.Router
-	DeviceModels fiber.Router
-	Devices      fiber.Router
-	LabData      fiber.Router
-	RawData      fiber.Router
+	APIRoot           fiber.Router
+	Users             fiber.Router
+	DeviceModels      fiber.Router
+	Devices           fiber.Router
+	LabData           fiber.Router
+	RawData           fiber.Router
+	TestCodeMappings  fiber.Router
+	ReferenceRanges   fiber.Router
+	Units             fiber.Router
+	UnitConversions   fiber.Router
+	CanonicalUnits    fiber.Router
+	VerificationRules fiber.Router
+	CriticalAlerts    fiber.Router
+	Orders            fiber.Router
+	Events            fiber.Router
+
+	events *eventHub
 }
 
 // ApiRV is the API response value.
@@ -36,6 +47,7 @@ func NewAPI(logger *log.Logger, router *fiber.App, store *store.Store) (*API, er
 		Logger: logger,
 		Root:   router,
 		Store:  store,
+		events: newEventHub(logger, store),
 	}
 
 	api.Root.Use(func(c *fiber.Ctx) error {
@@ -50,12 +62,26 @@ func NewAPI(logger *log.Logger, router *fiber.App, store *store.Store) (*API, er
 	api.initDeviceAPI()
 	api.initLabDataAPI()
 	api.initRawDataAPI()
+	api.initTestCodeMappingAPI()
+	api.initReferenceRangeAPI()
+	api.initUnitAPI()
+	api.initUnitConversionAPI()
+	api.initCanonicalUnitAPI()
+	api.initVerificationRuleAPI()
+	api.initCriticalAlertAPI()
+	api.initOrderAPI()
+	api.initEventAPI()
 
 	api.addNoRoute()
 
 	return api, nil
 }
 
+// Close closes the event streams, they would otherwise keep the server from shutting down.
+func (api *API) Close() {
+	api.events.close()
+}
+
 // getApiFromContext gets the API from the context.
 func getApiFromContext(c *fiber.Ctx) (*API, error) {
 	api, ok := c.Locals("api").(*API)
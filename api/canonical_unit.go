@@ -0,0 +1,190 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// canonicalUnitAPIPath is the path for the canonical unit API.
+const canonicalUnitAPIPath = "/canonical_units"
+
+// initCanonicalUnitAPI initializes the canonical unit API.
+func (api *API) initCanonicalUnitAPI() {
+	api.CanonicalUnits = api.APIRoot.Group(canonicalUnitAPIPath)
+
+	api.CanonicalUnits.Use(isAuthorized)
+
+	api.CanonicalUnits.Get("/", getCanonicalUnits)
+	api.CanonicalUnits.Get("/:id", getCanonicalUnit)
+	api.CanonicalUnits.Get("/param/:param", getCanonicalUnitsByParam)
+	api.CanonicalUnits.Post("/", createCanonicalUnit)
+	api.CanonicalUnits.Put("/:id", updateCanonicalUnit)
+	api.CanonicalUnits.Delete("/:id", deleteCanonicalUnit)
+}
+
+// getCanonicalUnits gets all canonical units.
+func getCanonicalUnits(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	canonicalUnits, page, err := api.Store.CanonicalUnitStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get canonical units")
+		return apiResponseListError(c, err, "failed to get canonical units")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("canonical_units", canonicalUnits, page))
+}
+
+// getCanonicalUnit gets a canonical unit by ID.
+func getCanonicalUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	canonicalUnit, err := api.Store.CanonicalUnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the canonical unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("canonical_unit", canonicalUnit))
+}
+
+// getCanonicalUnitsByParam gets the canonical units of a HIS test code.
+func getCanonicalUnitsByParam(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	param := c.Params("param")
+	if param == "" {
+		return apiResponseError(c, fiber.StatusBadRequest, "param is required")
+	}
+
+	canonicalUnits, err := api.Store.CanonicalUnitStore.GetByParams([]string{param})
+	if err != nil {
+		api.Logger.Err(err, "failed to get the canonical units by param")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the canonical units by param")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("canonical_units", canonicalUnits))
+}
+
+// createCanonicalUnit creates a new canonical unit.
+func createCanonicalUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	canonicalUnit := &model.CanonicalUnit{}
+	if err := c.BodyParser(canonicalUnit); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateCanonicalUnit(canonicalUnit); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.CanonicalUnitStore.Create(canonicalUnit); err != nil {
+		api.Logger.Err(err, "failed to create the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the canonical unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", canonicalUnit.ID))
+}
+
+// updateCanonicalUnit updates a canonical unit.
+func updateCanonicalUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	canonicalUnit, err := api.Store.CanonicalUnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the canonical unit")
+	}
+
+	if err := c.BodyParser(canonicalUnit); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateCanonicalUnit(canonicalUnit); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.CanonicalUnitStore.Update(canonicalUnit); err != nil {
+		api.Logger.Err(err, "failed to update the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the canonical unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", canonicalUnit.ID))
+}
+
+// deleteCanonicalUnit deletes a canonical unit.
+func deleteCanonicalUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	canonicalUnit, err := api.Store.CanonicalUnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the canonical unit")
+	}
+
+	if err := api.Store.CanonicalUnitStore.Delete(canonicalUnit); err != nil {
+		api.Logger.Err(err, "failed to delete the canonical unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the canonical unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", canonicalUnit.ID))
+}
+
+// validateCanonicalUnit validates the canonical unit and returns the message of the first problem.
+func validateCanonicalUnit(canonicalUnit *model.CanonicalUnit) string {
+	if canonicalUnit.Param == "" || canonicalUnit.Unit == "" {
+		return "param and unit are required"
+	}
+
+	return ""
+}
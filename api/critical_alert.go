@@ -0,0 +1,124 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// criticalAlertAPIPath is the path for the critical alert API.
+const criticalAlertAPIPath = "/critical_alerts"
+
+// initCriticalAlertAPI initializes the critical alert API.
+func (api *API) initCriticalAlertAPI() {
+	api.CriticalAlerts = api.APIRoot.Group(criticalAlertAPIPath)
+
+	api.CriticalAlerts.Use(isAuthorized)
+
+	api.CriticalAlerts.Get("/", getCriticalAlerts)
+	api.CriticalAlerts.Get("/unacknowledged", getUnacknowledgedCriticalAlerts)
+	api.CriticalAlerts.Get("/:id", getCriticalAlert)
+	api.CriticalAlerts.Put("/:id/acknowledge", acknowledgeCriticalAlert)
+}
+
+// getCriticalAlerts gets all critical alerts.
+func getCriticalAlerts(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	alerts, page, err := api.Store.CriticalAlertStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get critical alerts")
+		return apiResponseListError(c, err, "failed to get critical alerts")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("critical_alerts", alerts, page))
+}
+
+// getUnacknowledgedCriticalAlerts gets the critical alerts which are not acknowledged yet.
+func getUnacknowledgedCriticalAlerts(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	alerts, page, err := api.Store.CriticalAlertStore.GetUnacknowledged(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get unacknowledged critical alerts")
+		return apiResponseListError(c, err, "failed to get unacknowledged critical alerts")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("critical_alerts", alerts, page))
+}
+
+// getCriticalAlert gets a critical alert by ID.
+func getCriticalAlert(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	alert, err := api.Store.CriticalAlertStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the critical alert")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the critical alert")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("critical_alert", alert))
+}
+
+// acknowledgeCriticalAlert marks a critical alert acknowledged by the current user.
+func acknowledgeCriticalAlert(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	alert, err := api.Store.CriticalAlertStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the critical alert")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the critical alert")
+	}
+
+	if alert.AcknowledgedAt != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, "critical alert is already acknowledged")
+	}
+
+	username, _ := c.Locals("username").(string)
+	alert.Acknowledge(username, time.Now())
+
+	if err := api.Store.CriticalAlertStore.Update(alert); err != nil {
+		api.Logger.Err(err, "failed to acknowledge the critical alert")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to acknowledge the critical alert")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", alert.ID))
+}
@@ -15,7 +15,9 @@ func (api *API) initDeviceAPI() {
 	api.Devices.Use(isAuthorized)
 
 	api.Devices.Get("/", getDevices)
+	api.Devices.Get("/status", getDeviceStatusSummary)
 	api.Devices.Get("/:id", getDevice)
+	api.Devices.Get("/:id/status", getDeviceStatus)
 	api.Devices.Post("/", createDevice)
 	api.Devices.Put("/:id", updateDevice)
 	api.Devices.Delete("/:id", deleteDevice)
@@ -29,13 +31,18 @@ func getDevices(c *fiber.Ctx) error {
 		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
 	}
 
-	devices, err := api.Store.DeviceStore.GetAll()
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	devices, page, err := api.Store.DeviceStore.List(query)
 	if err != nil {
 		api.Logger.Err(err, "failed to get devices")
-		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get devices")
+		return apiResponseListError(c, err, "failed to get devices")
 	}
 
-	return apiResponseData(c, fiber.StatusOK, NewAPIRV("devices", devices))
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("devices", devices, page))
 }
 
 // getDevice gets a device by ID.
@@ -61,6 +68,59 @@ func getDevice(c *fiber.Ctx) error {
 	return apiResponseData(c, fiber.StatusOK, NewAPIRV("device", device))
 }
 
+// getDeviceStatusSummary gets the connection states of all the devices with their counts.
+func getDeviceStatusSummary(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	devices, err := api.Store.DeviceStore.GetAll()
+	if err != nil {
+		api.Logger.Err(err, "failed to get devices")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get devices")
+	}
+
+	statuses, err := api.Store.DeviceStatusStore.GetAll()
+	if err != nil {
+		api.Logger.Err(err, "failed to get the device statuses")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the device statuses")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("summary", model.NewDeviceStatusSummary(devices, statuses)))
+}
+
+// getDeviceStatus gets the connection state of a device by ID.
+func getDeviceStatus(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	device, err := api.Store.DeviceStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the device")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the device")
+	}
+
+	status, err := api.Store.DeviceStatusStore.GetByDeviceID(device.ID)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the device status")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the device status")
+	}
+	status.DeviceName = device.Name
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("status", status))
+}
+
 // createDevice creates a new device.
 func createDevice(c *fiber.Ctx) error {
 	api, err := getApiFromContext(c)
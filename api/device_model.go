@@ -31,13 +31,18 @@ func getDeviceModels(c *fiber.Ctx) error {
 		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
 	}
 
-	deviceModels, err := api.Store.DeviceModelStore.GetAll()
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	deviceModels, page, err := api.Store.DeviceModelStore.List(query)
 	if err != nil {
 		api.Logger.Err(err, "failed to get device models")
-		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get device models")
+		return apiResponseListError(c, err, "failed to get device models")
 	}
 
-	return apiResponseData(c, fiber.StatusOK, NewAPIRV("device_models", deviceModels))
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("device_models", deviceModels, page))
 }
 
 // getDeviceModel gets a device model by ID.
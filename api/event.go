@@ -0,0 +1,408 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// eventAPIPath is the path for the event API.
+const eventAPIPath = "/events"
+
+const (
+	eventPollInterval      = 500 * time.Millisecond
+	eventPingInterval      = 15 * time.Second
+	eventBatchSize         = 500
+	eventSubscriberBuffer  = 1000
+	eventRetryMilliseconds = 3000
+)
+
+// eventTypes are the types of the events of the stream.
+var eventTypes = []string{
+	model.EventTypeLabData,
+	model.EventTypeRawData,
+	model.EventTypeDeviceConnected,
+	model.EventTypeDeviceDisconnected,
+	model.EventTypeDeviceIdle,
+}
+
+var (
+	// errEventHubClosed is returned when subscribing to the closed hub.
+	errEventHubClosed = errors.New("the event hub is closed")
+	// errEventStreamClosed is returned when the hub closed the stream of a subscriber.
+	errEventStreamClosed = errors.New("the event stream was closed by the hub")
+)
+
+// streamEvent represents an event sent to the stream.
+type streamEvent struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	DeviceID  uint            `json:"device_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// initEventAPI initializes the event API.
+func (api *API) initEventAPI() {
+	api.Events = api.APIRoot.Group(eventAPIPath)
+
+	api.Events.Use(isAuthorized)
+
+	api.Events.Get("/stream", streamEvents)
+}
+
+// streamEvents streams the events of the device server as Server-Sent Events.
+// The device_id and types query parameters filter the events by comma separated device IDs and event types.
+// A reconnecting client gets the events it missed after its Last-Event-ID header or last_event_id query parameter.
+func streamEvents(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var since uint
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 0)
+		if err != nil {
+			return apiResponseError(c, fiber.StatusBadRequest, "invalid last event ID: "+lastEventID)
+		}
+		since = uint(id)
+	}
+
+	sub, err := api.events.subscribe(filter)
+	if err != nil {
+		api.Logger.Err(err, "failed to subscribe to the events")
+		return apiResponseError(c, fiber.StatusServiceUnavailable, "failed to subscribe to the events")
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer api.events.unsubscribe(sub)
+
+		err := api.events.stream(w, sub, since, lastEventID != "")
+		if err != nil {
+			api.Logger.Debug(fmt.Sprintf("event stream closed: %v", err))
+		}
+	})
+
+	return nil
+}
+
+// eventFilter represents the devices and the types of the events of a subscriber, the empty ones match every event.
+type eventFilter struct {
+	deviceIDs []uint
+	types     []string
+}
+
+// parseEventFilter parses the device_id and types query parameters of the stream.
+func parseEventFilter(c *fiber.Ctx) (*eventFilter, error) {
+	filter := &eventFilter{}
+
+	for _, value := range splitQuery(c.Query("device_id")) {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid device_id: %s", value)
+		}
+		filter.deviceIDs = append(filter.deviceIDs, uint(id))
+	}
+
+	for _, value := range splitQuery(c.Query("types")) {
+		if !slices.Contains(eventTypes, value) {
+			return nil, fmt.Errorf("invalid type: %s", value)
+		}
+		filter.types = append(filter.types, value)
+	}
+
+	return filter, nil
+}
+
+// splitQuery splits the comma separated values of a query parameter.
+func splitQuery(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// matches checks if the event passes the filter.
+func (f *eventFilter) matches(event *model.Event) bool {
+	if len(f.deviceIDs) > 0 && !slices.Contains(f.deviceIDs, event.DeviceID) {
+		return false
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, event.Type) {
+		return false
+	}
+
+	return true
+}
+
+// eventSubscriber represents a client of the stream, its channel is closed when it falls behind or the hub is closed.
+type eventSubscriber struct {
+	filter *eventFilter
+	events chan *model.Event
+}
+
+// eventHub polls the events written by the device server and fans them out to the subscribers.
+// It polls only while there are subscribers and starts from the last event when the first one subscribes.
+type eventHub struct {
+	log         *log.Logger
+	store       *store.Store
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]bool
+	lastID      uint
+	running     bool
+	closed      bool
+	stop        chan struct{}
+}
+
+// newEventHub creates a new eventHub.
+func newEventHub(logger *log.Logger, store *store.Store) *eventHub {
+	return &eventHub{
+		log:         logger,
+		store:       store,
+		subscribers: map[*eventSubscriber]bool{},
+		stop:        make(chan struct{}),
+	}
+}
+
+// subscribe adds a subscriber with the filter, starting the polling if it is the first one.
+func (h *eventHub) subscribe(filter *eventFilter) (*eventSubscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, errEventHubClosed
+	}
+
+	if !h.running {
+		lastID, err := h.store.EventStore.GetLastID()
+		if err != nil {
+			return nil, err
+		}
+		h.lastID = lastID
+		h.running = true
+		go h.run()
+	}
+
+	sub := &eventSubscriber{
+		filter: filter,
+		events: make(chan *model.Event, eventSubscriberBuffer),
+	}
+	h.subscribers[sub] = true
+
+	return sub, nil
+}
+
+// unsubscribe removes the subscriber.
+func (h *eventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+// remove removes the subscriber and closes its channel, the lock must be held.
+func (h *eventHub) remove(sub *eventSubscriber) {
+	if !h.subscribers[sub] {
+		return
+	}
+
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+// close stops the polling and closes the channels of the subscribers, ending their streams.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.closed = true
+	close(h.stop)
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// run polls the new events until the hub is closed or has no subscribers.
+func (h *eventHub) run() {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if !h.poll() {
+			return
+		}
+	}
+}
+
+// poll sends the new events to the subscribers and reports if the polling goes on.
+func (h *eventHub) poll() bool {
+	h.mu.Lock()
+	lastID := h.lastID
+	h.mu.Unlock()
+
+	events, err := h.store.EventStore.GetAfter(lastID, eventBatchSize)
+	if err != nil {
+		h.log.Err(err, "failed to get the new events")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	for _, event := range events {
+		h.lastID = event.ID
+		for sub := range h.subscribers {
+			if !sub.filter.matches(event) {
+				continue
+			}
+
+			select {
+			case sub.events <- event:
+			default:
+				h.log.Warn("an event stream fell behind, closing it")
+				h.remove(sub)
+			}
+		}
+	}
+
+	if len(h.subscribers) == 0 {
+		h.running = false
+		return false
+	}
+
+	return true
+}
+
+// stream writes the events of the subscriber until its channel is closed or the client is gone.
+// With a last event ID the stored events after it are written first.
+func (h *eventHub) stream(w *bufio.Writer, sub *eventSubscriber, since uint, resume bool) error {
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetryMilliseconds)
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	sent := uint(0)
+	if resume {
+		sent = since
+		for {
+			events, err := h.store.EventStore.GetAfter(sent, eventBatchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				sent = event.ID
+				if !sub.filter.matches(event) {
+					continue
+				}
+				err = writeEvent(w, event)
+				if err != nil {
+					return err
+				}
+			}
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+
+			if len(events) < eventBatchSize {
+				break
+			}
+		}
+	}
+
+	ping := time.NewTicker(eventPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				return errEventStreamClosed
+			}
+			if event.ID <= sent {
+				continue
+			}
+			sent = event.ID
+
+			err = writeEvent(w, event)
+		case <-ping.C:
+			_, err = w.WriteString(": ping\n\n")
+		}
+		if err != nil {
+			return err
+		}
+
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeEvent writes the event in the Server-Sent Events format.
+func writeEvent(w *bufio.Writer, event *model.Event) error {
+	payload := json.RawMessage(event.Payload)
+	if !json.Valid(payload) {
+		payload = json.RawMessage("null")
+	}
+
+	data, err := json.Marshal(streamEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		DeviceID:  event.DeviceID,
+		CreatedAt: event.CreatedAt,
+		Payload:   payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
 )
 
 // labDataAPIPath is the path for the lab data API.
@@ -15,14 +18,17 @@ func (api *API) initLabDataAPI() {
 	api.LabData.Use(isAuthorized)
 
 	api.LabData.Get("/", getLabDatas)
+	api.LabData.Get("/changes", getLabDataChanges)
 	api.LabData.Get("/:id", getLabData)
 	api.LabData.Get("/barcode/:barcode", getLabDataByBarcode)
 	api.LabData.Get("/device/:device_id", getLabDataByDeviceID)
 	api.LabData.Get("/device/:device_id/barcode/:barcode", getLabDataByDeviceIDAndBarcode)
 	api.LabData.Get("/serial/:serial", getLabDataBySerial)
 	api.LabData.Get("/serial/:serial/barcode/:barcode", getLabDataBySerialAndBarcode)
+	api.LabData.Get("/verification_status/:status", getLabDataByVerificationStatus)
 	api.LabData.Post("/", createLabData)
 	api.LabData.Put("/:id", updateLabData)
+	api.LabData.Put("/:id/verify", verifyLabData)
 	api.LabData.Delete("/:id", deleteLabData)
 }
 
@@ -34,13 +40,54 @@ func getLabDatas(c *fiber.Ctx) error {
 		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
 	}
 
-	labData, err := api.Store.LabDataStore.GetAll()
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	labData, page, err := api.Store.LabDataStore.List(query)
 	if err != nil {
 		api.Logger.Err(err, "failed to get lab datas")
-		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get lab datas")
+		return apiResponseListError(c, err, "failed to get lab datas")
 	}
 
-	return apiResponseData(c, fiber.StatusOK, NewAPIRV("lab_data", labData))
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("lab_data", labData, page))
+}
+
+// getLabDataChanges gets the lab datas created, updated or deleted after the since cursor.
+// The feed is continued from its next_cursor, the deleted lab datas have their deleted_at set.
+func getLabDataChanges(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	since, err := queryInt(c, "since")
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if limit == 0 {
+		limit = store.DefaultListLimit
+	}
+	limit = min(limit, store.MaxListLimit)
+
+	feed, err := api.Store.LabDataStore.GetChanges(uint(since), limit)
+	if err != nil {
+		api.Logger.Err(err, "failed to get lab data changes")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get lab data changes")
+	}
+
+	rv := NewAPIRV("changes", feed.Changes)
+	rv["next_cursor"] = feed.NextCursor
+	rv["has_more"] = feed.HasMore
+
+	return apiResponseData(c, fiber.StatusOK, rv)
 }
 
 // getLabData gets a lab data by ID.
@@ -168,6 +215,29 @@ func getLabDataBySerialAndBarcode(c *fiber.Ctx) error {
 	return apiResponseData(c, fiber.StatusOK, NewAPIRV("lab_data", labData))
 }
 
+// getLabDataByVerificationStatus gets lab data by verification status, e.g. the held ones waiting for a review.
+func getLabDataByVerificationStatus(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	status := c.Params("status")
+	labData, page, err := api.Store.LabDataStore.GetByVerificationStatus(status, query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the lab data by verification status")
+		return apiResponseListError(c, err, "failed to get the lab data by verification status")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("lab_data", labData, page))
+}
+
 // createLabData creates a new lab data.
 func createLabData(c *fiber.Ctx) error {
 	api, err := getApiFromContext(c)
@@ -223,6 +293,37 @@ func updateLabData(c *fiber.Ctx) error {
 	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", labData.ID))
 }
 
+// verifyLabData marks a lab data verified by the current user, a held lab data is released to the HIS.
+func verifyLabData(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	labData, err := api.Store.LabDataStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the lab data")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the lab data")
+	}
+
+	username, _ := c.Locals("username").(string)
+	labData.Verify(username, time.Now())
+
+	if err := api.Store.LabDataStore.Update(labData); err != nil {
+		api.Logger.Err(err, "failed to verify the lab data")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to verify the lab data")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", labData.ID))
+}
+
 // deleteLabData deletes a lab data.
 func deleteLabData(c *fiber.Ctx) error {
 	api, err := getApiFromContext(c)
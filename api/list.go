@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// listDateFormats are the formats of the from and to query parameters of the list endpoints.
+var listDateFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// parseListQuery parses the pagination, the filters and the sort of a list endpoint from the query parameters:
+// limit, offset, cursor, sort (a field, "-field" for the descending order), date_field, from, to, device_id, barcode (a prefix), param and processed.
+func parseListQuery(c *fiber.Ctx) (*store.ListQuery, error) {
+	query := &store.ListQuery{
+		DateField:     c.Query("date_field"),
+		BarcodePrefix: c.Query("barcode"),
+		Param:         c.Query("param"),
+	}
+
+	var err error
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		return nil, err
+	}
+	if query.Offset, err = queryInt(c, "offset"); err != nil {
+		return nil, err
+	}
+
+	cursor, err := queryInt(c, "cursor")
+	if err != nil {
+		return nil, err
+	}
+	query.Cursor = uint(cursor)
+
+	deviceID, err := queryInt(c, "device_id")
+	if err != nil {
+		return nil, err
+	}
+	query.DeviceID = uint(deviceID)
+
+	sort := c.Query("sort")
+	query.SortField = strings.TrimPrefix(sort, "-")
+	query.SortDesc = strings.HasPrefix(sort, "-")
+
+	if query.From, err = queryTime(c, "from"); err != nil {
+		return nil, err
+	}
+	if query.To, err = queryTime(c, "to"); err != nil {
+		return nil, err
+	}
+
+	if processed := c.Query("processed"); processed != "" {
+		value, err := strconv.ParseBool(processed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid processed: %s", processed)
+		}
+		query.Processed = &value
+	}
+
+	return query, nil
+}
+
+// queryInt parses a non-negative integer query parameter, 0 if it is not set.
+func queryInt(c *fiber.Ctx, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", key, value)
+	}
+
+	return i, nil
+}
+
+// queryTime parses a time query parameter, nil if it is not set.
+func queryTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, format := range listDateFormats {
+		t, err := time.ParseInLocation(format, value, time.Local)
+		if err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid %s: %s", key, value)
+}
+
+// apiResponseListError sends the error response of a failed list query, an invalid query is a bad request.
+func apiResponseListError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, store.ErrInvalidListQuery) {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return apiResponseError(c, fiber.StatusInternalServerError, message)
+}
+
+// NewAPIListRV creates a new API response value of a page of a list with its total count and the pagination.
+func NewAPIListRV(k string, v interface{}, page *store.Page) ApiRV {
+	rv := NewAPIRV(k, v)
+	rv["total"] = page.Total
+	rv["limit"] = page.Limit
+	rv["offset"] = page.Offset
+	if page.NextCursor != 0 {
+		rv["next_cursor"] = page.NextCursor
+	}
+
+	return rv
+}
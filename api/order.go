@@ -0,0 +1,283 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+)
+
+// orderAPIPath is the path for the order API.
+const orderAPIPath = "/orders"
+
+// worklistRequestBody is the worklist registered by the HIS ahead of the host queries of the devices.
+// The indicators are in the format of the HIS query response.
+type worklistRequestBody struct {
+	Orders []worklistOrder `json:"orders"`
+}
+
+// worklistOrder is an order of the worklist, the device is found by its serial (hardware SN), an empty serial is any device.
+type worklistOrder struct {
+	Barcode    string                          `json:"barcode"`
+	HardwareSN string                          `json:"hardware_sn"`
+	Indicators []services.DeviceQueryIndicator `json:"indicators"`
+}
+
+// initOrderAPI initializes the order API.
+func (api *API) initOrderAPI() {
+	api.Orders = api.APIRoot.Group(orderAPIPath)
+
+	api.Orders.Use(isAuthorized)
+
+	api.Orders.Get("/", getOrders)
+	api.Orders.Get("/outstanding", getOutstandingOrders)
+	api.Orders.Get("/barcode/:barcode", getOrdersByBarcode)
+	api.Orders.Get("/:id", getOrder)
+	api.Orders.Post("/", createOrder)
+	api.Orders.Post("/worklist", createWorklist)
+	api.Orders.Put("/:id/close", closeOrder)
+	api.Orders.Delete("/:id", deleteOrder)
+}
+
+// getOrders gets all orders.
+func getOrders(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	orders, page, err := api.Store.OrderStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get orders")
+		return apiResponseListError(c, err, "failed to get orders")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("orders", orders, page))
+}
+
+// getOutstandingOrders gets the orders with pending or missing tests.
+func getOutstandingOrders(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	orders, page, err := api.Store.OrderStore.GetOutstanding(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get outstanding orders")
+		return apiResponseListError(c, err, "failed to get outstanding orders")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("orders", orders, page))
+}
+
+// getOrdersByBarcode gets the orders of a barcode.
+func getOrdersByBarcode(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	barcode := c.Params("barcode")
+	orders, err := api.Store.OrderStore.GetByBarcode(barcode)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the orders by barcode")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the orders by barcode")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("orders", orders))
+}
+
+// getOrder gets an order by ID.
+func getOrder(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	order, err := api.Store.OrderStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the order")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("order", order))
+}
+
+// createOrder records the tests ordered by the HIS for a barcode.
+// The tests are added to the open order of the barcode and the device if there is one.
+func createOrder(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	reqOrder := &model.Order{}
+	if err := c.BodyParser(reqOrder); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateOrder(reqOrder); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	order, err := services.NewOrderService(api.Logger, api.Store, reqOrder.DeviceID).Record(reqOrder.Barcode, model.OrderSourceHIS, reqOrder.Sample, reqOrder.Tests)
+	if err != nil {
+		api.Logger.Err(err, "failed to create the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the order")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", order.ID))
+}
+
+// createWorklist records the orders of the worklist and returns their IDs.
+// The worklist is validated before any of its orders is recorded, then its orders are recorded in a single transaction, all or none.
+func createWorklist(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	reqBody := &worklistRequestBody{}
+	if err := c.BodyParser(reqBody); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	orders := make([]*model.Order, 0, len(reqBody.Orders))
+	for _, worklistOrder := range reqBody.Orders {
+		order := &model.Order{
+			Barcode: worklistOrder.Barcode,
+			Sample:  services.OrderSampleFromIndicators(worklistOrder.Indicators),
+			Tests:   services.OrderTestsFromIndicators(worklistOrder.Indicators),
+		}
+		if msg := validateOrder(order); msg != "" {
+			return apiResponseError(c, fiber.StatusBadRequest, msg)
+		}
+
+		if worklistOrder.HardwareSN != "" {
+			device, err := api.Store.DeviceStore.GetBySerial(worklistOrder.HardwareSN)
+			if err != nil {
+				api.Logger.Err(err, "failed to get the device of the worklist order")
+				return apiResponseError(c, fiber.StatusBadRequest, "unknown hardware SN: "+worklistOrder.HardwareSN)
+			}
+			order.DeviceID = device.ID
+		}
+
+		orders = append(orders, order)
+	}
+
+	recorded, err := services.RecordOrders(api.Store.OrderStore, model.OrderSourceHIS, orders)
+	if err != nil {
+		api.Logger.Err(err, "failed to create the worklist orders")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the worklist orders")
+	}
+
+	ids := make([]uint, 0, len(recorded))
+	for _, order := range recorded {
+		ids = append(ids, order.ID)
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("ids", ids))
+}
+
+// closeOrder closes an order, its pending tests are marked missing.
+func closeOrder(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	order, err := api.Store.OrderStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the order")
+	}
+
+	if order.Status != model.OrderStatusPending {
+		return apiResponseError(c, fiber.StatusBadRequest, "order is already closed")
+	}
+
+	order.Close()
+
+	if err := api.Store.OrderStore.Update(order); err != nil {
+		api.Logger.Err(err, "failed to close the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to close the order")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", order.ID))
+}
+
+// deleteOrder deletes an order.
+func deleteOrder(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	order, err := api.Store.OrderStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the order")
+	}
+
+	if err := api.Store.OrderStore.Delete(order); err != nil {
+		api.Logger.Err(err, "failed to delete the order")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the order")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", order.ID))
+}
+
+// validateOrder validates the order and returns the message of the first problem.
+func validateOrder(order *model.Order) string {
+	if order.Barcode == "" {
+		return "barcode is required"
+	}
+	if len(order.Tests) == 0 {
+		return "at least one test is required"
+	}
+	for _, test := range order.Tests {
+		if test.Param == "" {
+			return "param of every test is required"
+		}
+	}
+
+	return ""
+}
@@ -1,13 +1,20 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/reprocess"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
 )
 
 // rawDataAPIPath is the path for the raw data API.
 const rawDataAPIPath = "/raw_data"
 
+// maxReprocessRawDatas is the number of raw datas a request can reprocess, more are reprocessed with the reprocess command.
+const maxReprocessRawDatas = 1000
+
 // initRawDataAPI initializes the raw data API.
 func (api *API) initRawDataAPI() {
 	api.RawData = api.APIRoot.Group(rawDataAPIPath)
@@ -18,6 +25,7 @@ func (api *API) initRawDataAPI() {
 	api.RawData.Get("/:id", getRawData)
 	api.RawData.Get("/device/:device_id", getRawDataByDeviceID)
 	api.RawData.Post("/", createRawData)
+	api.RawData.Post("/reprocess", reprocessRawDatas)
 	api.RawData.Put("/:id", updateRawData)
 	api.RawData.Delete("/:id", deleteRawData)
 }
@@ -30,10 +38,15 @@ func getRawDatas(c *fiber.Ctx) error {
 		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
 	}
 
-	rawData, err := api.Store.RawDataStore.GetAll()
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rawData, page, err := api.Store.RawDataStore.List(query)
 	if err != nil {
 		api.Logger.Err(err, "failed to get raw datas")
-		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get raw datas")
+		return apiResponseListError(c, err, "failed to get raw datas")
 	}
 
 	rawDataAPIs := make([]*model.RawDataApi, 0, len(rawData))
@@ -41,7 +54,7 @@ func getRawDatas(c *fiber.Ctx) error {
 		rawDataAPIs = append(rawDataAPIs, model.NewRawDataApi(r))
 	}
 
-	return apiResponseData(c, fiber.StatusOK, NewAPIRV("raw_data", rawDataAPIs))
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("raw_data", rawDataAPIs, page))
 }
 
 // getRawData gets a raw data by ID.
@@ -119,6 +132,59 @@ func createRawData(c *fiber.Ctx) error {
 	return apiResponseData(c, fiber.StatusCreated, NewAPIRV("id", rawData.ID))
 }
 
+// reprocessRequestBody is the request body of the raw data reprocessing.
+type reprocessRequestBody struct {
+	store.RawDataFilter
+	Push bool `json:"push"`
+}
+
+// reprocessRawDatas re-parses the selected raw datas with the current drivers of their devices.
+// At most maxReprocessRawDatas raw datas are reprocessed by a request.
+func reprocessRawDatas(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	if !isAdmin(c) {
+		return apiResponseError(c, fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	reqBody := &reprocessRequestBody{}
+	if err := c.BodyParser(reqBody); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if reqBody.IsEmpty() {
+		return apiResponseError(c, fiber.StatusBadRequest, "no raw datas selected")
+	}
+
+	count, err := api.Store.RawDataStore.CountByFilter(&reqBody.RawDataFilter)
+	if err != nil {
+		api.Logger.Err(err, "failed to count the raw datas to reprocess")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to count the raw datas to reprocess")
+	}
+	if count > maxReprocessRawDatas {
+		return apiResponseError(c, fiber.StatusBadRequest, fmt.Sprintf("%d raw datas selected, at most %d can be reprocessed at once, use the reprocess command for more", count, maxReprocessRawDatas))
+	}
+
+	pipeline, err := reprocess.NewPipeline(api.Logger, api.Store)
+	if err != nil {
+		api.Logger.Err(err, "failed to create the reprocessing pipeline")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the reprocessing pipeline")
+	}
+
+	results, err := reprocess.NewReprocessor(api.Logger, api.Store, reqBody.Push, pipeline).Reprocess(&reqBody.RawDataFilter)
+	if err != nil {
+		api.Logger.Err(err, "failed to reprocess the raw datas")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to reprocess the raw datas")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("results", results))
+}
+
 // updateRawData updates a raw data.
 func updateRawData(c *fiber.Ctx) error {
 	api, err := getApiFromContext(c)
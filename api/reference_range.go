@@ -0,0 +1,199 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// referenceRangeAPIPath is the path for the reference range API.
+const referenceRangeAPIPath = "/reference_ranges"
+
+// initReferenceRangeAPI initializes the reference range API.
+func (api *API) initReferenceRangeAPI() {
+	api.ReferenceRanges = api.APIRoot.Group(referenceRangeAPIPath)
+
+	api.ReferenceRanges.Use(isAuthorized)
+
+	api.ReferenceRanges.Get("/", getReferenceRanges)
+	api.ReferenceRanges.Get("/:id", getReferenceRange)
+	api.ReferenceRanges.Get("/param/:param", getReferenceRangesByParam)
+	api.ReferenceRanges.Post("/", createReferenceRange)
+	api.ReferenceRanges.Put("/:id", updateReferenceRange)
+	api.ReferenceRanges.Delete("/:id", deleteReferenceRange)
+}
+
+// getReferenceRanges gets all reference ranges.
+func getReferenceRanges(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	referenceRanges, page, err := api.Store.ReferenceRangeStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get reference ranges")
+		return apiResponseListError(c, err, "failed to get reference ranges")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("reference_ranges", referenceRanges, page))
+}
+
+// getReferenceRange gets a reference range by ID.
+func getReferenceRange(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	referenceRange, err := api.Store.ReferenceRangeStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the reference range")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("reference_range", referenceRange))
+}
+
+// getReferenceRangesByParam gets the reference ranges of a HIS test code.
+func getReferenceRangesByParam(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	param := c.Params("param")
+	if param == "" {
+		return apiResponseError(c, fiber.StatusBadRequest, "param is required")
+	}
+
+	referenceRanges, err := api.Store.ReferenceRangeStore.GetByParams([]string{param})
+	if err != nil {
+		api.Logger.Err(err, "failed to get the reference ranges by param")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the reference ranges by param")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("reference_ranges", referenceRanges))
+}
+
+// createReferenceRange creates a new reference range.
+func createReferenceRange(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	referenceRange := &model.ReferenceRange{}
+	if err := c.BodyParser(referenceRange); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateReferenceRange(referenceRange); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.ReferenceRangeStore.Create(referenceRange); err != nil {
+		api.Logger.Err(err, "failed to create the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the reference range")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", referenceRange.ID))
+}
+
+// updateReferenceRange updates a reference range.
+func updateReferenceRange(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	referenceRange, err := api.Store.ReferenceRangeStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the reference range")
+	}
+
+	if err := c.BodyParser(referenceRange); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateReferenceRange(referenceRange); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.ReferenceRangeStore.Update(referenceRange); err != nil {
+		api.Logger.Err(err, "failed to update the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the reference range")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", referenceRange.ID))
+}
+
+// deleteReferenceRange deletes a reference range.
+func deleteReferenceRange(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	referenceRange, err := api.Store.ReferenceRangeStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the reference range")
+	}
+
+	if err := api.Store.ReferenceRangeStore.Delete(referenceRange); err != nil {
+		api.Logger.Err(err, "failed to delete the reference range")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the reference range")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", referenceRange.ID))
+}
+
+// validateReferenceRange validates the reference range and returns the message of the first problem.
+func validateReferenceRange(referenceRange *model.ReferenceRange) string {
+	if referenceRange.Param == "" {
+		return "param is required"
+	}
+	if referenceRange.Sex != "" && referenceRange.Sex != "M" && referenceRange.Sex != "F" {
+		return "sex must be M, F or empty"
+	}
+	if referenceRange.AgeToDays != 0 && referenceRange.AgeToDays < referenceRange.AgeFromDays {
+		return "age to must not be less than age from"
+	}
+	if referenceRange.Low == nil && referenceRange.High == nil && referenceRange.CriticalLow == nil && referenceRange.CriticalHigh == nil {
+		return "at least one limit is required"
+	}
+
+	return ""
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// testCodeMappingAPIPath is the path for the test code mapping API.
+const testCodeMappingAPIPath = "/test_code_mappings"
+
+// initTestCodeMappingAPI initializes the test code mapping API.
+func (api *API) initTestCodeMappingAPI() {
+	api.TestCodeMappings = api.APIRoot.Group(testCodeMappingAPIPath)
+
+	api.TestCodeMappings.Use(isAuthorized)
+
+	api.TestCodeMappings.Get("/", getTestCodeMappings)
+	api.TestCodeMappings.Get("/:id", getTestCodeMapping)
+	api.TestCodeMappings.Get("/device_model/:device_model_id", getTestCodeMappingsByDeviceModelID)
+	api.TestCodeMappings.Post("/", createTestCodeMapping)
+	api.TestCodeMappings.Put("/:id", updateTestCodeMapping)
+	api.TestCodeMappings.Delete("/:id", deleteTestCodeMapping)
+}
+
+// getTestCodeMappings gets all test code mappings.
+func getTestCodeMappings(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	mappings, page, err := api.Store.TestCodeMappingStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get test code mappings")
+		return apiResponseListError(c, err, "failed to get test code mappings")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("test_code_mappings", mappings, page))
+}
+
+// getTestCodeMapping gets a test code mapping by ID.
+func getTestCodeMapping(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	mapping, err := api.Store.TestCodeMappingStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the test code mapping")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("test_code_mapping", mapping))
+}
+
+// getTestCodeMappingsByDeviceModelID gets the test code mappings of a device model.
+func getTestCodeMappingsByDeviceModelID(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	deviceModelID, err := c.ParamsInt("device_model_id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the device model ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the device model ID")
+	}
+
+	mappings, err := api.Store.TestCodeMappingStore.GetByDeviceModelID(uint(deviceModelID))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the test code mappings by device model ID")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the test code mappings by device model ID")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("test_code_mappings", mappings))
+}
+
+// createTestCodeMapping creates a new test code mapping.
+func createTestCodeMapping(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	mapping := &model.TestCodeMapping{}
+	if err := c.BodyParser(mapping); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if mapping.DeviceModelID == 0 || mapping.DeviceCode == "" || mapping.HISCode == "" {
+		return apiResponseError(c, fiber.StatusBadRequest, "device model, device code and HIS code are required")
+	}
+
+	if err := api.Store.TestCodeMappingStore.Create(mapping); err != nil {
+		api.Logger.Err(err, "failed to create the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the test code mapping")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", mapping.ID))
+}
+
+// updateTestCodeMapping updates a test code mapping.
+func updateTestCodeMapping(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	mapping, err := api.Store.TestCodeMappingStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the test code mapping")
+	}
+
+	if err := c.BodyParser(mapping); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if err := api.Store.TestCodeMappingStore.Update(mapping); err != nil {
+		api.Logger.Err(err, "failed to update the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the test code mapping")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", mapping.ID))
+}
+
+// deleteTestCodeMapping deletes a test code mapping.
+func deleteTestCodeMapping(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	mapping, err := api.Store.TestCodeMappingStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the test code mapping")
+	}
+
+	if err := api.Store.TestCodeMappingStore.Delete(mapping); err != nil {
+		api.Logger.Err(err, "failed to delete the test code mapping")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the test code mapping")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", mapping.ID))
+}
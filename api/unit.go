@@ -0,0 +1,167 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// unitAPIPath is the path for the unit API.
+const unitAPIPath = "/units"
+
+// initUnitAPI initializes the unit API.
+func (api *API) initUnitAPI() {
+	api.Units = api.APIRoot.Group(unitAPIPath)
+
+	api.Units.Use(isAuthorized)
+
+	api.Units.Get("/", getUnits)
+	api.Units.Get("/:id", getUnit)
+	api.Units.Post("/", createUnit)
+	api.Units.Put("/:id", updateUnit)
+	api.Units.Delete("/:id", deleteUnit)
+}
+
+// getUnits gets all units.
+func getUnits(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	units, page, err := api.Store.UnitStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get units")
+		return apiResponseListError(c, err, "failed to get units")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("units", units, page))
+}
+
+// getUnit gets a unit by ID.
+func getUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unit, err := api.Store.UnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("unit", unit))
+}
+
+// createUnit creates a new unit.
+func createUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	unit := &model.Unit{}
+	if err := c.BodyParser(unit); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateUnit(unit); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.UnitStore.Create(unit); err != nil {
+		api.Logger.Err(err, "failed to create the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unit.ID))
+}
+
+// updateUnit updates a unit.
+func updateUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unit, err := api.Store.UnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit")
+	}
+
+	if err := c.BodyParser(unit); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateUnit(unit); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.UnitStore.Update(unit); err != nil {
+		api.Logger.Err(err, "failed to update the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unit.ID))
+}
+
+// deleteUnit deletes a unit.
+func deleteUnit(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unit, err := api.Store.UnitStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit")
+	}
+
+	if err := api.Store.UnitStore.Delete(unit); err != nil {
+		api.Logger.Err(err, "failed to delete the unit")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the unit")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unit.ID))
+}
+
+// validateUnit validates the unit and returns the message of the first problem.
+func validateUnit(unit *model.Unit) string {
+	if unit.Code == "" {
+		return "code is required"
+	}
+
+	return ""
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// unitConversionAPIPath is the path for the unit conversion API.
+const unitConversionAPIPath = "/unit_conversions"
+
+// initUnitConversionAPI initializes the unit conversion API.
+func (api *API) initUnitConversionAPI() {
+	api.UnitConversions = api.APIRoot.Group(unitConversionAPIPath)
+
+	api.UnitConversions.Use(isAuthorized)
+
+	api.UnitConversions.Get("/", getUnitConversions)
+	api.UnitConversions.Get("/:id", getUnitConversion)
+	api.UnitConversions.Get("/param/:param", getUnitConversionsByParam)
+	api.UnitConversions.Post("/", createUnitConversion)
+	api.UnitConversions.Put("/:id", updateUnitConversion)
+	api.UnitConversions.Delete("/:id", deleteUnitConversion)
+}
+
+// getUnitConversions gets all unit conversions.
+func getUnitConversions(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	unitConversions, page, err := api.Store.UnitConversionStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get unit conversions")
+		return apiResponseListError(c, err, "failed to get unit conversions")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("unit_conversions", unitConversions, page))
+}
+
+// getUnitConversion gets a unit conversion by ID.
+func getUnitConversion(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unitConversion, err := api.Store.UnitConversionStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit conversion")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("unit_conversion", unitConversion))
+}
+
+// getUnitConversionsByParam gets the unit conversions of a HIS test code, including the ones for every test code.
+func getUnitConversionsByParam(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	param := c.Params("param")
+	if param == "" {
+		return apiResponseError(c, fiber.StatusBadRequest, "param is required")
+	}
+
+	unitConversions, err := api.Store.UnitConversionStore.GetByParams([]string{param})
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit conversions by param")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit conversions by param")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("unit_conversions", unitConversions))
+}
+
+// createUnitConversion creates a new unit conversion.
+func createUnitConversion(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	unitConversion := &model.UnitConversion{}
+	if err := c.BodyParser(unitConversion); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateUnitConversion(unitConversion); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.UnitConversionStore.Create(unitConversion); err != nil {
+		api.Logger.Err(err, "failed to create the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the unit conversion")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unitConversion.ID))
+}
+
+// updateUnitConversion updates a unit conversion.
+func updateUnitConversion(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unitConversion, err := api.Store.UnitConversionStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit conversion")
+	}
+
+	if err := c.BodyParser(unitConversion); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateUnitConversion(unitConversion); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.UnitConversionStore.Update(unitConversion); err != nil {
+		api.Logger.Err(err, "failed to update the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the unit conversion")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unitConversion.ID))
+}
+
+// deleteUnitConversion deletes a unit conversion.
+func deleteUnitConversion(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	unitConversion, err := api.Store.UnitConversionStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the unit conversion")
+	}
+
+	if err := api.Store.UnitConversionStore.Delete(unitConversion); err != nil {
+		api.Logger.Err(err, "failed to delete the unit conversion")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the unit conversion")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", unitConversion.ID))
+}
+
+// validateUnitConversion validates the unit conversion and returns the message of the first problem.
+func validateUnitConversion(unitConversion *model.UnitConversion) string {
+	if unitConversion.FromUnit == "" || unitConversion.ToUnit == "" {
+		return "from unit and to unit are required"
+	}
+	if unitConversion.FromUnit == unitConversion.ToUnit {
+		return "from unit and to unit must differ"
+	}
+	if unitConversion.Factor == 0 {
+		return "factor must not be zero"
+	}
+
+	return ""
+}
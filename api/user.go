@@ -100,13 +100,18 @@ func getUsers(c *fiber.Ctx) error {
 		return apiResponseError(c, fiber.StatusUnauthorized, "unauthorized")
 	}
 
-	users, err := api.Store.UserStore.GetAll()
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	users, page, err := api.Store.UserStore.List(query)
 	if err != nil {
 		api.Logger.Err(err, "failed to get users")
-		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get users")
+		return apiResponseListError(c, err, "failed to get users")
 	}
 
-	return apiResponseData(c, fiber.StatusOK, NewAPIRV("users", users))
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("users", users, page))
 }
 
 // getUser gets a user by ID.
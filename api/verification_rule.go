@@ -0,0 +1,200 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// verificationRuleAPIPath is the path for the verification rule API.
+const verificationRuleAPIPath = "/verification_rules"
+
+// initVerificationRuleAPI initializes the verification rule API.
+func (api *API) initVerificationRuleAPI() {
+	api.VerificationRules = api.APIRoot.Group(verificationRuleAPIPath)
+
+	api.VerificationRules.Use(isAuthorized)
+
+	api.VerificationRules.Get("/", getVerificationRules)
+	api.VerificationRules.Get("/:id", getVerificationRule)
+	api.VerificationRules.Get("/param/:param", getVerificationRulesByParam)
+	api.VerificationRules.Post("/", createVerificationRule)
+	api.VerificationRules.Put("/:id", updateVerificationRule)
+	api.VerificationRules.Delete("/:id", deleteVerificationRule)
+}
+
+// getVerificationRules gets all verification rules.
+func getVerificationRules(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiResponseError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	verificationRules, page, err := api.Store.VerificationRuleStore.List(query)
+	if err != nil {
+		api.Logger.Err(err, "failed to get verification rules")
+		return apiResponseListError(c, err, "failed to get verification rules")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIListRV("verification_rules", verificationRules, page))
+}
+
+// getVerificationRule gets a verification rule by ID.
+func getVerificationRule(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	verificationRule, err := api.Store.VerificationRuleStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the verification rule")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("verification_rule", verificationRule))
+}
+
+// getVerificationRulesByParam gets the verification rules of a HIS test code, including the ones for every test code.
+func getVerificationRulesByParam(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	param := c.Params("param")
+	if param == "" {
+		return apiResponseError(c, fiber.StatusBadRequest, "param is required")
+	}
+
+	verificationRules, err := api.Store.VerificationRuleStore.GetByParams([]string{param})
+	if err != nil {
+		api.Logger.Err(err, "failed to get the verification rules by param")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the verification rules by param")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("verification_rules", verificationRules))
+}
+
+// createVerificationRule creates a new verification rule.
+func createVerificationRule(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	verificationRule := &model.VerificationRule{}
+	if err := c.BodyParser(verificationRule); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateVerificationRule(verificationRule); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.VerificationRuleStore.Create(verificationRule); err != nil {
+		api.Logger.Err(err, "failed to create the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to create the verification rule")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", verificationRule.ID))
+}
+
+// updateVerificationRule updates a verification rule.
+func updateVerificationRule(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	verificationRule, err := api.Store.VerificationRuleStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the verification rule")
+	}
+
+	if err := c.BodyParser(verificationRule); err != nil {
+		api.Logger.Err(err, "failed to parse the request body")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the request body")
+	}
+
+	if msg := validateVerificationRule(verificationRule); msg != "" {
+		return apiResponseError(c, fiber.StatusBadRequest, msg)
+	}
+
+	if err := api.Store.VerificationRuleStore.Update(verificationRule); err != nil {
+		api.Logger.Err(err, "failed to update the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to update the verification rule")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", verificationRule.ID))
+}
+
+// deleteVerificationRule deletes a verification rule.
+func deleteVerificationRule(c *fiber.Ctx) error {
+	api, err := getApiFromContext(c)
+	if err != nil {
+		api.Logger.Err(err, "failed to get the app from context")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the app from context")
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		api.Logger.Err(err, "failed to parse the ID")
+		return apiResponseError(c, fiber.StatusBadRequest, "failed to parse the ID")
+	}
+
+	verificationRule, err := api.Store.VerificationRuleStore.GetByID(uint(id))
+	if err != nil {
+		api.Logger.Err(err, "failed to get the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to get the verification rule")
+	}
+
+	if err := api.Store.VerificationRuleStore.Delete(verificationRule); err != nil {
+		api.Logger.Err(err, "failed to delete the verification rule")
+		return apiResponseError(c, fiber.StatusInternalServerError, "failed to delete the verification rule")
+	}
+
+	return apiResponseData(c, fiber.StatusOK, NewAPIRV("id", verificationRule.ID))
+}
+
+// validateVerificationRule validates the verification rule and returns the message of the first problem.
+func validateVerificationRule(verificationRule *model.VerificationRule) string {
+	switch verificationRule.Type {
+	case model.VerificationRuleTypeRange, model.VerificationRuleTypeCritical:
+		if verificationRule.Low == nil && verificationRule.High == nil {
+			return "low or high is required"
+		}
+	case model.VerificationRuleTypeDelta:
+		if verificationRule.DeltaAbsolute == nil && verificationRule.DeltaPercent == nil {
+			return "delta absolute or delta percent is required"
+		}
+	case model.VerificationRuleTypeInstrumentFlag:
+	default:
+		return "type must be range, delta, critical or instrument_flag"
+	}
+
+	return ""
+}
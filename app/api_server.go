@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/voidmaindev/doctra_lis_middleware/api"
@@ -151,16 +150,23 @@ func (a *APIServerApplication) Start() error {
 }
 
 // Stop stops the API server application.
-func (a *APIServerApplication) Stop() error {
+// The requests in progress are finished until the context is done, then the store is closed.
+func (a *APIServerApplication) Stop(ctx context.Context) error {
 	a.Log.Info("stopping the API server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*serverStopTimeout)
-	defer cancel()
+	a.API.Close()
+
 	err := a.Router.ShutdownWithContext(ctx)
 	if err != nil {
 		a.Log.Err(err, "failed to stop the API server")
 		return err
 	}
 
+	err = a.Store.Close()
+	if err != nil {
+		a.Log.Err(err, "failed to close the store")
+		return err
+	}
+
 	return nil
 }
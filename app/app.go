@@ -1,9 +1,11 @@
 // Package app provides the interface that defines the methods that an application should implement.
 package app
 
-import "github.com/voidmaindev/doctra_lis_middleware/log"
+import (
+	"context"
 
-const serverStopTimeout = 10
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+)
 
 // App is the interface that defines the methods that an application should implement.
 type App interface {
@@ -11,5 +13,21 @@ type App interface {
 	InitApp() error
 	setConfig() error
 	Start() error
-	Stop() error
+	Stop(context.Context) error
+}
+
+// waitContext runs the wait function until it returns or the context is done.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
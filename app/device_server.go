@@ -1,17 +1,20 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"strings"
+	"sync"
 
 	"github.com/voidmaindev/doctra_lis_middleware/config"
-	"github.com/voidmaindev/doctra_lis_middleware/driver"
 	"github.com/voidmaindev/doctra_lis_middleware/log"
 	"github.com/voidmaindev/doctra_lis_middleware/model"
 	"github.com/voidmaindev/doctra_lis_middleware/services"
+	"github.com/voidmaindev/doctra_lis_middleware/session"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
 	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 // DeviceServerApplication is the application for the device server.
@@ -21,6 +24,16 @@ type DeviceServerApplication struct {
 	Listener net.Listener
 	Store    *store.Store
 	TCP      *tcp.TCP
+	Sessions map[string]*session.Session
+
+	ResultDelivery *services.ResultDeliveryService
+	CriticalAlerts *services.CriticalAlertService
+	QueryClients   services.QueryClients
+	Events         *services.EventService
+	DeviceStatus   *services.DeviceStatusService
+	Pipeline       *services.LabDataPipeline
+
+	sessionsMu sync.Mutex
 }
 
 // SetLogger sets the logger for the device server application.
@@ -54,6 +67,29 @@ func (a *DeviceServerApplication) InitApp() error {
 		return err
 	}
 
+	a.setResultDelivery()
+
+	err = a.setCriticalAlerts()
+	if err != nil {
+		a.Log.Error("failed to set the critical alerts")
+		return err
+	}
+
+	a.setEvents()
+	a.Pipeline = services.NewLabDataPipeline(a.Log, a.ResultDelivery, a.CriticalAlerts, a.Events)
+
+	err = a.setDeviceStatus()
+	if err != nil {
+		a.Log.Error("failed to set the device status")
+		return err
+	}
+
+	err = a.setQueryClients()
+	if err != nil {
+		a.Log.Error("failed to set the query clients")
+		return err
+	}
+
 	return nil
 }
 
@@ -99,7 +135,72 @@ func (a *DeviceServerApplication) setStore() error {
 // setTCP sets the TCP for the device server application.
 func (a *DeviceServerApplication) setTCP() error {
 	tcp := tcp.NewTCP(a.Log, a.Listener)
+	tcp.OnConnect = a.openSession
+	tcp.OnDisconnect = a.closeSession
 	a.TCP = tcp
+	a.Sessions = map[string]*session.Session{}
+
+	return nil
+}
+
+// setResultDelivery sets the result delivery to the HIS if it is enabled.
+func (a *DeviceServerApplication) setResultDelivery() {
+	if !a.Config.ResultPush.Enabled {
+		return
+	}
+
+	a.ResultDelivery = services.NewResultDeliveryService(a.Log, a.Store, a.Config.ResultPush)
+}
+
+// setCriticalAlerts sets the sending of the critical result alerts to the webhook if it is enabled.
+// The alerts are stored with the critical lab datas even if the sending is disabled.
+func (a *DeviceServerApplication) setCriticalAlerts() error {
+	if !a.Config.CriticalAlerts.Enabled {
+		return nil
+	}
+
+	criticalAlerts, err := services.NewCriticalAlertService(a.Log, a.Store, a.Config.CriticalAlerts)
+	if err != nil {
+		a.Log.Err(err, "failed to create the critical alert service")
+		return err
+	}
+
+	a.CriticalAlerts = criticalAlerts
+
+	return nil
+}
+
+// setEvents sets the events of the live stream if they are enabled.
+func (a *DeviceServerApplication) setEvents() {
+	if !a.Config.Events.Enabled {
+		return
+	}
+
+	a.Events = services.NewEventService(a.Log, a.Store, a.Config.Events)
+}
+
+// setDeviceStatus sets the connection states of the devices and their idle alerts.
+func (a *DeviceServerApplication) setDeviceStatus() error {
+	deviceStatus, err := services.NewDeviceStatusService(a.Log, a.Store, a.Events, a.Config.DeviceStatus)
+	if err != nil {
+		a.Log.Err(err, "failed to create the device status service")
+		return err
+	}
+
+	a.DeviceStatus = deviceStatus
+
+	return nil
+}
+
+// setQueryClients sets the clients of the host queries to the configured query backends.
+func (a *DeviceServerApplication) setQueryClients() error {
+	queryClients, err := services.NewQueryClients(a.Log, a.Config.DBSettings.QueryHost, a.Config.QueryBackends, a.Config.Query)
+	if err != nil {
+		a.Log.Err(err, "failed to create the query clients")
+		return err
+	}
+
+	a.QueryClients = queryClients
 
 	return nil
 }
@@ -111,20 +212,113 @@ func (a *DeviceServerApplication) Start() error {
 	go a.TCP.AcceptConnections()
 	go a.ManageMessages()
 
+	err := a.connectDevices()
+	if err != nil {
+		a.Log.Error("failed to open the device connections")
+		return err
+	}
+
+	if a.ResultDelivery != nil {
+		a.ResultDelivery.Start()
+	}
+
+	if a.CriticalAlerts != nil {
+		a.CriticalAlerts.Start()
+	}
+
+	a.QueryClients.Start()
+	a.Events.Start()
+	a.DeviceStatus.Start()
+
 	return nil
 }
 
 // Stop stops the device server application.
-func (a *DeviceServerApplication) Stop() error {
+// It stops accepting the connections, lets the messages in progress be stored and acknowledged until the context is done
+// and closes the device connections, then it stops the background services and closes the store.
+// The store stays open if the work in progress did not finish in time.
+func (a *DeviceServerApplication) Stop(ctx context.Context) error {
 	a.Log.Info("stopping the device server")
 
-	err := a.Listener.Close()
+	var errs []error
+	err := a.TCP.Shutdown(ctx)
+	if err != nil {
+		a.Log.Err(err, "failed to drain the device connections")
+		errs = append(errs, err)
+	}
+
+	err = waitContext(ctx, a.stopServices)
+	if err != nil {
+		a.Log.Err(err, "failed to stop the background services")
+		return errors.Join(append(errs, err)...)
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	err = a.Store.Close()
+	if err != nil {
+		a.Log.Err(err, "failed to close the store")
+		return err
+	}
+
+	return nil
+}
+
+// stopServices stops the background services, they finish their current work first.
+func (a *DeviceServerApplication) stopServices() {
+	if a.ResultDelivery != nil {
+		a.ResultDelivery.Stop()
+	}
+
+	if a.CriticalAlerts != nil {
+		a.CriticalAlerts.Stop()
+	}
+
+	a.QueryClients.Stop()
+	a.DeviceStatus.Stop()
+	a.Events.Stop()
+}
+
+// connectDevices opens the own listeners of the devices in the server mode,
+// the outbound connections to the devices in the client mode and the serial ports of the devices in the serial mode.
+// The devices are read once, the devices added later are connected after a restart.
+func (a *DeviceServerApplication) connectDevices() error {
+	devices, err := a.Store.DeviceStore.GetAll()
 	if err != nil {
-		a.Log.Err(err, "failed to stop the TCP listener")
+		a.Log.Err(err, "failed to get the devices")
 		return err
 	}
 
-	close(a.TCP.RcvChannel)
+	ports := map[string]bool{a.Config.Port: true}
+	for _, device := range devices {
+		switch device.ConnectionMode {
+		case model.ConnectionModeClient:
+			a.Log.Info(fmt.Sprintf("connecting to %s at %s", device.Name, device.NetAddress))
+			go a.TCP.ConnectTo(device.NetAddress, device.ID)
+			continue
+		case model.ConnectionModeSerial:
+			serialConfig := transport.SerialConfig(device.SerialPort)
+			a.Log.Info(fmt.Sprintf("opening serial port %s for %s", serialConfig, device.Name))
+			go a.TCP.ConnectSerial(serialConfig, device.ID)
+			continue
+		}
+
+		if device.ListenPort == "" || ports[device.ListenPort] {
+			continue
+		}
+		ports[device.ListenPort] = true
+
+		listener, err := a.TCP.Listen(net.JoinHostPort(a.Config.Host, device.ListenPort))
+		if err != nil {
+			a.Log.Err(err, fmt.Sprintf("failed to listen on port %s for %s", device.ListenPort, device.Name))
+			continue
+		}
+
+		a.Log.Info(fmt.Sprintf("listening on port %s for %s", device.ListenPort, device.Name))
+		go a.TCP.Serve(listener)
+	}
 
 	return nil
 }
@@ -136,13 +330,140 @@ func (a *DeviceServerApplication) ManageMessages() {
 	}
 }
 
+// openSession opens a session for the accepted connection.
+func (a *DeviceServerApplication) openSession(conn *tcp.ConnData) {
+	sess, err := a.getSession(conn)
+	if err != nil {
+		a.Log.Error("failed to open a session for " + conn.ConnString)
+		return
+	}
+
+	a.DeviceStatus.Connected(sess.Device, conn.ConnString)
+	a.Events.PublishConnection(model.EventTypeDeviceConnected, sess.Device.ID, newEventConnection(sess))
+}
+
+// closeSession closes the session of the closed connection.
+func (a *DeviceServerApplication) closeSession(conn *tcp.ConnData) {
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	sess, ok := a.Sessions[conn.ConnString]
+	if !ok || sess.ConnData != conn {
+		return
+	}
+
+	sess.Close()
+	delete(a.Sessions, conn.ConnString)
+
+	a.DeviceStatus.Disconnected(sess.Device.ID, conn.ConnString, conn.Err)
+	a.Events.PublishConnection(model.EventTypeDeviceDisconnected, sess.Device.ID, newEventConnection(sess))
+}
+
+// newEventConnection creates the payload of the connection events of the session.
+func newEventConnection(sess *session.Session) model.EventConnection {
+	return model.EventConnection{
+		ConnString: sess.ConnData.ConnString,
+		DeviceName: sess.Device.Name,
+		RemoteIP:   sess.ConnData.RemoteIP,
+		RemotePort: sess.ConnData.RemotePort,
+	}
+}
+
+// getSession gets the session of the connection, creating it if the connection has none yet.
+// The device is looked up only when the session is created.
+func (a *DeviceServerApplication) getSession(conn *tcp.ConnData) (*session.Session, error) {
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	sess, ok := a.Sessions[conn.ConnString]
+	if ok && sess.ConnData == conn {
+		return sess, nil
+	}
+
+	device, err := a.identifyDevice(conn)
+	if err != nil {
+		a.Log.Error("failed to identify a device for connection: " + conn.ConnString)
+		return nil, err
+	}
+
+	sess, err = session.NewSession(a.Log, a.Store, a.QueryClients, conn, device)
+	if err != nil {
+		a.Log.Error("failed to create a session for " + device.Name)
+		return nil, err
+	}
+
+	if ok {
+		a.Sessions[conn.ConnString].Close()
+	}
+	a.Sessions[conn.ConnString] = sess
+
+	return sess, nil
+}
+
+// identifyDevice finds the device of the connection.
+// The device of an outbound connection is known, for the accepted connections devices identified by IP and port come first, then by the listening port, then by IP.
+// A device identified by sender ID is only a candidate sharing the IP, it is confirmed by the sender ID of its messages.
+func (a *DeviceServerApplication) identifyDevice(conn *tcp.ConnData) (*model.Device, error) {
+	if conn.DeviceID != 0 {
+		return a.Store.DeviceStore.GetByID(conn.DeviceID)
+	}
+
+	device, err := a.Store.DeviceStore.GetByNetAddressAndIdentifyBy(net.JoinHostPort(conn.RemoteIP, conn.RemotePort), model.IdentifyByIPPort)
+	if err == nil {
+		return device, nil
+	}
+
+	device, err = a.Store.DeviceStore.GetByListenPort(conn.LocalPort)
+	if err == nil {
+		return device, nil
+	}
+
+	device, err = a.Store.DeviceStore.GetByNetAddressAndIdentifyBy(conn.RemoteIP, model.IdentifyByIP)
+	if err == nil {
+		return device, nil
+	}
+
+	device, err = a.Store.DeviceStore.GetByNetAddressAndIdentifyBy(conn.RemoteIP, model.IdentifyBySenderID)
+	if err == nil {
+		return device, nil
+	}
+
+	return nil, fmt.Errorf("no device for connection: %v", conn.ConnString)
+}
+
+// identifyBySenderID rebinds the session to the device with the sender ID of the raw data.
+func (a *DeviceServerApplication) identifyBySenderID(rawData string, sess *session.Session) {
+	senderID := sess.Driver.SenderID(rawData)
+	if senderID == "" || senderID == sess.Device.SenderID {
+		return
+	}
+
+	device, err := a.Store.DeviceStore.GetBySenderID(senderID)
+	if err != nil {
+		a.Log.Warn(fmt.Sprintf("no device with sender ID %s, keeping %s", senderID, sess.Device.Name))
+		return
+	}
+
+	previousDeviceID := sess.Device.ID
+	err = sess.Rebind(device)
+	if err != nil {
+		a.Log.Error("failed to rebind the session to " + device.Name)
+		return
+	}
+
+	a.DeviceStatus.Disconnected(previousDeviceID, sess.ConnData.ConnString, nil)
+	a.DeviceStatus.Connected(device, sess.ConnData.ConnString)
+
+	a.Log.Info(fmt.Sprintf("connection %s identified as %s by sender ID %s", sess.ConnData.ConnString, device.Name, senderID))
+}
+
 // manageMessage manages the message received by the device server.
 func (a *DeviceServerApplication) manageMessage(msg tcp.RcvData) {
 	defer func() {
 		msg.Wg.Done()
 	}()
 
-	conn := a.TCP.Conns[msg.ConnString]
+	conn := a.TCP.Conn(msg.ConnString)
 
 	if conn == nil {
 		a.Log.Error("failed to get a connection by network address: " + msg.ConnString)
@@ -150,94 +471,113 @@ func (a *DeviceServerApplication) manageMessage(msg tcp.RcvData) {
 	}
 
 	a.Log.Info("received a message from " + msg.ConnString)
-	a.Log.Info(string(msg.Data))
+	a.Log.Debug(string(msg.Data))
 
-	device, err := a.Store.DeviceStore.GetByNetAddress(msg.ConnString)
+	sess, err := a.getSession(conn)
 	if err != nil {
-		a.Log.Error("failed to get a device by network address: " + msg.ConnString)
+		a.Log.Error("failed to get a session for " + msg.ConnString)
 		return
 	}
 
-	err = a.processDeviceMessage(msg.Data, conn, device, a.Log, a.Store)
+	a.DeviceStatus.Received(sess.Device.ID, conn.ConnString, len(msg.Data))
+
+	err = a.processDeviceMessage(msg.Data, sess)
 	if err != nil {
 		a.Log.Error("failed to process the device message")
+		a.DeviceStatus.Failed(sess.Device.ID, err)
 		return
 	}
 }
 
 // processDeviceMessage processes the device message.
-func (a *DeviceServerApplication) processDeviceMessage(deviceMsg []byte, conn *tcp.ConnData, device *model.Device, log *log.Logger, store *store.Store) error {
-	device, err := store.DeviceStore.GetByID(device.ID)
+func (a *DeviceServerApplication) processDeviceMessage(deviceMsg []byte, sess *session.Session) error {
+	rawDatas, err := sess.RawDatas(deviceMsg)
 	if err != nil {
-		log.Error("failed to get a device by ID: " + fmt.Sprint(device.ID))
 		return err
 	}
 
-	deviceQueryService := services.NewDeviceQueryService(a.Config.DBSettings.QueryHost, device.Serial)
+	for _, rawData := range rawDatas {
+		err = a.processRawData(rawData, sess)
+		if err != nil {
+			return err
+		}
+	}
 
-	deviceDriver, err := driver.NewDriver(device.DeviceModel.Driver, log, store, deviceQueryService)
-	if err != nil {
-		log.Error(fmt.Sprintf("failed to create a driver for %s with driver name %s", device.Name, device.DeviceModel.Driver))
-		return err
+	return nil
+}
+
+// processRawData unmarshals and stores the raw data, then acknowledges it to the device.
+// The lab datas of the raw data are stored in one transaction, so a negative acknowledgement never leaves a part of them stored.
+func (a *DeviceServerApplication) processRawData(rawData string, sess *session.Session) error {
+	if sess.Device.IdentifyBy == model.IdentifyBySenderID {
+		a.identifyBySenderID(rawData, sess)
 	}
 
-	msg := string(deviceMsg)
-	for k, v := range deviceDriver.DataToBeReplaced() {
-		msg = strings.ReplaceAll(msg, k, v)
+	device := sess.Device
+	deviceDriver := sess.Driver
+
+	rd := &model.RawData{
+		ConnString: sess.ConnData.ConnString,
+		DeviceID:   device.ID,
+		Data:       []byte(rawData),
+		Processed:  true,
 	}
 
-	if deviceDriver.ReceivedSimpleACK(msg) {
-		return nil
+	labDatas, additionalData, processErr := deviceDriver.Unmarshal(rawData)
+	if processErr != nil {
+		deviceDriver.Log().Error("failed to unmarshal a raw data from " + device.Name)
+		rd.Processed = false
+	} else {
+		processErr = sess.LabDatas.Prepare(labDatas)
+		if processErr != nil {
+			deviceDriver.Log().Err(processErr, "failed to prepare the lab datas of a raw data from "+device.Name)
+			rd.Processed = false
+		}
 	}
 
-	err = deviceDriver.SendSimpleACK(conn.Conn)
+	err := deviceDriver.Store().RawDataStore.Create(rd)
 	if err != nil {
-		log.Err(err, "failed to send an ACK message to "+device.Name)
+		deviceDriver.Log().Error("failed to create a raw data from " + device.Name)
+		sess.Acknowledge(additionalData, err)
 		return err
 	}
 
-	rawDatas := driver.GetRawDatas(deviceDriver, msg, conn.PrevData)
-
-	for _, rawData := range rawDatas {
-		rd := &model.RawData{
-			ConnString: conn.ConnString,
-			DeviceID:   device.ID,
-			Data:       []byte(rawData),
-			Processed:  true,
+	for _, labData := range labDatas {
+		labData.RawDataID = rd.ID
+		labData.DeviceID = device.ID
+		if a.ResultDelivery != nil {
+			labData.QueueDelivery()
 		}
+	}
 
-		labDatas, additionalData, err := deviceDriver.Unmarshal(rawData)
+	if rd.Processed {
+		err = deviceDriver.Store().LabDataStore.CreateAll(labDatas)
 		if err != nil {
-			deviceDriver.Log().Error("failed to unmarshal a raw data from " + device.Name)
+			deviceDriver.Log().Err(err, fmt.Sprintf("failed to create the lab datas from %s", device.Name))
+			processErr = err
 			rd.Processed = false
-		}
 
-		err = deviceDriver.Store().RawDataStore.Create(rd)
-		if err != nil {
-			deviceDriver.Log().Error("failed to create a raw data from " + device.Name)
-			return err
+			err = deviceDriver.Store().RawDataStore.Update(rd)
+			if err != nil {
+				deviceDriver.Log().Error("failed to update a raw data from " + device.Name)
+			}
 		}
+	}
 
-		// TODO: Check if is query
-		if labDatas == nil {
-			deviceDriver.PostUnmarshalActions(conn.Conn, additionalData)
-		}
+	a.Pipeline.Stored(rd, labDatas, sess.Orders)
 
-		for _, labData := range labDatas {
-			labData.RawDataID = rd.ID
-			labData.DeviceID = device.ID
+	a.DeviceStatus.MessageReceived(device.ID)
+	if processErr != nil {
+		a.DeviceStatus.Failed(device.ID, processErr)
+	}
 
-			err = deviceDriver.Store().LabDataStore.Create(labData)
-			if err != nil {
-				deviceDriver.Log().Error(fmt.Sprintf("failed to create a lab data from %s with barcode %s and index %d", device.Name, labData.Barcode, labData.Index))
-				rd.Processed = false
-				continue
-			}
+	err = sess.Acknowledge(additionalData, processErr)
+	if err != nil {
+		deviceDriver.Log().Err(err, "failed to acknowledge a raw data from "+device.Name)
+	}
 
-			if rd.Processed {
-				deviceDriver.PostUnmarshalActions(conn.Conn, additionalData)
-			}
-		}
+	if rd.Processed {
+		sess.PostUnmarshalActions(additionalData)
 	}
 
 	return nil
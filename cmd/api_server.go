@@ -29,9 +29,7 @@ func apiServerCommand(cmd *cobra.Command, args []string) error {
 		srv.Log.Fatal("failed to start the API server")
 	}
 
-	defer srv.Stop()
-
 	waitForShutdown()
 
-	return nil
+	return stopServer(srv)
 }
@@ -29,9 +29,7 @@ func deviceServerCommand(cmd *cobra.Command, args []string) error {
 		srv.Log.Fatal("failed to start the Device server")
 	}
 
-	defer srv.Stop()
-
 	waitForShutdown()
 
-	return nil
+	return stopServer(srv)
 }
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/reprocess"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// reprocessDateFormat is the date format of the reprocess command flags.
+const reprocessDateFormat = "2006-01-02"
+
+// ReprocessCommand is the command to reprocess the stored raw datas.
+var ReprocessCommand = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Reprocess the stored raw datas",
+	Long: "This command re-parses the selected raw datas with the current drivers of their devices and replaces their lab datas.\n" +
+		"The new lab datas are reconciled with the orders and their critical alerts and events are recorded as for the received ones.\n" +
+		"The dates are in the " + reprocessDateFormat + " or RFC 3339 format, --to is exclusive.",
+	RunE: reprocessCommand,
+}
+
+// init sets the flags of the reprocess command.
+func init() {
+	ReprocessCommand.Flags().UintSlice("id", nil, "IDs of the raw datas")
+	ReprocessCommand.Flags().Uint("device", 0, "ID of the device")
+	ReprocessCommand.Flags().String("from", "", "received at or after the date")
+	ReprocessCommand.Flags().String("to", "", "received before the date")
+	ReprocessCommand.Flags().Bool("unprocessed", false, "only the raw datas which failed to be processed")
+	ReprocessCommand.Flags().Bool("push", false, "queue the new lab datas for the delivery to the HIS")
+}
+
+// reprocessCommand is the function that is called when the reprocess command is executed.
+func reprocessCommand(cmd *cobra.Command, args []string) error {
+	filter, err := getRawDataFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	if filter.IsEmpty() {
+		return errors.New("no raw datas selected, set at least one of --id, --device, --from, --to or --unprocessed")
+	}
+
+	push, _ := cmd.Flags().GetBool("push")
+
+	logger, err := log.NewLogger()
+	if err != nil {
+		return err
+	}
+
+	store, err := store.NewStore(logger)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := reprocess.NewPipeline(logger, store)
+	if err != nil {
+		return err
+	}
+
+	results, err := reprocess.NewReprocessor(logger, store, push, pipeline).Reprocess(filter)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Processed || result.Error != "" {
+			failed++
+			fmt.Printf("raw data %d (device %d): failed: %s\n", result.RawDataID, result.DeviceID, result.Error)
+			continue
+		}
+		fmt.Printf("raw data %d (device %d): %d lab datas\n", result.RawDataID, result.DeviceID, result.LabDatas)
+	}
+	fmt.Printf("reprocessed %d raw datas, %d failed\n", len(results), failed)
+
+	return nil
+}
+
+// getRawDataFilter gets the raw data filter from the flags of the command.
+func getRawDataFilter(cmd *cobra.Command) (*store.RawDataFilter, error) {
+	filter := &store.RawDataFilter{}
+	filter.IDs, _ = cmd.Flags().GetUintSlice("id")
+	filter.DeviceID, _ = cmd.Flags().GetUint("device")
+	filter.UnprocessedOnly, _ = cmd.Flags().GetBool("unprocessed")
+
+	for name, date := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value, _ := cmd.Flags().GetString(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := parseReprocessDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %v", name, value)
+		}
+		*date = &t
+	}
+
+	return filter, nil
+}
+
+// parseReprocessDate parses the date of a flag, a date in the local time zone or an RFC 3339 time.
+func parseReprocessDate(value string) (time.Time, error) {
+	t, err := time.ParseInLocation(reprocessDateFormat, value, time.Local)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
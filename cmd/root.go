@@ -7,10 +7,15 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/voidmaindev/doctra_lis_middleware/server"
 )
 
+// serverStopTimeout is the time the server gets to finish its work in progress when it is stopped.
+const serverStopTimeout = 30 * time.Second
+
 // rootCmd is the root command for the CLI tool.
 var rootCmd = &cobra.Command{
 	Use:   "",
@@ -22,6 +27,8 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(ApiServerCommand)
 	rootCmd.AddCommand(DeviceServerCommand)
+	rootCmd.AddCommand(ReprocessCommand)
+	rootCmd.AddCommand(SimulateCommand)
 }
 
 // Execute executes the CLI tool.
@@ -35,6 +42,16 @@ func waitForShutdown() {
 	signal.Notify(interruptChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	<-interruptChan
+	signal.Stop(interruptChan)
 
 	fmt.Println("shutting down...")
 }
+
+// stopServer stops the server, giving it serverStopTimeout to finish its work in progress.
+// A second shutdown signal kills the process at once.
+func stopServer(srv *server.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), serverStopTimeout)
+	defer cancel()
+
+	return srv.Stop(ctx)
+}
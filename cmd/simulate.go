@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/simulator"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
+)
+
+// SimulateCommand is the command to simulate an analyzer.
+var SimulateCommand = &cobra.Command{
+	Use:   "simulate <fixture>",
+	Short: "Simulate an analyzer",
+	Long: "This command plays the instrument side of a device protocol and replays the conversation of the fixture file against the device server.\n" +
+		"By default it connects to the device server address of the config, --listen waits for the device server in the client mode,\n" +
+		"--serial uses a serial port and --pty creates a pseudo-terminal linked to the path for a device in the serial mode.",
+	Args: cobra.ExactArgs(1),
+	RunE: simulateCommand,
+}
+
+// init sets the flags of the simulate command.
+func init() {
+	SimulateCommand.Flags().String("protocol", simulator.ProtocolASTM, "protocol of the fixture: astm, hl7, text or script")
+	SimulateCommand.Flags().String("address", "", "address of the device server (default: the device server config)")
+	SimulateCommand.Flags().String("listen", "", "address to accept the device server connection on")
+	SimulateCommand.Flags().String("serial", "", "serial port")
+	SimulateCommand.Flags().Int("baud", 0, "baud rate of the serial port (default 9600)")
+	SimulateCommand.Flags().Int("data-bits", 0, "data bits of the serial port (default 8)")
+	SimulateCommand.Flags().String("parity", "", "parity of the serial port: none, odd or even (default none)")
+	SimulateCommand.Flags().Int("stop-bits", 0, "stop bits of the serial port (default 1)")
+	SimulateCommand.Flags().String("flow-control", "", "flow control of the serial port: none, rtscts or xonxoff (default none)")
+	SimulateCommand.Flags().String("pty", "", "path of the link to a new pseudo-terminal")
+	SimulateCommand.Flags().Duration("timeout", 10*time.Second, "time to wait for the device server")
+}
+
+// simulateCommand is the function that is called when the simulate command is executed.
+func simulateCommand(cmd *cobra.Command, args []string) error {
+	fixture, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read the fixture: %v", err)
+	}
+
+	conn, err := openSimulatorConn(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	protocol, _ := cmd.Flags().GetString("protocol")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	err = simulator.NewSimulator(conn, os.Stdout, timeout).Run(protocol, string(fixture))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("simulation completed")
+
+	return nil
+}
+
+// openSimulatorConn opens the connection to the device server selected by the flags.
+func openSimulatorConn(cmd *cobra.Command) (transport.Conn, error) {
+	flags := cmd.Flags()
+	timeout, _ := flags.GetDuration("timeout")
+
+	if path, _ := flags.GetString("pty"); path != "" {
+		return openSimulatorPTY(path)
+	}
+
+	if port, _ := flags.GetString("serial"); port != "" {
+		serialConfig := transport.SerialConfig{Port: port}
+		serialConfig.BaudRate, _ = flags.GetInt("baud")
+		serialConfig.DataBits, _ = flags.GetInt("data-bits")
+		serialConfig.Parity, _ = flags.GetString("parity")
+		serialConfig.StopBits, _ = flags.GetInt("stop-bits")
+		serialConfig.FlowControl, _ = flags.GetString("flow-control")
+
+		fmt.Println("opening " + serialConfig.String())
+		return transport.OpenSerial(serialConfig)
+	}
+
+	if address, _ := flags.GetString("listen"); address != "" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+
+		fmt.Println("waiting for the device server on " + address)
+		return listener.Accept()
+	}
+
+	address, _ := flags.GetString("address")
+	if address == "" {
+		settings, err := config.ReadDeviceServerConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the device server config, set --address: %v", err)
+		}
+		address = net.JoinHostPort(settings.Host, settings.Port)
+	}
+
+	fmt.Println("connecting to " + address)
+	return net.DialTimeout("tcp", address, timeout)
+}
+
+// openSimulatorPTY creates a pseudo-terminal linked to the path and waits until the device server opened it.
+func openSimulatorPTY(path string) (transport.Conn, error) {
+	master, slave, err := transport.OpenPTY()
+	if err != nil {
+		return nil, err
+	}
+
+	// a link left by a previous run is replaced, any other file is kept
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(path)
+	}
+
+	err = os.Symlink(slave, path)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("failed to link the pseudo-terminal: %v", err)
+	}
+
+	fmt.Printf("%s is linked to %s, press Enter when the device server opened it\n", path, slave)
+	bufio.NewReader(os.Stdin).ReadString('\n')
+
+	return &ptyConn{File: master, link: path}, nil
+}
+
+// ptyConn is the master side of the pseudo-terminal which removes its link on close.
+type ptyConn struct {
+	*os.File
+	link string
+}
+
+// Close closes the pseudo-terminal and removes its link.
+func (c *ptyConn) Close() error {
+	os.Remove(c.link)
+
+	return c.File.Close()
+}
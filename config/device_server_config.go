@@ -5,9 +5,111 @@ const deviceServerConfigFile = "config/device_server_config.json"
 
 // DeviceServerSettings is the struct that holds the log settings
 type DeviceServerSettings struct {
-	Host       string
-	Port       string
-	DBSettings *DBSettings
+	Host           string
+	Port           string
+	ResultPush     ResultPushSettings
+	CriticalAlerts CriticalAlertSettings
+	Query          QuerySettings
+	QueryBackends  map[string]QueryBackendSettings
+	Events         EventSettings
+	DeviceStatus   DeviceStatusSettings
+	DBSettings     *DBSettings
+}
+
+// ResultPushSettings is the struct that holds the settings of the result delivery to the HIS
+// A push not answered in TimeoutSeconds fails and is retried with the backoff
+type ResultPushSettings struct {
+	Enabled           bool
+	Host              string
+	IntervalSeconds   int
+	MaxBackoffSeconds int
+	BatchSize         int
+	TimeoutSeconds    int
+}
+
+// CriticalAlertSettings is the struct that holds the settings of the critical result alerts sent to the webhook
+// The alerts are stored with the critical lab datas, Enabled sends them to the webhook
+// The webhook requests are signed with HMAC-SHA256 of the Secret, which must be set when Enabled, a request not answered in TimeoutSeconds fails and is retried with the backoff
+type CriticalAlertSettings struct {
+	Enabled           bool
+	WebhookURL        string
+	Secret            string
+	IntervalSeconds   int
+	MaxBackoffSeconds int
+	TimeoutSeconds    int
+}
+
+// QuerySettings is the struct that holds the settings of the host queries sent to the HIS
+// The circuit breaker opens after BreakerFailures failed queries in a row and rejects the queries for BreakerCooldownSeconds
+type QuerySettings struct {
+	TimeoutSeconds            int
+	Retries                   int
+	RetryWaitMilliseconds     int
+	BreakerFailures           int
+	BreakerCooldownSeconds    int
+	CacheSeconds              int
+	MetricsLogIntervalSeconds int
+}
+
+// QueryBackendSettings is the struct that holds the settings of a backend answering the host queries, a device selects it by its name
+// Type is "doctra" (the Doctra JSON API at URL), "http" (a generic HTTP/JSON API), "sql" (a query against the HIS database) or "csv" (a static worklist File)
+// For "http" the {barcode} and {serial} placeholders of URL and Body are replaced with the query values,
+// the indicators are read from the array at the dot separated IndicatorsPath of the response, the paths of their fields default to the JSON names of the indicator fields
+// For "sql" the SQL of the DriverName database at DSN gets the @barcode and @serial named parameters and returns the columns named as the JSON names of the indicator fields,
+// the date columns cast to text (see services.SQLQueryBackend)
+type QueryBackendSettings struct {
+	Type                  string
+	URL                   string
+	Method                string
+	Headers               map[string]string
+	Body                  string
+	IndicatorsPath        string
+	IndicatorField        string
+	MaterialField         string
+	DilutionField         string
+	BarcodeField          string
+	PriorityField         string
+	SpecimenTypeField     string
+	CollectionTimeField   string
+	PatientIDField        string
+	PatientNameField      string
+	PatientSexField       string
+	PatientBirthDateField string
+	DriverName            string
+	DSN                   string
+	SQL                   string
+	File                  string
+}
+
+// EventSettings is the struct that holds the settings of the events written for the live stream of the API server
+// The events older than RetentionHours are deleted
+type EventSettings struct {
+	Enabled        bool
+	RetentionHours int
+}
+
+// DeviceStatusSettings is the struct that holds the settings of the connection states of the devices
+// The counters and the times of the received data are saved every FlushIntervalSeconds, the connections and the disconnections at once
+type DeviceStatusSettings struct {
+	FlushIntervalSeconds int
+	IdleAlerts           IdleAlertSettings
+}
+
+// IdleAlertSettings is the struct that holds the settings of the alerts of the devices sending nothing during the working hours
+// A device is idle after IdleMinutes without data counted from the start of the working hours at the earliest
+// WorkingDays are the days of the week from 0 (Sunday) to 6 (Saturday), every day if empty
+// WorkStart and WorkEnd are "HH:MM" times of the working hours, all day if empty
+// The alerts are logged, written to the events and posted to WebhookURL if it is set, signed with HMAC-SHA256 of the secret
+// A webhook request not answered in TimeoutSeconds fails, it is not retried
+type IdleAlertSettings struct {
+	Enabled        bool
+	IdleMinutes    int
+	WorkingDays    []int
+	WorkStart      string
+	WorkEnd        string
+	WebhookURL     string
+	Secret         string
+	TimeoutSeconds int
 }
 
 // ReadDeviceServerConfig reads the log configuration file
@@ -3,7 +3,6 @@ package driver
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
 	"github.com/voidmaindev/doctra_lis_middleware/driver/driver_astm"
@@ -16,6 +15,7 @@ import (
 	"github.com/voidmaindev/doctra_lis_middleware/services"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
 	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 // Driver is the interface for the driver of the laboratory device.
@@ -25,10 +25,13 @@ type Driver interface {
 	RawDataStartString() string
 	RawDataEndString() string
 	DataToBeReplaced() map[string]string
+	UnwrapFrames(transport.Conn, string, *tcp.PrevData) (string, error)
 	Unmarshal(string) ([]*model.LabData, map[string]interface{}, error)
-	SendSimpleACK(net.Conn) error
+	SenderID(string) string
+	SendSimpleACK(transport.Conn) error
 	ReceivedSimpleACK(msg string) bool
-	PostUnmarshalActions(net.Conn, map[string]interface{}) error
+	PostUnmarshalActions(transport.Conn, map[string]interface{}) error
+	Acknowledge(transport.Conn, map[string]interface{}, error) error
 }
 
 // NewDriver creates a new driver.
@@ -1,23 +1,29 @@
 package driver_astm
 
 import (
+	"errors"
 	"fmt"
-	"net"
+	"strings"
 
 	"github.com/voidmaindev/doctra_lis_middleware/log"
 	"github.com/voidmaindev/doctra_lis_middleware/services"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 const (
 	rawDataStartString  = 0x05
 	rawDataEndString    = 0x04
 	completedDateFormat = "20060102150405"
+	birthDateFormat     = "20060102"
 	ack                 = 0x06
 	queryName           = "QRY"
 	queryMessagesName   = "MSGS"
 )
 
+// errNAK is returned when the device answers with NAK.
+var errNAK = errors.New("received NAK from device")
+
 // Driver_astm is the driver for the "ASTM" laboratory device data format.
 type Driver_astm struct {
 	log                *log.Logger
@@ -55,17 +61,33 @@ func (d *Driver_astm) RawDataEndString() string {
 }
 
 // DataToBeReplaced returns the data to be replaced.
+// CR and LF are kept, they are part of the frame checksum and terminate the records.
 func (d *Driver_astm) DataToBeReplaced() map[string]string {
-	return map[string]string{"\r": "", "\n": ""}
+	return map[string]string{}
 }
 
-// SendSimpleACK sends an ACK message.
-func (d *Driver_astm) SendSimpleACK(conn net.Conn) error {
-	_, err := conn.Write([]byte{ack})
-	if err != nil {
-		return err
+// SenderID returns the sender ID from the first component of the H record sender field.
+func (d *Driver_astm) SenderID(rawData string) string {
+	for _, record := range strings.Split(rawData, stx) {
+		record = strings.TrimLeft(record, "01234567")
+		if !strings.HasPrefix(record, "H|") {
+			continue
+		}
+
+		fields := strings.Split(record, "|")
+		if len(fields) < 5 {
+			return ""
+		}
+
+		return strings.Split(fields[4], "^")[0]
 	}
 
+	return ""
+}
+
+// SendSimpleACK sends an ACK message.
+// The ASTM link layer acknowledges every frame in UnwrapFrames, so nothing is sent here.
+func (d *Driver_astm) SendSimpleACK(conn transport.Conn) error {
 	return nil
 }
 
@@ -74,8 +96,13 @@ func (d *Driver_astm) ReceivedSimpleACK(msg string) bool {
 	return msg == fmt.Sprintf("%c", ack)
 }
 
+// Acknowledge acknowledges the processed message, ASTM frames are acknowledged by the link layer in UnwrapFrames.
+func (d *Driver_astm) Acknowledge(conn transport.Conn, data map[string]interface{}, processErr error) error {
+	return nil
+}
+
 // PostUnmarshalActions performs the post-unmarshal actions.
-func (d *Driver_astm) PostUnmarshalActions(conn net.Conn, data map[string]interface{}) error {
+func (d *Driver_astm) PostUnmarshalActions(conn transport.Conn, data map[string]interface{}) error {
 	err := d.doQuery(conn, data)
 	if err != nil {
 		d.log.Error("failed to do the query action")
@@ -86,7 +113,7 @@ func (d *Driver_astm) PostUnmarshalActions(conn net.Conn, data map[string]interf
 }
 
 // doQuery does the query action if the message is a query message.
-func (d *Driver_astm) doQuery(conn net.Conn, data map[string]interface{}) error {
+func (d *Driver_astm) doQuery(conn transport.Conn, data map[string]interface{}) error {
 	query, ok := data[queryName]
 	if !ok {
 		return nil
@@ -119,21 +146,10 @@ func (d *Driver_astm) doQuery(conn net.Conn, data map[string]interface{}) error
 		return err
 	}
 
-	for i, msg := range formattedMessages {
-		msgForChecksum := fmt.Sprintf("%d", i+1) + msg + cr + etx
-		checkSum := calculateASTMChecksum(msgForChecksum)
-
-		formattedMsg := stx + msgForChecksum + checkSum + cr + lf
-
-		err := SendToConn(conn, []byte(formattedMsg))
+	for _, frame := range BuildFrames(formattedMessages) {
+		err := sendFrame(conn, frame)
 		if err != nil {
-			d.log.Err(err, fmt.Sprintf("failed to send the message: %v", msg))
-			return err
-		}
-
-		err = getAckFromDevice(conn)
-		if err != nil {
-			d.log.Err(err, "failed to get an ACK from the device")
+			d.log.Err(err, fmt.Sprintf("failed to send the frame: %q", frame))
 			return err
 		}
 	}
@@ -150,8 +166,8 @@ func (d *Driver_astm) doQuery(conn net.Conn, data map[string]interface{}) error
 func calculateASTMChecksum(content string) string {
 	sum := 0
 
-	for _, char := range content {
-		sum += int(char)
+	for i := 0; i < len(content); i++ {
+		sum += int(content[i])
 	}
 
 	checksum := sum % 0x100
@@ -160,7 +176,7 @@ func calculateASTMChecksum(content string) string {
 }
 
 // SendToConn sends the message to the connection.
-func SendToConn(conn net.Conn, msg []byte) error {
+func SendToConn(conn transport.Conn, msg []byte) error {
 	_, err := conn.Write(msg)
 	if err != nil {
 		return err
@@ -170,7 +186,7 @@ func SendToConn(conn net.Conn, msg []byte) error {
 }
 
 // getAckFromDevice waits for an ACK message from the device.
-func getAckFromDevice(conn net.Conn) error {
+func getAckFromDevice(conn transport.Conn) error {
 	buf := make([]byte, 1<<5)
 
 	n, err := conn.Read(buf)
@@ -178,7 +194,11 @@ func getAckFromDevice(conn net.Conn) error {
 		return err
 	}
 
-	if buf[0] != byte(ack) {
+	if n > 0 && buf[0] == nak {
+		return errNAK
+	}
+
+	if n == 0 || buf[0] != byte(ack) {
 		return fmt.Errorf("expected ACK from device, got: \"%v\"", buf[:n])
 	}
 
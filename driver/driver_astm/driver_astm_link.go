@@ -0,0 +1,211 @@
+package driver_astm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
+)
+
+const (
+	nak               = 0x15
+	maxFrameTextSize  = 240
+	maxFrameRetries   = 6
+	frameNumberModulo = 8
+)
+
+// UnwrapFrames implements the receiving side of the ASTM E1381 link layer.
+// Every complete frame is checked for its number and checksum and answered with ACK or NAK,
+// the records carried by the accepted frames are returned prefixed with STX, so that records split by ETB are joined again.
+// ENQ and EOT are passed through, an incomplete frame is kept in prds until the next read.
+func (d *Driver_astm) UnwrapFrames(conn transport.Conn, msg string, prds *tcp.PrevData) (string, error) {
+	data := prds.Frame + msg
+	prds.Frame = ""
+
+	var records strings.Builder
+	for len(data) > 0 {
+		switch data[0] {
+		case rawDataStartString:
+			prds.FrameNumber = 1
+			prds.RecordOpen = false
+			records.WriteByte(rawDataStartString)
+			data = data[1:]
+
+			err := SendToConn(conn, []byte{ack})
+			if err != nil {
+				d.log.Err(err, "failed to send an ACK for the ENQ")
+				return records.String(), err
+			}
+		case rawDataEndString:
+			prds.RecordOpen = false
+			records.WriteByte(rawDataEndString)
+			data = data[1:]
+		case stx[0]:
+			frameEnd, complete := findFrameEnd(data)
+			if !complete {
+				prds.Frame = data
+				data = ""
+				continue
+			}
+
+			reply := byte(ack)
+			text, ok := d.acceptFrame(data[:frameEnd], prds)
+			if ok {
+				writeFrameRecords(&records, text, data[frameEnd-3:frameEnd-2] == etx, prds)
+			} else {
+				reply = nak
+			}
+			data = data[frameEnd:]
+
+			err := SendToConn(conn, []byte{reply})
+			if err != nil {
+				d.log.Err(err, "failed to answer the frame")
+				return records.String(), err
+			}
+		default:
+			// CR, LF after the checksum and any noise between frames
+			data = data[1:]
+		}
+	}
+
+	return records.String(), nil
+}
+
+// findFrameEnd finds the end of the frame starting at the beginning of the data.
+// A frame is complete when its ETB or ETX is followed by both checksum characters.
+// A frame cut by a new STX is returned as complete so that it fails the checksum check.
+func findFrameEnd(data string) (int, bool) {
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case etb[0], etx[0]:
+			if len(data) < i+3 {
+				return 0, false
+			}
+			return i + 3, true
+		case stx[0]:
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// acceptFrame validates the frame number and the checksum of the frame and returns its text without the frame number.
+// A repeated frame that was already accepted is acknowledged again but its text is not returned twice.
+func (d *Driver_astm) acceptFrame(frame string, prds *tcp.PrevData) (string, bool) {
+	if len(frame) < 5 {
+		d.log.Error(fmt.Sprintf("received a malformed ASTM frame: %q", frame))
+		return "", false
+	}
+
+	content := frame[1 : len(frame)-2]
+	checksum := frame[len(frame)-2:]
+	if calculateASTMChecksum(content) != strings.ToUpper(checksum) {
+		d.log.Error(fmt.Sprintf("received an ASTM frame with wrong checksum %s: %q", checksum, frame))
+		return "", false
+	}
+
+	frameNumber := int(content[0] - '0')
+	if frameNumber == (prds.FrameNumber+frameNumberModulo-1)%frameNumberModulo {
+		d.log.Info(fmt.Sprintf("received a repeated ASTM frame %d", frameNumber))
+		return "", true
+	}
+	if frameNumber != prds.FrameNumber {
+		d.log.Error(fmt.Sprintf("received an ASTM frame %d out of sequence, expected %d", frameNumber, prds.FrameNumber))
+		return "", false
+	}
+	prds.FrameNumber = (prds.FrameNumber + 1) % frameNumberModulo
+
+	return content[1 : len(content)-1], true
+}
+
+// writeFrameRecords writes the records of the frame text, every record starting with STX.
+// A record is closed by CR or by the end of the last frame (ETX), otherwise it is continued by the next frame.
+func writeFrameRecords(records *strings.Builder, text string, last bool, prds *tcp.PrevData) {
+	for len(text) > 0 {
+		if !prds.RecordOpen {
+			records.WriteString(stx)
+			prds.RecordOpen = true
+		}
+
+		end := strings.Index(text, cr)
+		if end == -1 {
+			records.WriteString(strings.TrimRight(text, lf))
+			break
+		}
+
+		records.WriteString(text[:end])
+		prds.RecordOpen = false
+		text = strings.TrimLeft(text[end+1:], lf)
+	}
+
+	if last {
+		prds.RecordOpen = false
+	}
+}
+
+// BuildFrames builds the E1381 frames for the records.
+// Records longer than the maximum frame text size are split into intermediate frames ending with ETB.
+func BuildFrames(records []string) []string {
+	frames := []string{}
+	frameNumber := 1
+
+	for _, record := range records {
+		text := record + cr
+		for len(text) > 0 {
+			size := min(len(text), maxFrameTextSize)
+			terminator := etx
+			if size < len(text) {
+				terminator = etb
+			}
+
+			content := fmt.Sprintf("%d", frameNumber) + text[:size] + terminator
+			frames = append(frames, stx+content+calculateASTMChecksum(content)+cr+lf)
+
+			frameNumber = (frameNumber + 1) % frameNumberModulo
+			text = text[size:]
+		}
+	}
+
+	return frames
+}
+
+// ParseFrame validates the checksum of the complete frame and returns its number and text.
+// last is set for the frame ending with ETX.
+func ParseFrame(frame string) (number int, text string, last bool, err error) {
+	frame = strings.TrimRight(frame, cr+lf)
+	if len(frame) < 5 || frame[:1] != stx {
+		return 0, "", false, fmt.Errorf("malformed ASTM frame: %q", frame)
+	}
+
+	content := frame[1 : len(frame)-2]
+	checksum := frame[len(frame)-2:]
+	if calculateASTMChecksum(content) != strings.ToUpper(checksum) {
+		return 0, "", false, fmt.Errorf("wrong checksum %s of ASTM frame: %q", checksum, frame)
+	}
+
+	terminator := content[len(content)-1:]
+	if terminator != etx && terminator != etb {
+		return 0, "", false, fmt.Errorf("malformed ASTM frame: %q", frame)
+	}
+
+	return int(content[0] - '0'), content[1 : len(content)-1], terminator == etx, nil
+}
+
+// sendFrame sends the frame to the device and retransmits it while the device answers with NAK.
+func sendFrame(conn transport.Conn, frame string) error {
+	for i := 0; i < maxFrameRetries; i++ {
+		err := SendToConn(conn, []byte(frame))
+		if err != nil {
+			return err
+		}
+
+		err = getAckFromDevice(conn)
+		if err != errNAK {
+			return err
+		}
+	}
+
+	return fmt.Errorf("frame was not accepted after %d attempts", maxFrameRetries)
+}
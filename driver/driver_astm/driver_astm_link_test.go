@@ -0,0 +1,228 @@
+package driver_astm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+)
+
+// testConn records the bytes written by the driver.
+type testConn struct {
+	written bytes.Buffer
+}
+
+func (c *testConn) Read(b []byte) (int, error)  { return 0, nil }
+func (c *testConn) Write(b []byte) (int, error) { return c.written.Write(b) }
+func (c *testConn) Close() error                { return nil }
+
+// testFrame builds a frame with the number, the text and the terminator and a valid checksum.
+func testFrame(number int, text string, terminator string) string {
+	content := fmt.Sprint(number) + text + terminator
+
+	return stx + content + calculateASTMChecksum(content) + cr + lf
+}
+
+// replies returns the ACK and NAK written to the connection as "A" and "N".
+func replies(conn *testConn) string {
+	return strings.NewReplacer(string(rune(ack)), "A", string(rune(nak)), "N").Replace(conn.written.String())
+}
+
+func newTestDriver() *Driver_astm {
+	return NewDriver(&log.Logger{Disabled: true}, nil, nil)
+}
+
+func TestUnwrapFrames(t *testing.T) {
+	enq := string(rune(rawDataStartString))
+	eot := string(rune(rawDataEndString))
+	badChecksum := strings.Replace(testFrame(1, "H|\\^&"+cr, etx), calculateASTMChecksum("1H|\\^&"+cr+etx), "00", 1)
+
+	wrapped := ""
+	wrappedRecords := ""
+	for i := 1; i <= 9; i++ {
+		wrapped += testFrame(i%frameNumberModulo, fmt.Sprintf("R|%d", i)+cr, etx)
+		wrappedRecords += stx + fmt.Sprintf("R|%d", i)
+	}
+
+	tests := []struct {
+		name    string
+		msg     string
+		records string
+		replies string
+	}{
+		{
+			name:    "single frame",
+			msg:     enq + testFrame(1, "H|\\^&"+cr, etx) + eot,
+			records: enq + stx + "H|\\^&" + eot,
+			replies: "AA",
+		},
+		{
+			name:    "several records in a frame",
+			msg:     enq + testFrame(1, "H|\\^&"+cr+"L|1|N"+cr, etx),
+			records: enq + stx + "H|\\^&" + stx + "L|1|N",
+			replies: "AA",
+		},
+		{
+			name:    "wrong checksum",
+			msg:     enq + badChecksum,
+			records: enq,
+			replies: "AN",
+		},
+		{
+			name:    "lower case checksum",
+			msg:     enq + stx + "1P|1" + cr + etx + "3e" + cr + lf,
+			records: enq + stx + "P|1",
+			replies: "AA",
+		},
+		{
+			name:    "frame out of sequence",
+			msg:     enq + testFrame(2, "H|\\^&"+cr, etx),
+			records: enq,
+			replies: "AN",
+		},
+		{
+			name:    "repeated frame",
+			msg:     enq + testFrame(1, "H|\\^&"+cr, etx) + testFrame(1, "H|\\^&"+cr, etx) + testFrame(2, "L|1"+cr, etx),
+			records: enq + stx + "H|\\^&" + stx + "L|1",
+			replies: "AAAA",
+		},
+		{
+			name:    "frame numbers wrap modulo 8",
+			msg:     enq + wrapped,
+			records: enq + wrappedRecords,
+			replies: "A" + strings.Repeat("A", 9),
+		},
+		{
+			name:    "intermediate frames joined",
+			msg:     enq + testFrame(1, "R|1|^^^GLU|5.", etb) + testFrame(2, "5|mmol/L"+cr, etx),
+			records: enq + stx + "R|1|^^^GLU|5.5|mmol/L",
+			replies: "AAA",
+		},
+		{
+			name:    "frame cut by a new frame",
+			msg:     enq + testFrame(1, "H|\\^&"+cr, etx)[:5] + testFrame(1, "H|\\^&"+cr, etx),
+			records: enq + stx + "H|\\^&",
+			replies: "ANA",
+		},
+		{
+			name:    "malformed frame",
+			msg:     enq + stx + "1" + etx + "00",
+			records: enq,
+			replies: "AN",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &testConn{}
+			records, err := newTestDriver().UnwrapFrames(conn, test.msg, &tcp.PrevData{})
+			if err != nil {
+				t.Fatalf("UnwrapFrames() error = %v", err)
+			}
+			if records != test.records {
+				t.Errorf("UnwrapFrames() records = %q, want %q", records, test.records)
+			}
+			if got := replies(conn); got != test.replies {
+				t.Errorf("UnwrapFrames() replies = %q, want %q", got, test.replies)
+			}
+		})
+	}
+}
+
+func TestUnwrapFramesSplitAcrossReads(t *testing.T) {
+	frame := testFrame(1, "R|1|^^^GLU|5.5"+cr, etx)
+
+	for split := 1; split < len(frame); split++ {
+		conn := &testConn{}
+		prds := &tcp.PrevData{}
+		d := newTestDriver()
+
+		first, err := d.UnwrapFrames(conn, string(rune(rawDataStartString))+frame[:split], prds)
+		if err != nil {
+			t.Fatalf("split %d: UnwrapFrames() error = %v", split, err)
+		}
+		second, err := d.UnwrapFrames(conn, frame[split:], prds)
+		if err != nil {
+			t.Fatalf("split %d: UnwrapFrames() error = %v", split, err)
+		}
+
+		records := first + second
+		want := string(rune(rawDataStartString)) + stx + "R|1|^^^GLU|5.5"
+		if records != want {
+			t.Errorf("split %d: records = %q, want %q", split, records, want)
+		}
+		if got := replies(conn); got != "AA" {
+			t.Errorf("split %d: replies = %q, want %q", split, got, "AA")
+		}
+		if prds.Frame != "" {
+			t.Errorf("split %d: incomplete frame %q left", split, prds.Frame)
+		}
+	}
+}
+
+func TestBuildFrames(t *testing.T) {
+	long := "R|1|^^^GLU|" + strings.Repeat("9", 2*maxFrameTextSize)
+	records := []string{"H|\\^&", long, "L|1|N"}
+
+	frames := BuildFrames(records)
+
+	text := ""
+	for i, frame := range frames {
+		number, frameText, last, err := ParseFrame(frame)
+		if err != nil {
+			t.Fatalf("ParseFrame(frame %d) error = %v", i, err)
+		}
+		if want := (i + 1) % frameNumberModulo; number != want {
+			t.Errorf("frame %d number = %d, want %d", i, number, want)
+		}
+		if len(frameText) > maxFrameTextSize {
+			t.Errorf("frame %d text size = %d, want at most %d", i, len(frameText), maxFrameTextSize)
+		}
+		if wantLast := strings.HasSuffix(frameText, cr); last != wantLast {
+			t.Errorf("frame %d last = %v, want %v", i, last, wantLast)
+		}
+		text += frameText
+	}
+
+	if want := strings.Join(records, cr) + cr; text != want {
+		t.Errorf("frames text = %q, want %q", text, want)
+	}
+	if len(frames) != 5 {
+		t.Errorf("frames = %d, want 5", len(frames))
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		number  int
+		text    string
+		last    bool
+		wantErr bool
+	}{
+		{name: "last frame", frame: testFrame(3, "L|1"+cr, etx), number: 3, text: "L|1" + cr, last: true},
+		{name: "intermediate frame", frame: testFrame(0, "R|1|^^^", etb), number: 0, text: "R|1|^^^"},
+		{name: "wrong checksum", frame: stx + "1L|1" + cr + etx + "FF", wantErr: true},
+		{name: "no terminator", frame: stx + "1L|1" + calculateASTMChecksum("1L|1"), wantErr: true},
+		{name: "too short", frame: stx + "1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			number, text, last, err := ParseFrame(test.frame)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseFrame() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if number != test.number || text != test.text || last != test.last {
+				t.Errorf("ParseFrame() = %d, %q, %v, want %d, %q, %v", number, text, last, test.number, test.text, test.last)
+			}
+		})
+	}
+}
@@ -84,13 +84,22 @@ func (d *Driver_astm) Unmarshal(rawData string) (labDatas []*model.LabData, addi
 				return labDatas, nil, err
 			}
 
+			abnormalFlag, instrumentFlags := getFlagsForUnmarshalRawData(result)
+
 			labData := &model.LabData{
-				Barcode:       barcode,
-				Index:         index,
-				Param:         param,
-				Result:        res,
-				Unit:          unit,
-				CompletedDate: completedDate,
+				Barcode:          barcode,
+				Index:            index,
+				Param:            param,
+				Result:           res,
+				Unit:             unit,
+				CompletedDate:    completedDate,
+				ReferenceRange:   getReferenceRangeForUnmarshalRawData(result),
+				AbnormalFlag:     abnormalFlag,
+				ResultStatus:     model.NormalizeResultStatus(result.ResultStatus),
+				InstrumentFlags:  instrumentFlags,
+				PatientID:        getPatientIDForUnmarshalRawData(astm_msg.Patient),
+				PatientSex:       getPatientSexForUnmarshalRawData(astm_msg.Patient),
+				PatientBirthDate: getPatientBirthDateForUnmarshalRawData(astm_msg.Patient),
 			}
 
 			labDatas = append(labDatas, labData)
@@ -143,6 +152,54 @@ func getUnitForUnmarshalRawData(result Result) (string, error) {
 	return unit, nil
 }
 
+// getReferenceRangeForUnmarshalRawData gets the reference range for unmarshalling the raw data.
+// The lower and upper limits are separated by "-" or by the component delimiter.
+func getReferenceRangeForUnmarshalRawData(result Result) string {
+	limits := strings.Split(result.ReferenceRange, "^")
+	if len(limits) == 2 {
+		return strings.Join(limits, "-")
+	}
+
+	return result.ReferenceRange
+}
+
+// getFlagsForUnmarshalRawData gets the abnormal flag and the instrument flags for unmarshalling the raw data.
+// The flags are repeated with the repeat delimiter.
+func getFlagsForUnmarshalRawData(result Result) (string, string) {
+	return model.SplitFlags(strings.Split(result.Status, "\\"))
+}
+
+// getPatientIDForUnmarshalRawData gets the patient ID for unmarshalling the raw data.
+// The practice assigned patient ID is preferred to the laboratory assigned one.
+func getPatientIDForUnmarshalRawData(patient Patient) string {
+	for _, id := range []string{patient.PracticeID, patient.LabID} {
+		if id = strings.TrimSpace(strings.Split(id, "^")[0]); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// getPatientSexForUnmarshalRawData gets the patient sex (M, F or U) for unmarshalling the raw data.
+func getPatientSexForUnmarshalRawData(patient Patient) string {
+	return strings.ToUpper(strings.TrimSpace(patient.Sex))
+}
+
+// getPatientBirthDateForUnmarshalRawData gets the patient birth date for unmarshalling the raw data.
+func getPatientBirthDateForUnmarshalRawData(patient Patient) *time.Time {
+	if len(patient.BirthDate) < len(birthDateFormat) {
+		return nil
+	}
+
+	birthDate, err := time.Parse(birthDateFormat, patient.BirthDate[:len(birthDateFormat)])
+	if err != nil {
+		return nil
+	}
+
+	return &birthDate
+}
+
 // Segment represents a parsed segment of the message
 type Segment struct {
 	Type    string
@@ -175,8 +232,12 @@ type Header struct {
 
 // Patient represents the patient segment
 type Patient struct {
-	Type string
-	ID   string
+	Type       string
+	ID         string
+	PracticeID string
+	LabID      string
+	BirthDate  string
+	Sex        string
 }
 
 // Order represents the order segment
@@ -198,6 +259,7 @@ type Result struct {
 	Units          string
 	ReferenceRange string
 	Status         string
+	ResultStatus   string
 	Timestamp      string
 }
 
@@ -369,8 +431,12 @@ func parseTimestamp(ts string) time.Time {
 func parsePatient(content string) Patient {
 	parts := strings.Split(content, "|")
 	return Patient{
-		Type: "P",
-		ID:   parts[1],
+		Type:       "P",
+		ID:         parts[1],
+		PracticeID: ifExists(parts, 2),
+		LabID:      ifExists(parts, 3),
+		BirthDate:  ifExists(parts, 7),
+		Sex:        ifExists(parts, 8),
 	}
 }
 
@@ -405,6 +471,7 @@ func parseResult(content string) Result {
 		Units:          parts[4],
 		ReferenceRange: parts[5],
 		Status:         parts[6],
+		ResultStatus:   ifExists(parts, 8),
 		Timestamp:      parts[12],
 	}
 }
@@ -505,7 +572,7 @@ func generateASTMMessagesFromQuery(queryMessages []Message, dataToReturn []servi
 			)
 			formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 
-			formattedMsg = "P|1"
+			formattedMsg = buildPatientRecord(dataToReturn)
 			formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 		}
 		if msg.Query.Type == "Q" {
@@ -528,30 +595,10 @@ func generateASTMMessagesFromQuery(queryMessages []Message, dataToReturn []servi
 			// 	formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 			// }
 
-			barcodeToSend := ""
-			for _, data := range dataToReturn {
-				prefix := ""
-				if barcodeToSend != "" {
-					prefix = "\\"
-				}
-				barcodeToSend += prefix + fmt.Sprintf("^^^%s^", data.Param)
+			for i, group := range groupQueryData(dataToReturn) {
+				formattedMsg = buildOrderRecord(i+1, msg.Query.SampleID, group)
+				formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 			}
-			order := QueryAnswerOrder{
-				ID:        fmt.Sprintf("%d", 1),
-				PatientID: msg.Query.SampleID,
-				Param:     barcodeToSend,
-				Priority:  "R",
-				Report:    "A",
-			}
-			// formattedMsg = fmt.Sprintf("O|%s|%s||^^^%s^\\^^^687|%s||||||%s||||||||||||||O\\Q",
-			formattedMsg = fmt.Sprintf("O|%s|%s||%s|%s||||||%s||||||||||||||O\\Q",
-				order.ID,
-				order.PatientID,
-				order.Param,
-				order.Priority,
-				order.Report,
-			)
-			formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 			// formattedMsg = "C|1|L|Default·TS^^^^|G"
 			// formattedMessages = addFormattedMessage(formattedMessages, formattedMsg)
 		}
@@ -569,6 +616,88 @@ func generateASTMMessagesFromQuery(queryMessages []Message, dataToReturn []servi
 	return formattedMessages
 }
 
+// buildPatientRecord builds the P record with the patient of the sample.
+func buildPatientRecord(dataToReturn []services.DeviceQueryDataToReturn) string {
+	if len(dataToReturn) == 0 {
+		return "P|1"
+	}
+
+	sample := dataToReturn[0].Sample
+	birthDate := ""
+	if sample.PatientBirthDate != nil {
+		birthDate = sample.PatientBirthDate.Format(birthDateFormat)
+	}
+
+	return strings.TrimRight(fmt.Sprintf("P|1|%s|||%s||%s|%s",
+		astmText(sample.PatientID),
+		astmText(sample.PatientName),
+		birthDate,
+		sample.PatientSex,
+	), "|")
+}
+
+// groupQueryData groups the tests which are ordered with the same priority on the same specimen type, keeping their order.
+func groupQueryData(dataToReturn []services.DeviceQueryDataToReturn) [][]services.DeviceQueryDataToReturn {
+	var groups [][]services.DeviceQueryDataToReturn
+	index := map[string]int{}
+	for _, data := range dataToReturn {
+		key := data.Priority + "|" + data.SpecimenType
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], data)
+	}
+
+	return groups
+}
+
+// buildOrderRecord builds the O record of the tests with the dilution in the component after the test code in the test ID (O-5),
+// the priority (O-6), the collection time (O-8), the action code (O-12), the specimen type (O-16) and the report type (O-26).
+func buildOrderRecord(seq int, barcode string, group []services.DeviceQueryDataToReturn) string {
+	tests := make([]string, 0, len(group))
+	for _, data := range group {
+		tests = append(tests, fmt.Sprintf("^^^%s^%s", data.Param, astmText(data.Dilution)))
+	}
+
+	collectedAt := ""
+	if sample := group[0].Sample; sample.CollectedAt != nil {
+		collectedAt = sample.CollectedAt.Format(completedDateFormat)
+	}
+
+	fields := make([]string, 26)
+	fields[0] = "O"
+	fields[1] = fmt.Sprint(seq)
+	fields[2] = barcode
+	fields[4] = strings.Join(tests, "\\")
+	fields[5] = astmPriority(group[0].Priority)
+	fields[7] = collectedAt
+	fields[11] = "A"
+	fields[15] = astmText(group[0].SpecimenType)
+	fields[25] = "O\\Q"
+
+	return strings.Join(fields, "|")
+}
+
+// astmPriority converts the order priority to the ASTM priority code.
+func astmPriority(priority string) string {
+	switch priority {
+	case model.OrderPriorityStat:
+		return "S"
+	case model.OrderPriorityASAP:
+		return "A"
+	}
+
+	return "R"
+}
+
+// astmText replaces the ASTM delimiters in a text sent to the device.
+func astmText(text string) string {
+	return strings.NewReplacer("|", " ", "\\", " ", "&", " ", "\r", " ", "\n", " ").Replace(text)
+}
+
 // addFormattedMessage adds the formatted message to the formattedMessages slice
 func addFormattedMessage(formattedMessages []string, formattedMsg string) []string {
 	// checkSum := calculateASTMChecksum(formattedMsg + cr + string(etx))
@@ -0,0 +1,96 @@
+package driver_astm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+)
+
+func TestBuildOrderRecord(t *testing.T) {
+	collectedAt := time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name   string
+		group  []services.DeviceQueryDataToReturn
+		fields map[int]string
+	}{
+		{
+			name:  "routine test",
+			group: []services.DeviceQueryDataToReturn{{Param: "GLU", Priority: model.OrderPriorityRoutine}},
+			fields: map[int]string{
+				1: "O", 2: "1", 3: "BC1", 5: "^^^GLU^", 6: "R", 8: "", 12: "A", 16: "", 26: `O\Q`,
+			},
+		},
+		{
+			name: "stat tests with dilutions on serum",
+			group: []services.DeviceQueryDataToReturn{
+				{Param: "GLU", Dilution: "10", Priority: model.OrderPriorityStat, SpecimenType: "serum", Sample: model.OrderSample{CollectedAt: &collectedAt}},
+				{Param: "ALT", Priority: model.OrderPriorityStat, SpecimenType: "serum"},
+			},
+			fields: map[int]string{
+				5: `^^^GLU^10\^^^ALT^`, 6: "S", 8: "20240501083000", 16: "serum",
+			},
+		},
+		{
+			name:   "asap test",
+			group:  []services.DeviceQueryDataToReturn{{Param: "K", Priority: model.OrderPriorityASAP}},
+			fields: map[int]string{6: "A"},
+		},
+		{
+			name:   "delimiters in the texts replaced",
+			group:  []services.DeviceQueryDataToReturn{{Param: "NA", Dilution: "1|2", SpecimenType: `urine\24h`}},
+			fields: map[int]string{5: "^^^NA^1 2", 6: "R", 16: "urine 24h"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := buildOrderRecord(1, "BC1", test.group)
+
+			// the fields are numbered from O-1, the record type
+			fields := strings.Split(record, "|")
+			if len(fields) != 26 {
+				t.Fatalf("buildOrderRecord() = %q has %d fields, want 26", record, len(fields))
+			}
+			for number, want := range test.fields {
+				if fields[number-1] != want {
+					t.Errorf("O-%d = %q, want %q in %q", number, fields[number-1], want, record)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupQueryData(t *testing.T) {
+	data := []services.DeviceQueryDataToReturn{
+		{Param: "GLU", Priority: model.OrderPriorityRoutine, SpecimenType: "serum"},
+		{Param: "K", Priority: model.OrderPriorityStat, SpecimenType: "serum"},
+		{Param: "ALT", Priority: model.OrderPriorityRoutine, SpecimenType: "serum"},
+		{Param: "PRO", Priority: model.OrderPriorityRoutine, SpecimenType: "urine"},
+		{Param: "NA", Priority: model.OrderPriorityStat, SpecimenType: "serum"},
+	}
+
+	// the groups and their tests keep the order of the first test of the group
+	want := [][]string{{"GLU", "ALT"}, {"K", "NA"}, {"PRO"}}
+
+	groups := groupQueryData(data)
+	if len(groups) != len(want) {
+		t.Fatalf("groupQueryData() = %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		params := []string{}
+		for _, d := range group {
+			params = append(params, d.Param)
+		}
+		if strings.Join(params, ",") != strings.Join(want[i], ",") {
+			t.Errorf("group %d = %v, want %v", i, params, want[i])
+		}
+	}
+
+	if groups := groupQueryData(nil); len(groups) != 0 {
+		t.Errorf("groupQueryData(nil) = %v, want no groups", groups)
+	}
+}
@@ -2,17 +2,19 @@ package driver_hl7_231
 
 import (
 	"fmt"
-	"net"
 
 	"github.com/voidmaindev/doctra_lis_middleware/log"
 	"github.com/voidmaindev/doctra_lis_middleware/services"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 const (
 	rawDataStartString  = 0xb
 	rawDataEndString    = 0x1c
 	completedDateFormat = "20060102150405"
+	birthDateFormat     = "20060102"
 )
 
 // Driver_hl7_231 is the driver for the "HL7 2.3.1" laboratory device data format.
@@ -56,8 +58,31 @@ func (d *Driver_hl7_231) DataToBeReplaced() map[string]string {
 	return map[string]string{"\\r": "\n"}
 }
 
+// UnwrapFrames returns the message as is, the format has no low-level frames.
+func (d *Driver_hl7_231) UnwrapFrames(conn transport.Conn, msg string, prds *tcp.PrevData) (string, error) {
+	return msg, nil
+}
+
+// SenderID returns the sending application (MSH-3) of the message.
+func (d *Driver_hl7_231) SenderID(rawData string) (senderID string) {
+	defer func() {
+		if r := recover(); r != nil {
+			senderID = ""
+		}
+	}()
+
+	hl7msg, err := parseHL7Message(rawData)
+	if err != nil {
+		return ""
+	}
+
+	msh := firstSegment(hl7msg, "MSH")
+
+	return firstComponent(msh, field(msh, 2))
+}
+
 // SendSimpleACK sends an ACK message.
-func (d *Driver_hl7_231) SendSimpleACK(conn net.Conn) error {
+func (d *Driver_hl7_231) SendSimpleACK(conn transport.Conn) error {
 	return nil
 }
 
@@ -67,13 +92,34 @@ func (d *Driver_hl7_231) ReceivedSimpleACK(msg string) bool {
 }
 
 // PostUnmarshalActions performs the post-unmarshal actions.
-func (d *Driver_hl7_231) PostUnmarshalActions(conn net.Conn, data map[string]interface{}) error {
-	// Send ACK
-	if ackMsg, ok := data["ACK"]; ok {
-		_, err := conn.Write([]byte(ackMsg.(string)))
-		if err != nil {
-			return err
-		}
+func (d *Driver_hl7_231) PostUnmarshalActions(conn transport.Conn, data map[string]interface{}) error {
+	err := d.doQuery(conn, data)
+	if err != nil {
+		d.log.Error("failed to do the query action")
+		return err
+	}
+
+	return nil
+}
+
+// Acknowledge sends the ACK of the processed message.
+// MSA-1 is AA when the message was stored, AE when it could not be parsed and AR when it could not be stored,
+// the failure is described in the ERR segment.
+func (d *Driver_hl7_231) Acknowledge(conn transport.Conn, data map[string]interface{}, processErr error) error {
+	ack, ok := data[ackName].(*hl7Ack)
+	if !ok {
+		return nil
+	}
+
+	// the answer of a host query acknowledges it
+	if _, isQuery := data[queryName]; isQuery && processErr == nil && ack.ParseErr == nil {
+		return nil
+	}
+
+	_, err := conn.Write([]byte(wrapMessage(ack.build(processErr))))
+	if err != nil {
+		d.log.Err(err, "failed to send the ACK message")
+		return err
 	}
 
 	return nil
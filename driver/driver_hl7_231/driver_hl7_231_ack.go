@@ -0,0 +1,51 @@
+package driver_hl7_231
+
+import "strings"
+
+const (
+	ackName                 = "ACK"
+	ackAccept               = "AA"
+	ackError                = "AE"
+	ackReject               = "AR"
+	errCodeDataType         = "102"
+	errCodeApplicationError = "207"
+	ackAcceptedText         = "Message accepted"
+)
+
+// errTextEscaper escapes the delimiters in the error text.
+var errTextEscaper = strings.NewReplacer("\\", "\\E\\", "|", "\\F\\", "^", "\\S\\", "&", "\\T\\", "~", "\\R\\")
+
+// hl7Ack holds the data needed to acknowledge a received message.
+type hl7Ack struct {
+	MSH      []string
+	ParseErr error
+}
+
+// build builds the ACK message for the received message.
+// A parse error is answered with AE, a processing error with AR so that the device sends the message again.
+func (a *hl7Ack) build(processErr error) string {
+	controlID := field(a.MSH, 9)
+
+	switch {
+	case a.ParseErr != nil:
+		return buildAck(a.MSH, ackError, controlID, errCodeDataType, a.ParseErr.Error())
+	case processErr != nil:
+		return buildAck(a.MSH, ackReject, controlID, errCodeApplicationError, processErr.Error())
+	}
+
+	return joinSegments(
+		buildMSH(a.MSH, "ACK"),
+		"MSA|"+ackAccept+"|"+controlID+"|"+ackAcceptedText,
+	)
+}
+
+// buildAck builds the negative ACK message with the ERR segment.
+func buildAck(msh []string, code, controlID, errCode, errText string) string {
+	errText = errTextEscaper.Replace(errText)
+
+	return joinSegments(
+		buildMSH(msh, "ACK"),
+		"MSA|"+code+"|"+controlID+"|"+errText,
+		"ERR|^^^"+errCode+"&"+errText,
+	)
+}
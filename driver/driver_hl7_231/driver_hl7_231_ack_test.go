@@ -0,0 +1,138 @@
+package driver_hl7_231
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testConn records the messages written by the driver.
+type testConn struct {
+	written bytes.Buffer
+}
+
+func (c *testConn) Read(b []byte) (int, error)  { return 0, nil }
+func (c *testConn) Write(b []byte) (int, error) { return c.written.Write(b) }
+func (c *testConn) Close() error                { return nil }
+
+// writtenSegment returns the segment with the name of the message written to the connection.
+func writtenSegment(conn *testConn, name string) string {
+	for _, segment := range strings.Split(conn.written.String(), segmentSeparator) {
+		segment = strings.TrimPrefix(segment, string(rune(rawDataStartString)))
+		if strings.HasPrefix(segment, name+"|") {
+			return segment
+		}
+	}
+
+	return ""
+}
+
+func TestAcknowledge(t *testing.T) {
+	oru := testMessage("ORU^R01",
+		"PID|1||P1",
+		"OBR|1||BC1||||20240102030405",
+		"OBX|1|NM|^GLU||5.5|mmol/L|3.9-6.1|N|||F",
+	)
+
+	tests := []struct {
+		name       string
+		msg        string
+		processErr error
+		wantParse  bool
+		msa        string
+		err        string
+	}{
+		{
+			name: "stored",
+			msg:  oru,
+			msa:  "MSA|AA|CTRL1|Message accepted",
+		},
+		{
+			name:       "not stored",
+			msg:        oru,
+			processErr: errors.New("failed to create the lab datas"),
+			msa:        "MSA|AR|CTRL1|failed to create the lab datas",
+			err:        "ERR|^^^207&failed to create the lab datas",
+		},
+		{
+			name:      "not parsed",
+			msg:       testMessage("ORU^R01", "OBR|1||BC1", "OBX|1|NM|^GLU||5.5|mmol/L|||||F"),
+			wantParse: true,
+			msa:       "MSA|AE|CTRL1|",
+			err:       "ERR|^^^102&",
+		},
+		{
+			name:      "query without barcode",
+			msg:       testMessage("QRY^Q02", strings.Replace(testQRD, "%s", "", 1), testQRF),
+			wantParse: true,
+			msa:       "MSA|AE|CTRL1|failed to get barcode of the query",
+			err:       "ERR|^^^102&failed to get barcode of the query",
+		},
+		{
+			name:      "order query without barcode",
+			msg:       testMessage("ORM^O01", "PID|1||P1", "ORC|NW||"),
+			wantParse: true,
+			msa:       "MSA|AE|CTRL1|failed to get barcode of the query",
+			err:       "ERR|^^^102&failed to get barcode of the query",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDriver()
+			_, additionalData, err := d.Unmarshal(test.msg)
+			if (err != nil) != test.wantParse {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, test.wantParse)
+			}
+
+			processErr := test.processErr
+			if err != nil {
+				processErr = err
+			}
+
+			conn := &testConn{}
+			err = d.Acknowledge(conn, additionalData, processErr)
+			if err != nil {
+				t.Fatalf("Acknowledge() error = %v", err)
+			}
+
+			if msh := writtenSegment(conn, "MSH"); !strings.Contains(msh, "|LIS|Hospital|Analyzer|Lab|") || !strings.Contains(msh, "|ACK|") {
+				t.Errorf("ACK MSH = %q", msh)
+			}
+			if msa := writtenSegment(conn, "MSA"); !strings.HasPrefix(msa, test.msa) {
+				t.Errorf("ACK MSA = %q, want prefix %q", msa, test.msa)
+			}
+			if errSegment := writtenSegment(conn, "ERR"); !strings.HasPrefix(errSegment, test.err) || (test.err == "") != (errSegment == "") {
+				t.Errorf("ACK ERR = %q, want prefix %q", errSegment, test.err)
+			}
+		})
+	}
+}
+
+func TestAcknowledgeAnsweredQuery(t *testing.T) {
+	d := newTestDriver()
+	_, additionalData, err := d.Unmarshal(testMessage("QRY^Q02", strings.Replace(testQRD, "%s", "BC1", 1), testQRF))
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	conn := &testConn{}
+	err = d.Acknowledge(conn, additionalData, nil)
+	if err != nil {
+		t.Fatalf("Acknowledge() error = %v", err)
+	}
+	if conn.written.Len() != 0 {
+		t.Errorf("Acknowledge() wrote %q, want nothing as the answer acknowledges the query", conn.written.String())
+	}
+}
+
+func TestAcknowledgeEscapesErrorText(t *testing.T) {
+	ack := &hl7Ack{MSH: strings.Split(strings.Replace(testMSH, "%s", "ORU^R01", 1), "|")}
+
+	msg := ack.build(errors.New("bad|value^x&y"))
+
+	if !strings.Contains(msg, "MSA|AR|CTRL1|bad\\F\\value\\S\\x\\T\\y") {
+		t.Errorf("build() = %q, want the delimiters escaped", msg)
+	}
+}
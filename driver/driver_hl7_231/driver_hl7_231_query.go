@@ -0,0 +1,340 @@
+package driver_hl7_231
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
+)
+
+const (
+	queryName         = "QRY"
+	ackMessageType    = "ACK"
+	queryMessageType  = "QRY"
+	orderMessageType  = "ORM"
+	segmentSeparator  = "\r"
+	mllpEnd           = "\x1c\r"
+	dspPatientID      = 1
+	dspPatientName    = 3
+	dspBirthDate      = 4
+	dspSex            = 5
+	dspBarcode        = 21
+	dspSampleID       = 22
+	dspSampleTime     = 23
+	dspStat           = 24
+	dspSampleType     = 26
+	dspFirstTest      = 29
+	defaultStat       = "N"
+	defaultSampleType = "serum"
+	obrDilution       = 13
+)
+
+// hl7Query represents the host query (order download request) sent by the device.
+type hl7Query struct {
+	MessageType string
+	ControlID   string
+	Barcode     string
+	MSH         []string
+	QRD         []string
+	QRF         []string
+	PID         []string
+}
+
+// getMessageType gets the type of the message from MSH-9.
+func getMessageType(hl7msg *hl7Message) string {
+	msh, ok := hl7msg.Fields["MSH"]
+	if !ok {
+		return ""
+	}
+
+	return firstComponent(msh[0], field(msh[0], 8))
+}
+
+// parseHL7Query parses the QRY^Q02 or ORM^O01 query of the device.
+func parseHL7Query(hl7msg *hl7Message) (*hl7Query, error) {
+	msh := hl7msg.Fields["MSH"][0]
+	query := &hl7Query{
+		MessageType: getMessageType(hl7msg),
+		ControlID:   field(msh, 9),
+		MSH:         msh,
+		QRD:         firstSegment(hl7msg, "QRD"),
+		QRF:         firstSegment(hl7msg, "QRF"),
+		PID:         firstSegment(hl7msg, "PID"),
+	}
+
+	if query.MessageType == queryMessageType {
+		query.Barcode = firstComponent(msh, field(query.QRD, 8))
+	} else {
+		candidates := []struct {
+			segment string
+			index   int
+		}{
+			{"ORC", 2}, {"ORC", 3}, {"OBR", 2}, {"OBR", 3}, {"SPM", 2},
+		}
+		for _, candidate := range candidates {
+			segment := firstSegment(hl7msg, candidate.segment)
+			if barcode := firstComponent(msh, field(segment, candidate.index)); barcode != "" {
+				query.Barcode = barcode
+				break
+			}
+		}
+	}
+
+	if query.Barcode == "" {
+		return nil, errors.New("failed to get barcode of the query")
+	}
+
+	return query, nil
+}
+
+// doQuery answers the host query of the device with the tests ordered for the barcode.
+func (d *Driver_hl7_231) doQuery(conn transport.Conn, data map[string]interface{}) error {
+	q, ok := data[queryName]
+	if !ok {
+		return nil
+	}
+	query := q.(*hl7Query)
+
+	dataToReturn, err := d.deviceQueryService.Query(query.Barcode)
+	if err != nil {
+		d.log.Err(err, "failed to query the service")
+	}
+	if len(dataToReturn) == 0 {
+		d.log.Error("query service returned no data for barcode " + query.Barcode)
+	}
+
+	var messages []string
+	if query.MessageType == queryMessageType {
+		messages = append(messages, buildQueryAck(query, len(dataToReturn) > 0))
+		if len(dataToReturn) > 0 {
+			messages = append(messages, buildDSR(query, dataToReturn))
+		}
+	} else {
+		messages = append(messages, buildORR(query, dataToReturn))
+	}
+
+	for _, msg := range messages {
+		_, err := conn.Write([]byte(wrapMessage(msg)))
+		if err != nil {
+			d.log.Err(err, "failed to send the query answer")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildQueryAck builds the QCK^Q02 acknowledgement of the QRY^Q02 query.
+func buildQueryAck(query *hl7Query, found bool) string {
+	status := "OK"
+	if !found {
+		status = "NF"
+	}
+
+	return joinSegments(
+		buildMSH(query.MSH, "QCK^Q02"),
+		"MSA|AA|"+query.ControlID+"|Message accepted|||0|",
+		"ERR|0|",
+		"QAK|SR|"+status+"|",
+	)
+}
+
+// buildDSR builds the DSR^Q03 answer with the ordered tests in the DSP segments.
+func buildDSR(query *hl7Query, dataToReturn []services.DeviceQueryDataToReturn) string {
+	segments := []string{
+		buildMSH(query.MSH, "DSR^Q03"),
+		"MSA|AA|" + query.ControlID + "|Message accepted|||0|",
+		"ERR|0|",
+		"QAK|SR|OK|",
+		strings.Join(query.QRD, "|"),
+		strings.Join(query.QRF, "|"),
+	}
+
+	sample := dataToReturn[0].Sample
+	sampleTime := time.Now()
+	if sample.CollectedAt != nil {
+		sampleTime = *sample.CollectedAt
+	}
+
+	dsp := map[int]string{
+		dspPatientID:   hl7Text(sample.PatientID),
+		dspPatientName: hl7Text(sample.PatientName),
+		dspBirthDate:   formatBirthDate(sample.PatientBirthDate),
+		dspSex:         sample.PatientSex,
+		dspBarcode:     query.Barcode,
+		dspSampleID:    query.Barcode,
+		dspSampleTime:  sampleTime.Format(completedDateFormat),
+		dspStat:        defaultStat,
+		dspSampleType:  defaultSampleType,
+	}
+	for _, data := range dataToReturn {
+		if data.Priority != model.OrderPriorityRoutine {
+			dsp[dspStat] = "Y"
+		}
+	}
+	if specimenType := dataToReturn[0].SpecimenType; specimenType != "" {
+		dsp[dspSampleType] = hl7Text(specimenType)
+	}
+	for i := 1; i < dspFirstTest; i++ {
+		segments = append(segments, fmt.Sprintf("DSP|%d||%s|||", i, dsp[i]))
+	}
+	for i, data := range dataToReturn {
+		segments = append(segments, fmt.Sprintf("DSP|%d||%s^^^|||", dspFirstTest+i, data.Param))
+	}
+	segments = append(segments, "DSC||")
+
+	return joinSegments(segments...)
+}
+
+// buildORR builds the ORR^O02 answer of the ORM^O01 query with an OBR segment per ordered test.
+// The PID is built from the patient sent by the HIS, the PID of the query is echoed when there is none.
+// The priority is sent in the quantity/timing of ORC-7 and OBR-27, the collection time in OBR-7,
+// the specimen type in OBR-15 and the dilution factor in OBR-13.
+func buildORR(query *hl7Query, dataToReturn []services.DeviceQueryDataToReturn) string {
+	orderControl := "OK"
+	if len(dataToReturn) == 0 {
+		orderControl = "UA"
+	}
+
+	segments := []string{
+		buildMSH(query.MSH, "ORR^O02"),
+		"MSA|AA|" + query.ControlID,
+	}
+
+	priority := model.OrderPriorityRoutine
+	sample := model.OrderSample{}
+	if len(dataToReturn) > 0 {
+		priority = dataToReturn[0].Priority
+		sample = dataToReturn[0].Sample
+	}
+
+	if sample.PatientID != "" || sample.PatientName != "" {
+		segments = append(segments, fmt.Sprintf("PID|1||%s||%s||%s|%s",
+			hl7Text(sample.PatientID),
+			hl7Text(sample.PatientName),
+			formatBirthDate(sample.PatientBirthDate),
+			sample.PatientSex,
+		))
+	} else if query.PID != nil {
+		segments = append(segments, strings.Join(query.PID, "|"))
+	}
+	segments = append(segments, fmt.Sprintf("ORC|%s|%s|%s||||^^^^^%s", orderControl, query.Barcode, query.Barcode, hl7Priority(priority)))
+
+	collectedAt := ""
+	if sample.CollectedAt != nil {
+		collectedAt = sample.CollectedAt.Format(completedDateFormat)
+	}
+	for i, data := range dataToReturn {
+		fields := make([]string, 28)
+		fields[0] = "OBR"
+		fields[1] = fmt.Sprint(i + 1)
+		fields[2] = query.Barcode
+		fields[3] = query.Barcode
+		fields[4] = data.Param + "^^^"
+		fields[7] = collectedAt
+		fields[obrDilution] = hl7Text(data.Dilution)
+		fields[15] = hl7Text(data.SpecimenType)
+		fields[27] = "^^^^^" + hl7Priority(data.Priority)
+		segments = append(segments, strings.Join(fields, "|"))
+	}
+
+	return joinSegments(segments...)
+}
+
+// hl7Priority converts the order priority to the HL7 priority code.
+func hl7Priority(priority string) string {
+	switch priority {
+	case model.OrderPriorityStat:
+		return "S"
+	case model.OrderPriorityASAP:
+		return "A"
+	}
+
+	return "R"
+}
+
+// formatBirthDate formats the birth date of the patient or returns an empty string.
+func formatBirthDate(birthDate *time.Time) string {
+	if birthDate == nil {
+		return ""
+	}
+
+	return birthDate.Format(birthDateFormat)
+}
+
+// hl7Text replaces the HL7 delimiters in a text sent to the device.
+func hl7Text(text string) string {
+	return strings.NewReplacer("|", " ", "~", " ", "\\", " ", "&", " ", "\r", " ", "\n", " ").Replace(text)
+}
+
+// buildMSH builds the MSH segment of the answer, swapping the sender and the receiver of the received MSH.
+func buildMSH(msh []string, messageType string) string {
+	fields := []string{
+		"MSH",
+		field(msh, 1),
+		field(msh, 4),
+		field(msh, 5),
+		field(msh, 2),
+		field(msh, 3),
+		time.Now().Format(completedDateFormat),
+		"",
+		messageType,
+		fmt.Sprint(time.Now().UnixMilli()),
+		field(msh, 10),
+		field(msh, 11),
+	}
+	if len(msh) > 12 {
+		fields = append(fields, msh[12:]...)
+	}
+
+	return strings.Join(fields, "|")
+}
+
+// wrapMessage wraps the message into the MLLP start and end blocks.
+func wrapMessage(msg string) string {
+	return fmt.Sprintf("%c", rawDataStartString) + msg + mllpEnd
+}
+
+// joinSegments joins the segments into a message.
+func joinSegments(segments ...string) string {
+	return strings.Join(segments, segmentSeparator) + segmentSeparator
+}
+
+// firstSegment returns the fields of the first segment with the name or nil.
+func firstSegment(hl7msg *hl7Message, name string) []string {
+	segments, ok := hl7msg.Fields[name]
+	if !ok || len(segments) == 0 {
+		return nil
+	}
+
+	return segments[0]
+}
+
+// field returns the field by its index or an empty string.
+func field(fields []string, index int) string {
+	if index < len(fields) {
+		return fields[index]
+	}
+
+	return ""
+}
+
+// firstComponent returns the first non-empty component of the field using the component delimiter of the MSH.
+func firstComponent(msh []string, value string) string {
+	delimiter := "^"
+	if encoding := field(msh, 1); encoding != "" {
+		delimiter = encoding[:1]
+	}
+
+	for _, component := range strings.Split(value, delimiter) {
+		if component != "" {
+			return component
+		}
+	}
+
+	return ""
+}
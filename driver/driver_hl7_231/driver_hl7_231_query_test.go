@@ -0,0 +1,142 @@
+package driver_hl7_231
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+)
+
+func newTestDriver() *Driver_hl7_231 {
+	return NewDriver(&log.Logger{Disabled: true}, nil, nil)
+}
+
+const (
+	testMSH = "MSH|^~\\&|Analyzer|Lab|LIS|Hospital|20240102030405||%s|CTRL1|P|2.3.1"
+	testQRD = "QRD|20240102030405|R|D|1|||RD|%s|OTH|||T"
+	testQRF = "QRF|Analyzer|||||RCT|COR|ALL||"
+)
+
+// testMessage builds a message of the type from the segments.
+func testMessage(messageType string, segments ...string) string {
+	return strings.Join(append([]string{strings.Replace(testMSH, "%s", messageType, 1)}, segments...), "\r") + "\r"
+}
+
+func TestParseHL7Query(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		messageType string
+		barcode     string
+	}{
+		{
+			name:        "QRY barcode in QRD-8",
+			msg:         testMessage("QRY^Q02", strings.Replace(testQRD, "%s", "BC1", 1), testQRF),
+			messageType: queryMessageType,
+			barcode:     "BC1",
+		},
+		{
+			name:        "ORM barcode in ORC-2",
+			msg:         testMessage("ORM^O01", "PID|1||P1", "ORC|NW|BC2"),
+			messageType: orderMessageType,
+			barcode:     "BC2",
+		},
+		{
+			name:        "ORM barcode in OBR-3",
+			msg:         testMessage("ORM^O01", "ORC|NW||", "OBR|1||BC3^LAB"),
+			messageType: orderMessageType,
+			barcode:     "BC3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hl7msg, err := parseHL7Message(test.msg)
+			if err != nil {
+				t.Fatalf("parseHL7Message() error = %v", err)
+			}
+
+			query, err := parseHL7Query(hl7msg)
+			if err != nil {
+				t.Fatalf("parseHL7Query() error = %v", err)
+			}
+			if query.MessageType != test.messageType || query.Barcode != test.barcode || query.ControlID != "CTRL1" {
+				t.Errorf("parseHL7Query() = %s, %s, %s, want %s, %s, CTRL1", query.MessageType, query.Barcode, query.ControlID, test.messageType, test.barcode)
+			}
+		})
+	}
+}
+
+func TestUnmarshalQueryWithoutBarcode(t *testing.T) {
+	_, additionalData, err := newTestDriver().Unmarshal(testMessage("QRY^Q02", strings.Replace(testQRD, "%s", "", 1), testQRF))
+	if err == nil {
+		t.Fatal("Unmarshal() error = nil, want the query parse error")
+	}
+
+	if _, ok := additionalData[queryName]; ok {
+		t.Error("Unmarshal() returned the query, want none to answer")
+	}
+	ack, ok := additionalData[ackName].(*hl7Ack)
+	if !ok || ack.ParseErr == nil {
+		t.Errorf("Unmarshal() ACK = %+v, want the parse error to acknowledge", additionalData[ackName])
+	}
+}
+
+func TestBuildQueryAnswers(t *testing.T) {
+	hl7msg, err := parseHL7Message(testMessage("QRY^Q02", strings.Replace(testQRD, "%s", "BC1", 1), testQRF))
+	if err != nil {
+		t.Fatalf("parseHL7Message() error = %v", err)
+	}
+	query, err := parseHL7Query(hl7msg)
+	if err != nil {
+		t.Fatalf("parseHL7Query() error = %v", err)
+	}
+
+	dataToReturn := []services.DeviceQueryDataToReturn{
+		{Param: "GLU", Priority: model.OrderPriorityRoutine, Dilution: "2", SpecimenType: "urine", Sample: model.OrderSample{PatientID: "P1", PatientName: "Doe^John", PatientSex: "M"}},
+		{Param: "ALT", Priority: model.OrderPriorityStat},
+	}
+
+	dsr := strings.Split(buildDSR(query, dataToReturn), segmentSeparator)
+	for _, want := range []string{"MSA|AA|CTRL1|Message accepted|||0|", "QAK|SR|OK|", "DSP|1||P1|||", "DSP|21||BC1|||", "DSP|24||Y|||", "DSP|26||urine|||", "DSP|29||GLU^^^|||", "DSP|30||ALT^^^|||", "DSC||"} {
+		if !containsSegment(dsr, want) {
+			t.Errorf("buildDSR() has no %q in %q", want, dsr)
+		}
+	}
+
+	if qck := buildQueryAck(query, false); !strings.Contains(qck, "QAK|SR|NF|") {
+		t.Errorf("buildQueryAck() = %q, want the not found status", qck)
+	}
+
+	orr := strings.Split(buildORR(query, dataToReturn), segmentSeparator)
+	for _, want := range []string{"MSA|AA|CTRL1", "ORC|OK|BC1|BC1||||^^^^^R"} {
+		if !containsSegment(orr, want) {
+			t.Errorf("buildORR() has no %q in %q", want, orr)
+		}
+	}
+	if obr := segmentWithPrefix(orr, "OBR|2|"); !strings.HasSuffix(obr, "|^^^^^S") || !strings.Contains(obr, "|ALT^^^|") {
+		t.Errorf("buildORR() second OBR = %q", obr)
+	}
+
+	if orr := buildORR(query, nil); !strings.Contains(orr, "ORC|UA|BC1|") {
+		t.Errorf("buildORR() without tests = %q, want the unable to accept control", orr)
+	}
+}
+
+// containsSegment checks if the segments contain the segment.
+func containsSegment(segments []string, segment string) bool {
+	return segmentWithPrefix(segments, segment) == segment
+}
+
+// segmentWithPrefix returns the first segment starting with the prefix.
+func segmentWithPrefix(segments []string, prefix string) string {
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, prefix) {
+			return segment
+		}
+	}
+
+	return ""
+}
@@ -11,8 +11,10 @@ import (
 )
 
 // hl7Message represents the entire HL7 message with segments stored in a map where keys are segment types.
+// Fields keeps the unparsed fields of the segments, the first field is the segment name.
 type hl7Message struct {
 	Segments map[string][]map[string]interface{} `json:"segments"`
+	Fields   map[string][][]string               `json:"-"`
 }
 
 // Unmarshal unmarshals the raw data.
@@ -27,6 +29,10 @@ func (d *Driver_hl7_231) Unmarshal(rawData string) (labDatas []*model.LabData, a
 			labDatas = []*model.LabData{}
 			err = errors.New("failed to unmarshal raw data")
 		}
+
+		if ack, ok := additionalData[ackName].(*hl7Ack); ok && err != nil {
+			ack.ParseErr = err
+		}
 	}()
 
 	hl7msg, err := parseHL7Message(rawData)
@@ -35,57 +41,83 @@ func (d *Driver_hl7_231) Unmarshal(rawData string) (labDatas []*model.LabData, a
 		return labDatas, nil, err
 	}
 
-	ackMsg := hl7msg.Segments["ACK"][0]["ACK"].(string)
-	ackMsg = fmt.Sprintf("%c", rawDataStartString) + ackMsg + fmt.Sprintf("%c", rawDataEndString)
-	additionalData = map[string]interface{}{"ACK": ackMsg}
+	messageType := getMessageType(hl7msg)
+	if messageType == ackMessageType {
+		return labDatas, nil, nil
+	}
+
+	if msh, ok := hl7msg.Fields["MSH"]; ok {
+		additionalData = map[string]interface{}{ackName: &hl7Ack{MSH: msh[0]}}
+	}
+
+	if messageType == queryMessageType || messageType == orderMessageType {
+		query, err := parseHL7Query(hl7msg)
+		if err != nil {
+			d.log.Err(err, "failed to parse the HL7 query")
+			return labDatas, additionalData, err
+		}
+		additionalData[queryName] = query
+		return labDatas, additionalData, nil
+	}
 
+	pid := firstSegment(hl7msg, "PID")
 	checkObrObx := len(hl7msg.Segments["OBR"]) > 1
 	for _, obr := range hl7msg.Segments["OBR"] {
-		for _, obx := range hl7msg.Segments["OBX"] {
+		for j, obx := range hl7msg.Segments["OBX"] {
 			if !checkObrObx || obr["Set ID - OBR"] == obx["Set ID - OBX"] {
 				barcode, err := getBarcodeForUnmarshalRawData(obr, hl7msg)
 				if err != nil {
 					fmt.Println("failed to get barcode for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
 				index, err := getIndexForUnmarshalRawData(obx)
 				if err != nil {
 					fmt.Println("failed to get index for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
 				param, err := getParamForUnmarshalRawData(obx)
 				if err != nil {
 					fmt.Println("failed to get param for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
 				result, err := getResultForUnmarshalRawData(obx)
 				if err != nil {
 					fmt.Println("failed to get result for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
 				unit, err := getUnitForUnmarshalRawData(obx)
 				if err != nil {
 					fmt.Println("failed to get unit for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
 				completedDate, err := getCompleteDateForUnmarshalRawData(obr, obx)
 				if err != nil {
 					fmt.Println("failed to get completed date for unmarshalRawData")
-					return labDatas, nil, err
+					return labDatas, additionalData, err
 				}
 
+				obxFields := hl7msg.Fields["OBX"][j]
+				abnormalFlag, instrumentFlags := getFlagsForUnmarshalRawData(obxFields)
+
 				labData := &model.LabData{
-					Barcode:       barcode,
-					Index:         index,
-					Param:         param,
-					Result:        result,
-					Unit:          unit,
-					CompletedDate: completedDate,
+					Barcode:          barcode,
+					Index:            index,
+					Param:            param,
+					Result:           result,
+					Unit:             unit,
+					CompletedDate:    completedDate,
+					ReferenceRange:   field(obxFields, 7),
+					AbnormalFlag:     abnormalFlag,
+					ResultStatus:     model.NormalizeResultStatus(field(obxFields, 11)),
+					InstrumentFlags:  instrumentFlags,
+					PatientID:        getPatientIDForUnmarshalRawData(hl7msg, pid),
+					PatientSex:       strings.ToUpper(strings.TrimSpace(field(pid, 8))),
+					PatientBirthDate: getPatientBirthDateForUnmarshalRawData(pid),
 				}
 				labDatas = append(labDatas, labData)
 			}
@@ -216,9 +248,43 @@ func getUnitForUnmarshalRawData(obx map[string]interface{}) (string, error) {
 	return "", errors.New("failed to get unit")
 }
 
+// getFlagsForUnmarshalRawData gets the abnormal flag and the instrument flags (OBX-8) for unmarshalling the raw data.
+// The flags are repeated with the repetition delimiter.
+func getFlagsForUnmarshalRawData(obxFields []string) (string, string) {
+	return model.SplitFlags(strings.Split(field(obxFields, 8), "~"))
+}
+
+// getPatientIDForUnmarshalRawData gets the patient ID for unmarshalling the raw data.
+// The ID of the patient identifier list (PID-3) is preferred to the external patient ID (PID-2).
+func getPatientIDForUnmarshalRawData(hl7msg *hl7Message, pidFields []string) string {
+	msh := firstSegment(hl7msg, "MSH")
+	for _, index := range []int{3, 2} {
+		if id := firstComponent(msh, strings.Split(field(pidFields, index), "~")[0]); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// getPatientBirthDateForUnmarshalRawData gets the patient birth date (PID-7) for unmarshalling the raw data.
+func getPatientBirthDateForUnmarshalRawData(pidFields []string) *time.Time {
+	birthDateString := field(pidFields, 7)
+	if len(birthDateString) < len(birthDateFormat) {
+		return nil
+	}
+
+	birthDate, err := time.Parse(birthDateFormat, birthDateString[:len(birthDateFormat)])
+	if err != nil {
+		return nil
+	}
+
+	return &birthDate
+}
+
 // parseHL7Message parses the HL7 message.
 func parseHL7Message(rawMessage string) (*hl7Message, error) {
-	message := &hl7Message{Segments: make(map[string][]map[string]interface{})}
+	message := &hl7Message{Segments: make(map[string][]map[string]interface{}), Fields: make(map[string][][]string)}
 	normalized := strings.ReplaceAll(rawMessage, "\r\n", "\r")
 	normalized = strings.ReplaceAll(normalized, "\n", "\r")
 	segments := strings.Split(normalized, "\r")
@@ -262,35 +328,12 @@ func parseHL7Message(rawMessage string) (*hl7Message, error) {
 			}
 		}
 		message.Segments[segmentName] = append(message.Segments[segmentName], segmentFields)
-
-		// Add ACK segment
-		if segmentName == "MSH" {
-			addAckSegment(message, fields)
-		}
+		message.Fields[segmentName] = append(message.Fields[segmentName], fields)
 	}
 
 	return message, nil
 }
 
-// addAckSegment adds the ACK segment to the HL7 message.
-func addAckSegment(message *hl7Message, fields []string) {
-	ackSegment := "MSH"
-	fieldDefinitions := getFieldDefinitions("MSH")
-	msgControlID := ""
-	for i, fieldName := range fieldDefinitions {
-		if fieldName == "Message Type" {
-			ackSegment += "|ACK"
-			continue
-		}
-		if fieldName == "Message Control ID" {
-			msgControlID = fields[i+1]
-		}
-		ackSegment += "|" + fields[i+1]
-	}
-	ackSegment += "\rMSA|AA|" + msgControlID + "\r"
-	message.Segments["ACK"] = []map[string]interface{}{{"ACK": ackSegment}}
-}
-
 // parseDelimiters parses the delimiters of MSH segment.
 func parseDelimiters(mshSegment string) struct{ field, component, repetition, escape, subComponent string } {
 	return struct{ field, component, repetition, escape, subComponent string }{
@@ -343,6 +386,10 @@ func getFieldDefinitions(segmentName string) []string {
 		return []string{"Set ID - SPM", "Specimen ID", "Specimen Parent IDs", "Specimen Type", "Specimen Type Modifier", "Specimen Additives", "Specimen Collection Method", "Specimen Source Site", "Specimen Source Site Modifier", "Specimen Collection Site", "Specimen Role", "Specimen Collection Amount", "Grouped Specimen Count", "Specimen Description", "Specimen Handling Code", "Specimen Risk Code", "Specimen Collection Date/Time", "Specimen Received Date/Time", "Specimen Expiration Date/Time", "Specimen Availability", "Specimen Reject Reason", "Specimen Quality", "Specimen Appropriateness", "Specimen Condition", "Specimen Child Role"}
 	case "ORC":
 		return []string{"Order Control", "Placer Order Number", "Filler Order Number", "Placer Group Number", "Order Status", "Response Flag", "Quantity/Timing", "Parent", "Date/Time of Transaction", "Entered By", "Verified By", "Ordering Provider", "Enterer's Location", "Call Back Phone Number", "Order Effective Date/Time", "Order Control Code Reason", "Entering Organization", "Entering Device", "Action By", "Advanced Beneficiary Notice Code", "Ordering Facility Name", "Ordering Facility Address", "Ordering Facility Phone Number", "Ordering Provider Address"}
+	case "QRD":
+		return []string{"Query Date/Time", "Query Format Code", "Query Priority", "Query ID", "Deferred Response Type", "Deferred Response Date/Time", "Quantity Limited Request", "Who Subject Filter", "What Subject Filter", "What Department Data Code", "What Data Code Value Qual", "Query Results Level"}
+	case "QRF":
+		return []string{"Where Subject Filter", "When Data Start Date/Time", "When Data End Date/Time", "What User Qualifier", "Other QRY Subject Filter", "Which Date/Time Qualifier", "Which Date/Time Status Qualifier", "Date/Time Selection Qualifier", "When Quantity/Timing Qualifier"}
 	case "NTE":
 		return []string{"Set ID - NTE", "Source of Comment", "Comment", "Comment Type", "Entered By", "Entered Date/Time", "Effective Start Date", "Expiration Date", "Comment Completion Date"}
 	default:
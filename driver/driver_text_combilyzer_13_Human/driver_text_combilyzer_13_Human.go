@@ -2,11 +2,12 @@ package driver_text_combilyzer_13_Human
 
 import (
 	"fmt"
-	"net"
 
 	"github.com/voidmaindev/doctra_lis_middleware/log"
 	"github.com/voidmaindev/doctra_lis_middleware/services"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 const (
@@ -56,8 +57,18 @@ func (d *Driver_text_Combilyzer_13_Human) DataToBeReplaced() map[string]string {
 	return map[string]string{}
 }
 
+// UnwrapFrames returns the message as is, the format has no low-level frames.
+func (d *Driver_text_Combilyzer_13_Human) UnwrapFrames(conn transport.Conn, msg string, prds *tcp.PrevData) (string, error) {
+	return msg, nil
+}
+
+// SenderID returns the sender ID of the raw data, the format does not carry one.
+func (d *Driver_text_Combilyzer_13_Human) SenderID(rawData string) string {
+	return ""
+}
+
 // SendSimpleACK sends an ACK message.
-func (d *Driver_text_Combilyzer_13_Human) SendSimpleACK(conn net.Conn) error {
+func (d *Driver_text_Combilyzer_13_Human) SendSimpleACK(conn transport.Conn) error {
 	return nil
 }
 
@@ -66,7 +77,12 @@ func (d *Driver_text_Combilyzer_13_Human) ReceivedSimpleACK(msg string) bool {
 	return false
 }
 
+// Acknowledge acknowledges the processed message, the format has no acknowledgements.
+func (d *Driver_text_Combilyzer_13_Human) Acknowledge(conn transport.Conn, data map[string]interface{}, processErr error) error {
+	return nil
+}
+
 // PostUnmarshalActions performs the post-unmarshal actions.
-func (d *Driver_text_Combilyzer_13_Human) PostUnmarshalActions(conn net.Conn, data map[string]interface{}) error {
+func (d *Driver_text_Combilyzer_13_Human) PostUnmarshalActions(conn transport.Conn, data map[string]interface{}) error {
 	return nil
 }
@@ -85,6 +85,8 @@ func (d *Driver_text_Combilyzer_13_Human) Unmarshal(rawData string) (labDatas []
 			Result:        result,
 			Unit:          unit,
 			CompletedDate: completedDate,
+			AbnormalFlag:  getAbnormalFlagForUnmarshalRawData(parts),
+			ResultStatus:  model.ResultStatusFinal,
 		}
 
 		labDatas = append(labDatas, labData)
@@ -126,6 +128,16 @@ func getParamForUnmarshalRawData(parts []string) (string, error) {
 	return param, nil
 }
 
+// getAbnormalFlagForUnmarshalRawData gets the abnormal flag for unmarshalling the raw data.
+// The device marks the pathological results with "*" before the param.
+func getAbnormalFlagForUnmarshalRawData(parts []string) string {
+	if strings.HasPrefix(parts[0], "*") {
+		return model.FlagAbnormal
+	}
+
+	return ""
+}
+
 // getResultForUnmarshalRawData gets the result for unmarshalling the raw data.
 func getResultForUnmarshalRawData(parts []string) (string, error) {
 	result := ""
@@ -1,11 +1,11 @@
 package driver_text_huma_reader_hs
 
 import (
-	"net"
-
 	"github.com/voidmaindev/doctra_lis_middleware/log"
 	"github.com/voidmaindev/doctra_lis_middleware/services"
 	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 const (
@@ -54,8 +54,18 @@ func (d *driver_text_huma_reader_hs) DataToBeReplaced() map[string]string {
 	return map[string]string{}
 }
 
+// UnwrapFrames returns the message as is, the format has no low-level frames.
+func (d *driver_text_huma_reader_hs) UnwrapFrames(conn transport.Conn, msg string, prds *tcp.PrevData) (string, error) {
+	return msg, nil
+}
+
+// SenderID returns the sender ID of the raw data, the format does not carry one.
+func (d *driver_text_huma_reader_hs) SenderID(rawData string) string {
+	return ""
+}
+
 // SendSimpleACK sends an ACK message.
-func (d *driver_text_huma_reader_hs) SendSimpleACK(conn net.Conn) error {
+func (d *driver_text_huma_reader_hs) SendSimpleACK(conn transport.Conn) error {
 	return nil
 }
 
@@ -64,7 +74,12 @@ func (d *driver_text_huma_reader_hs) ReceivedSimpleACK(msg string) bool {
 	return false
 }
 
+// Acknowledge acknowledges the processed message, the format has no acknowledgements.
+func (d *driver_text_huma_reader_hs) Acknowledge(conn transport.Conn, data map[string]interface{}, processErr error) error {
+	return nil
+}
+
 // PostUnmarshalActions performs post-unmarshal actions.
-func (d *driver_text_huma_reader_hs) PostUnmarshalActions(conn net.Conn, data map[string]interface{}) error {
+func (d *driver_text_huma_reader_hs) PostUnmarshalActions(conn transport.Conn, data map[string]interface{}) error {
 	return nil
 }
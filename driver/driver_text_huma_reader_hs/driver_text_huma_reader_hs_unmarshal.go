@@ -86,6 +86,7 @@ func (d *driver_text_huma_reader_hs) Unmarshal(rawData string) (labDatas []*mode
 			Result:        result,
 			Unit:          unit,
 			CompletedDate: completedDate,
+			ResultStatus:  model.ResultStatusFinal,
 		}
 
 		labDatas = append(labDatas, labData)
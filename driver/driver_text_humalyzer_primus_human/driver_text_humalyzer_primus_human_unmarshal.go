@@ -86,6 +86,7 @@ func (d *driver_text_humalyzer_primus_human) Unmarshal(rawData string) (labDatas
 			Result:        result,
 			Unit:          unit,
 			CompletedDate: completedDate,
+			ResultStatus:  model.ResultStatusFinal,
 		}
 
 		labDatas = append(labDatas, labData)
@@ -15,6 +15,8 @@ import (
 type Logger struct {
 	Logger   *zerolog.Logger
 	Disabled bool
+
+	file *os.File
 }
 
 // NewLogger creates a new Logger instance
@@ -50,7 +52,26 @@ func NewLogger() (*Logger, error) {
 
 	logger := loggerContext.Logger()
 
-	return &Logger{Logger: &logger}, nil
+	l := &Logger{Logger: &logger}
+	if isFile {
+		l.file = output.(*os.File)
+	}
+
+	return l, nil
+}
+
+// Close flushes the log file to the disk and closes it, the messages logged after it are lost
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	return l.file.Close()
 }
 
 // Trace logs a message at trace level
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// defaultUnitDecimals is the number of decimals of the converted results when the canonical unit does not set it.
+const defaultUnitDecimals = 2
+
+// resultComparators are the comparison prefixes of the results out of the measuring range, e.g. "<0.5".
+var resultComparators = []string{"<=", ">=", "<", ">"}
+
+// CanonicalUnit represents the unit the results of a HIS test code are normalized to.
+// Decimals is the number of decimals of the converted results, two when it is not set.
+type CanonicalUnit struct {
+	gorm.Model
+	Param    string `json:"param" gorm:"not null;index"`
+	Unit     string `json:"unit" gorm:"not null"`
+	Decimals *uint  `json:"decimals"`
+}
+
+// CanonicalUnits represents the canonical units of the test codes.
+type CanonicalUnits []*CanonicalUnit
+
+// Find finds the canonical unit of the param.
+func (c CanonicalUnits) Find(param string) *CanonicalUnit {
+	for _, canonicalUnit := range c {
+		if canonicalUnit.Param == param {
+			return canonicalUnit
+		}
+	}
+
+	return nil
+}
+
+// ConvertResult converts the numeric result, keeping its comparison prefix, e.g. "<0.5".
+// It returns false when the result is not numeric.
+func (c *CanonicalUnit) ConvertResult(result string, convert func(float64) float64) (string, bool) {
+	result = strings.TrimSpace(result)
+	comparator := ""
+	for _, prefix := range resultComparators {
+		if strings.HasPrefix(result, prefix) {
+			comparator = prefix
+			break
+		}
+	}
+
+	value, err := ParseNumericResult(strings.TrimPrefix(result, comparator))
+	if err != nil {
+		return "", false
+	}
+
+	return comparator + c.formatValue(convert(value)), true
+}
+
+// ConvertReferenceRange converts the reference range sent as "low-high".
+// It returns false when the range is in another form.
+func (c *CanonicalUnit) ConvertReferenceRange(referenceRange string, convert func(float64) float64) (string, bool) {
+	parsed := ParseReferenceRange(referenceRange)
+	if parsed == nil {
+		return "", false
+	}
+
+	return c.formatValue(convert(*parsed.Low)) + "-" + c.formatValue(convert(*parsed.High)), true
+}
+
+// formatValue rounds the value to the decimals of the canonical unit.
+func (c *CanonicalUnit) formatValue(value float64) string {
+	decimals := uint(defaultUnitDecimals)
+	if c.Decimals != nil {
+		decimals = *c.Decimals
+	}
+
+	return strconv.FormatFloat(value, 'f', int(decimals), 64)
+}
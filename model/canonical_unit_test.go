@@ -0,0 +1,57 @@
+package model
+
+import "testing"
+
+func TestCanonicalUnitConvertResult(t *testing.T) {
+	decimals := uint(1)
+	oneDecimal := &CanonicalUnit{Param: "GLU", Unit: "mmol/L", Decimals: &decimals}
+	defaultDecimals := &CanonicalUnit{Param: "GLU", Unit: "mmol/L"}
+	double := func(value float64) float64 { return value * 2 }
+
+	tests := []struct {
+		name          string
+		canonicalUnit *CanonicalUnit
+		result        string
+		want          string
+		ok            bool
+	}{
+		{name: "default decimals", canonicalUnit: defaultDecimals, result: "1.234", want: "2.47", ok: true},
+		{name: "decimals of the unit", canonicalUnit: oneDecimal, result: "1.234", want: "2.5", ok: true},
+		{name: "comma separator", canonicalUnit: oneDecimal, result: " 1,5 ", want: "3.0", ok: true},
+		{name: "comparator kept", canonicalUnit: oneDecimal, result: "<0.5", want: "<1.0", ok: true},
+		{name: "two character comparator", canonicalUnit: oneDecimal, result: ">=10", want: ">=20.0", ok: true},
+		{name: "text result", canonicalUnit: oneDecimal, result: "positive"},
+		{name: "empty result", canonicalUnit: oneDecimal, result: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := test.canonicalUnit.ConvertResult(test.result, double)
+			if ok != test.ok || got != test.want {
+				t.Errorf("ConvertResult(%q) = %q, %v, want %q, %v", test.result, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestCanonicalUnitConvertReferenceRange(t *testing.T) {
+	canonicalUnit := &CanonicalUnit{Param: "GLU", Unit: "mmol/L"}
+	toMillimoles := func(value float64) float64 { return value * 0.0555 }
+
+	got, ok := canonicalUnit.ConvertReferenceRange("70-110", toMillimoles)
+	if !ok || got != "3.89-6.11" {
+		t.Errorf("ConvertReferenceRange(70-110) = %q, %v, want 3.89-6.11, true", got, ok)
+	}
+
+	got, ok = canonicalUnit.ConvertReferenceRange("<110", toMillimoles)
+	if ok || got != "" {
+		t.Errorf("ConvertReferenceRange(<110) = %q, %v, want an empty range, false", got, ok)
+	}
+
+	if found := (CanonicalUnits{canonicalUnit}).Find("GLU"); found != canonicalUnit {
+		t.Errorf("Find(GLU) = %v, want %v", found, canonicalUnit)
+	}
+	if found := (CanonicalUnits{canonicalUnit}).Find("CHOL"); found != nil {
+		t.Errorf("Find(CHOL) = %v, want nil", found)
+	}
+}
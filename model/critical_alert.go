@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CriticalAlert represents the alert of a critical lab data sent to the webhook.
+// It documents when the critical result was detected (CreatedAt), notified and acknowledged.
+type CriticalAlert struct {
+	gorm.Model
+	LabDataID            uint       `json:"lab_data_id" gorm:"not null;index"`
+	DeviceID             uint       `json:"device_id" gorm:"not null;index"`
+	Barcode              string     `json:"barcode" gorm:"not null;index"`
+	Param                string     `json:"param" gorm:"not null"`
+	Result               string     `json:"result" gorm:"not null"`
+	Unit                 string     `json:"unit"`
+	AbnormalFlag         string     `json:"abnormal_flag"`
+	Reason               string     `json:"reason"`
+	NotificationAttempts uint       `json:"notification_attempts"`
+	NotificationError    string     `json:"notification_error"`
+	NextNotificationAt   *time.Time `json:"next_notification_at" gorm:"type:datetime"`
+	NotifiedAt           *time.Time `json:"notified_at" gorm:"type:datetime;index"`
+	AcknowledgedBy       string     `json:"acknowledged_by"`
+	AcknowledgedAt       *time.Time `json:"acknowledged_at" gorm:"type:datetime;index"`
+}
+
+// NewCriticalAlert creates a new alert of the critical lab data.
+func NewCriticalAlert(labData *LabData) *CriticalAlert {
+	return &CriticalAlert{
+		LabDataID:    labData.ID,
+		DeviceID:     labData.DeviceID,
+		Barcode:      labData.Barcode,
+		Param:        labData.Param,
+		Result:       labData.Result,
+		Unit:         labData.Unit,
+		AbnormalFlag: labData.AbnormalFlag,
+		Reason:       labData.VerificationReason,
+	}
+}
+
+// NewCriticalAlerts creates the alerts of the critical lab datas among the lab datas.
+func NewCriticalAlerts(labDatas []*LabData) []*CriticalAlert {
+	alerts := []*CriticalAlert{}
+	for _, labData := range labDatas {
+		if labData.VerificationStatus == VerificationStatusCritical {
+			alerts = append(alerts, NewCriticalAlert(labData))
+		}
+	}
+
+	return alerts
+}
+
+// Acknowledge marks the alert acknowledged by the user.
+func (a *CriticalAlert) Acknowledge(username string, now time.Time) {
+	a.AcknowledgedBy = username
+	a.AcknowledgedAt = &now
+}
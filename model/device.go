@@ -2,12 +2,52 @@ package model
 
 import "gorm.io/gorm"
 
+// Device identification modes, they define how a connection is matched to a device.
+const (
+	IdentifyByIP         = "ip"
+	IdentifyByIPPort     = "ip_port"
+	IdentifyByListenPort = "listen_port"
+	IdentifyBySenderID   = "sender_id"
+)
+
+// Device connection modes, they define which side opens the connection.
+const (
+	ConnectionModeServer = "server"
+	ConnectionModeClient = "client"
+	ConnectionModeSerial = "serial"
+)
+
 // Device represents a device.
+// NetAddress holds the IP for IdentifyByIP and "IP:port" for IdentifyByIPPort,
+// ListenPort the local port the device connects to for IdentifyByListenPort and
+// SenderID the sender in the ASTM H record or HL7 MSH-3 for IdentifyBySenderID.
+// In ConnectionModeServer (the default) the device connects to the middleware, to ListenPort if it is set,
+// in ConnectionModeClient the middleware connects to the device at NetAddress ("host:port")
+// and in ConnectionModeSerial it opens the serial port of SerialPort.
+// QueryBackend is the name of the configured backend answering the host queries of the device, the default backend if it is empty.
 type Device struct {
 	gorm.Model
-	Name          string      `json:"name" gorm:"not null"`
-	DeviceModelID uint        `json:"device_model_id" gorm:"not null;index"`
-	DeviceModel   DeviceModel `json:"device_model" gorm:"foreignKey:device_model_id"`
-	Serial        string      `json:"serial"`
-	NetAddress    string      `json:"net_address" gorm:"not null"`
+	Name           string      `json:"name" gorm:"not null"`
+	DeviceModelID  uint        `json:"device_model_id" gorm:"not null;index"`
+	DeviceModel    DeviceModel `json:"device_model" gorm:"foreignKey:device_model_id"`
+	Serial         string      `json:"serial"`
+	NetAddress     string      `json:"net_address" gorm:"not null"`
+	IdentifyBy     string      `json:"identify_by"`
+	ListenPort     string      `json:"listen_port"`
+	SenderID       string      `json:"sender_id" gorm:"index"`
+	ConnectionMode string      `json:"connection_mode"`
+	SerialPort     SerialPort  `json:"serial_port" gorm:"embedded;embeddedPrefix:serial_port_"`
+	QueryBackend   string      `json:"query_backend"`
+}
+
+// SerialPort represents the serial port settings of a device.
+// Parity is "none", "odd" or "even" and FlowControl "none", "rtscts" or "xonxoff",
+// the unset settings default to 9600 8N1 without flow control.
+type SerialPort struct {
+	Port        string `json:"port"`
+	BaudRate    int    `json:"baud_rate"`
+	DataBits    int    `json:"data_bits"`
+	Parity      string `json:"parity"`
+	StopBits    int    `json:"stop_bits"`
+	FlowControl string `json:"flow_control"`
 }
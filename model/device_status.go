@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// DeviceStatus represents the connection state of a device kept by the device server.
+// BytesReceived and MessagesReceived count all the data and the complete messages received from the device,
+// LastError is the last failure of its connection or of the processing of its messages.
+// IdleSince is set when the idle alert of the device is raised and cleared by the next data it sends.
+type DeviceStatus struct {
+	DeviceID         uint       `json:"device_id" gorm:"primarykey;autoIncrement:false"`
+	DeviceName       string     `json:"device_name" gorm:"-"`
+	Connected        bool       `json:"connected"`
+	ConnString       string     `json:"conn_string"`
+	ConnectedSince   *time.Time `json:"connected_since" gorm:"type:datetime"`
+	DisconnectedAt   *time.Time `json:"disconnected_at" gorm:"type:datetime"`
+	LastMessageAt    *time.Time `json:"last_message_at" gorm:"type:datetime"`
+	BytesReceived    int64      `json:"bytes_received"`
+	MessagesReceived int64      `json:"messages_received"`
+	LastError        string     `json:"last_error"`
+	LastErrorAt      *time.Time `json:"last_error_at" gorm:"type:datetime"`
+	IdleSince        *time.Time `json:"idle_since" gorm:"type:datetime"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DeviceStatusSummary represents the connection states of all the devices.
+type DeviceStatusSummary struct {
+	Total          int             `json:"total"`
+	Connected      int             `json:"connected"`
+	Disconnected   int             `json:"disconnected"`
+	Idle           int             `json:"idle"`
+	NeverConnected int             `json:"never_connected"`
+	Devices        []*DeviceStatus `json:"devices"`
+}
+
+// NewDeviceStatusSummary creates the summary of the statuses of the devices, the devices without a status never connected.
+func NewDeviceStatusSummary(devices []Device, statuses []*DeviceStatus) *DeviceStatusSummary {
+	byDeviceID := map[uint]*DeviceStatus{}
+	for _, status := range statuses {
+		byDeviceID[status.DeviceID] = status
+	}
+
+	summary := &DeviceStatusSummary{
+		Total:   len(devices),
+		Devices: make([]*DeviceStatus, 0, len(devices)),
+	}
+	for _, device := range devices {
+		status, ok := byDeviceID[device.ID]
+		if !ok {
+			status = &DeviceStatus{DeviceID: device.ID}
+			summary.NeverConnected++
+		} else if status.Connected {
+			summary.Connected++
+		} else {
+			summary.Disconnected++
+		}
+		if status.IdleSince != nil {
+			summary.Idle++
+		}
+
+		status.DeviceName = device.Name
+		summary.Devices = append(summary.Devices, status)
+	}
+
+	return summary
+}
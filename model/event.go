@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Types of the events of the device server.
+const (
+	EventTypeLabData            = "lab_data"
+	EventTypeRawData            = "raw_data"
+	EventTypeDeviceConnected    = "device_connected"
+	EventTypeDeviceDisconnected = "device_disconnected"
+	EventTypeDeviceIdle         = "device_idle"
+)
+
+// Event represents an event of the device server written to the outbox for the live stream of the API server.
+// Payload is the JSON of the lab data, the raw data or the connection of the event.
+type Event struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Type      string    `json:"type" gorm:"not null"`
+	DeviceID  uint      `json:"device_id"`
+	Payload   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// EventConnection represents the payload of the device connection events.
+type EventConnection struct {
+	ConnString string `json:"conn_string"`
+	DeviceName string `json:"device_name"`
+	RemoteIP   string `json:"remote_ip"`
+	RemotePort string `json:"remote_port"`
+}
@@ -1,20 +1,135 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Delivery statuses of the lab data pushed to the HIS.
+// A held lab data is pushed when it is verified.
+const (
+	DeliveryStatusNone      = ""
+	DeliveryStatusHeld      = "held"
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusFailed    = "failed"
+	DeliveryStatusDelivered = "delivered"
+)
+
+// Abnormal flags of the lab data.
+const (
+	FlagNormal       = "N"
+	FlagLow          = "L"
+	FlagHigh         = "H"
+	FlagCriticalLow  = "LL"
+	FlagCriticalHigh = "HH"
+	FlagAbnormal     = "A"
+)
+
+// Verification statuses of the lab data.
+// The held and critical lab datas are released when they are verified by a reviewer.
+const (
+	VerificationStatusAutoVerified = "auto_verified"
+	VerificationStatusHeld         = "held"
+	VerificationStatusCritical     = "critical"
+	VerificationStatusVerified     = "verified"
+)
+
+// Result statuses of the lab data.
+const (
+	ResultStatusPreliminary = "preliminary"
+	ResultStatusFinal       = "final"
+	ResultStatusCorrected   = "corrected"
+)
+
 // LabData represents a lab data received from the device
+// Param is the HIS test code, DeviceParam the test code sent by the device
+// Result and Unit are normalized to the canonical unit of the test code, OriginalResult and OriginalUnit are sent by the device
 type LabData struct {
 	gorm.Model
-	RawDataID     uint      `json:"raw_data_id" gorm:"not null;index"`
-	DeviceID      uint      `json:"device_id" gorm:"not null;index"`
-	Barcode       string    `json:"barcode" gorm:"not null;index"`
-	Index         uint      `json:"index" gorm:"not null"`
-	Param         string    `json:"param" gorm:"not null"`
-	Result        string    `json:"result" gorm:"not null"`
-	Unit          string    `json:"unit" gorm:"not null"`
-	CompletedDate time.Time `json:"completed_date" gorm:"type:datetime;not null"`
+	RawDataID          uint       `json:"raw_data_id" gorm:"not null;index"`
+	DeviceID           uint       `json:"device_id" gorm:"not null;index"`
+	Barcode            string     `json:"barcode" gorm:"not null;index"`
+	Index              uint       `json:"index" gorm:"not null"`
+	Param              string     `json:"param" gorm:"not null"`
+	DeviceParam        string     `json:"device_param"`
+	Result             string     `json:"result" gorm:"not null"`
+	Unit               string     `json:"unit" gorm:"not null"`
+	OriginalResult     string     `json:"original_result"`
+	OriginalUnit       string     `json:"original_unit"`
+	CompletedDate      time.Time  `json:"completed_date" gorm:"type:datetime;not null"`
+	ReferenceRange     string     `json:"reference_range"`
+	AbnormalFlag       string     `json:"abnormal_flag"`
+	ResultStatus       string     `json:"result_status"`
+	InstrumentFlags    string     `json:"instrument_flags"`
+	PatientID          string     `json:"patient_id" gorm:"index"`
+	PatientSex         string     `json:"patient_sex"`
+	PatientBirthDate   *time.Time `json:"patient_birth_date" gorm:"type:datetime"`
+	VerificationStatus string     `json:"verification_status" gorm:"index"`
+	VerificationReason string     `json:"verification_reason"`
+	VerifiedBy         string     `json:"verified_by"`
+	VerifiedAt         *time.Time `json:"verified_at" gorm:"type:datetime"`
+	DeliveryStatus     string     `json:"delivery_status" gorm:"index"`
+	DeliveryAttempts   uint       `json:"delivery_attempts"`
+	DeliveryError      string     `json:"delivery_error"`
+	NextDeliveryAt     *time.Time `json:"next_delivery_at" gorm:"type:datetime"`
+	DeliveredAt        *time.Time `json:"delivered_at" gorm:"type:datetime"`
+}
+
+// Released checks if the lab data may be pushed to the HIS, i.e. it is not waiting for a review.
+func (l *LabData) Released() bool {
+	return l.VerificationStatus != VerificationStatusHeld && l.VerificationStatus != VerificationStatusCritical
+}
+
+// QueueDelivery queues the lab data for the push to the HIS, a lab data waiting for a review is held until it is verified.
+func (l *LabData) QueueDelivery() {
+	if l.Released() {
+		l.DeliveryStatus = DeliveryStatusPending
+	} else {
+		l.DeliveryStatus = DeliveryStatusHeld
+	}
+}
+
+// Verify marks the lab data verified by the reviewer and releases it to the HIS if it was held.
+func (l *LabData) Verify(username string, now time.Time) {
+	l.VerificationStatus = VerificationStatusVerified
+	l.VerifiedBy = username
+	l.VerifiedAt = &now
+	if l.DeliveryStatus == DeliveryStatusHeld {
+		l.DeliveryStatus = DeliveryStatusPending
+	}
+}
+
+// SplitFlags splits the flags reported by a device into the abnormal flag and the other (instrument) flags.
+func SplitFlags(flags []string) (abnormalFlag string, instrumentFlags string) {
+	others := []string{}
+	for _, flag := range flags {
+		flag = strings.TrimSpace(flag)
+		switch strings.ToUpper(flag) {
+		case "":
+		case FlagNormal, FlagLow, FlagHigh, FlagCriticalLow, FlagCriticalHigh, FlagAbnormal:
+			if abnormalFlag == "" || abnormalFlag == FlagNormal {
+				abnormalFlag = strings.ToUpper(flag)
+			}
+		default:
+			others = append(others, flag)
+		}
+	}
+
+	return abnormalFlag, strings.Join(others, ",")
+}
+
+// NormalizeResultStatus converts the result status code of ASTM R-9 or HL7 OBX-11 to the result status.
+func NormalizeResultStatus(code string) string {
+	switch strings.ToUpper(strings.TrimSpace(code)) {
+	case "P", "I", "S":
+		return ResultStatusPreliminary
+	case "F", "U":
+		return ResultStatusFinal
+	case "C":
+		return ResultStatusCorrected
+	}
+
+	return ""
 }
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Operations of the lab data changes.
+const (
+	LabDataChangeCreated = "created"
+	LabDataChangeUpdated = "updated"
+	LabDataChangeDeleted = "deleted"
+)
+
+// LabDataChange represents a change of a lab data recorded with it, its ID is the cursor of the change feed.
+// LabData is the current state of the changed lab data, it is soft-deleted when the lab data is deleted.
+type LabDataChange struct {
+	ID        uint      `json:"cursor" gorm:"primarykey"`
+	LabDataID uint      `json:"lab_data_id" gorm:"not null;index"`
+	Operation string    `json:"operation" gorm:"not null"`
+	CreatedAt time.Time `json:"changed_at" gorm:"index"`
+	LabData   *LabData  `json:"lab_data" gorm:"-"`
+}
+
+// LabDataChangeFeed represents a page of the lab data change feed.
+// NextCursor is the cursor to continue the feed from, HasMore tells if there are more changes after it.
+type LabDataChangeFeed struct {
+	Changes    []*LabDataChange `json:"changes"`
+	NextCursor uint             `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+}
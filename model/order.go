@@ -0,0 +1,213 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sources of the orders.
+const (
+	OrderSourceQuery = "query"
+	OrderSourceHIS   = "his"
+)
+
+// Statuses of the orders.
+// An incomplete order has no pending tests but some of them are missing.
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusIncomplete = "incomplete"
+	OrderStatusCompleted  = "completed"
+)
+
+// Statuses of the order tests.
+// A test is pending until its result is received or its order is closed, it is missing when the order is closed without its result.
+const (
+	OrderTestStatusPending   = "pending"
+	OrderTestStatusCompleted = "completed"
+	OrderTestStatusMissing   = "missing"
+)
+
+// Priorities of the order tests.
+const (
+	OrderPriorityRoutine = "routine"
+	OrderPriorityASAP    = "asap"
+	OrderPriorityStat    = "stat"
+)
+
+// Sexes of the patients.
+const (
+	PatientSexMale    = "M"
+	PatientSexFemale  = "F"
+	PatientSexUnknown = "U"
+)
+
+// orderTimeFormats are the formats of the collection time and the birth date accepted from the HIS.
+var orderTimeFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "20060102150405", "2006-01-02", "20060102"}
+
+// Order represents the tests requested for a sample (barcode).
+// DeviceID is the device the tests are run on, 0 when it is not known.
+type Order struct {
+	gorm.Model
+	Barcode  string       `json:"barcode" gorm:"not null;index"`
+	DeviceID uint         `json:"device_id" gorm:"index"`
+	Source   string       `json:"source"`
+	Status   string       `json:"status" gorm:"index"`
+	Sample   OrderSample  `json:"sample" gorm:"embedded"`
+	Tests    []*OrderTest `json:"tests" gorm:"foreignKey:OrderID"`
+}
+
+// OrderSample represents the sample of the order, when it was collected and the patient it was collected from.
+type OrderSample struct {
+	CollectedAt      *time.Time `json:"collected_at" gorm:"type:datetime"`
+	PatientID        string     `json:"patient_id"`
+	PatientName      string     `json:"patient_name"`
+	PatientSex       string     `json:"patient_sex"`
+	PatientBirthDate *time.Time `json:"patient_birth_date" gorm:"type:datetime"`
+}
+
+// OrderTest represents a test (HIS test code) requested by the order.
+// Material is the material code and SpecimenType the specimen type (e.g. serum or urine) the test is run on.
+type OrderTest struct {
+	gorm.Model
+	OrderID      uint       `json:"order_id" gorm:"not null;index"`
+	Param        string     `json:"param" gorm:"not null"`
+	Material     int        `json:"material"`
+	Dilution     string     `json:"dilution"`
+	Priority     string     `json:"priority"`
+	SpecimenType string     `json:"specimen_type"`
+	Status       string     `json:"status"`
+	LabDataID    uint       `json:"lab_data_id"`
+	CompletedAt  *time.Time `json:"completed_at" gorm:"type:datetime"`
+}
+
+// SetSample sets the sample attributes which are not set yet.
+func (o *Order) SetSample(sample OrderSample) {
+	if o.Sample.CollectedAt == nil {
+		o.Sample.CollectedAt = sample.CollectedAt
+	}
+	if o.Sample.PatientID == "" {
+		o.Sample.PatientID = sample.PatientID
+	}
+	if o.Sample.PatientName == "" {
+		o.Sample.PatientName = sample.PatientName
+	}
+	if o.Sample.PatientSex == "" {
+		o.Sample.PatientSex = NormalizePatientSex(sample.PatientSex)
+	}
+	if o.Sample.PatientBirthDate == nil {
+		o.Sample.PatientBirthDate = sample.PatientBirthDate
+	}
+}
+
+// AddTests adds the tests which are not requested by the order yet as pending.
+func (o *Order) AddTests(tests []*OrderTest) {
+	for _, test := range tests {
+		if o.Test(test.Param) != nil {
+			continue
+		}
+		test.Status = OrderTestStatusPending
+		test.Priority = NormalizeOrderPriority(test.Priority)
+		o.Tests = append(o.Tests, test)
+	}
+
+	o.UpdateStatus()
+}
+
+// Test returns the test of the param or nil if it is not requested.
+func (o *Order) Test(param string) *OrderTest {
+	for _, test := range o.Tests {
+		if test.Param == param {
+			return test
+		}
+	}
+
+	return nil
+}
+
+// Reconcile completes the tests with the results of the lab datas of the barcode.
+// The tests left without results stay pending, a device may send the results of a sample in several messages.
+func (o *Order) Reconcile(labDatas []*LabData) {
+	for _, labData := range labDatas {
+		test := o.Test(labData.Param)
+		if test == nil {
+			continue
+		}
+		completedAt := labData.CompletedDate
+		test.Status = OrderTestStatusCompleted
+		test.LabDataID = labData.ID
+		test.CompletedAt = &completedAt
+	}
+
+	o.UpdateStatus()
+}
+
+// Close marks the pending tests missing, no more results are expected for the order.
+// A missing test is still completed if its result is received later.
+func (o *Order) Close() {
+	for _, test := range o.Tests {
+		if test.Status == OrderTestStatusPending {
+			test.Status = OrderTestStatusMissing
+		}
+	}
+
+	o.UpdateStatus()
+}
+
+// UpdateStatus sets the status of the order by the statuses of its tests.
+func (o *Order) UpdateStatus() {
+	o.Status = OrderStatusCompleted
+	for _, test := range o.Tests {
+		switch test.Status {
+		case OrderTestStatusPending:
+			o.Status = OrderStatusPending
+			return
+		case OrderTestStatusMissing:
+			o.Status = OrderStatusIncomplete
+		}
+	}
+}
+
+// NormalizeOrderPriority converts the priority sent by the HIS (a name or an ASTM/HL7 code) to the order priority.
+func NormalizeOrderPriority(priority string) string {
+	switch strings.ToLower(strings.TrimSpace(priority)) {
+	case "s", "stat", "c", "cito", "urgent":
+		return OrderPriorityStat
+	case "a", "asap":
+		return OrderPriorityASAP
+	}
+
+	return OrderPriorityRoutine
+}
+
+// NormalizePatientSex converts the sex sent by the HIS to M, F or U, an empty sex stays empty.
+func NormalizePatientSex(sex string) string {
+	switch strings.ToLower(strings.TrimSpace(sex)) {
+	case "":
+		return ""
+	case "m", "male":
+		return PatientSexMale
+	case "f", "female":
+		return PatientSexFemale
+	}
+
+	return PatientSexUnknown
+}
+
+// ParseOrderTime parses the collection time or the birth date sent by the HIS, nil if it is empty or invalid.
+func ParseOrderTime(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	for _, format := range orderTimeFormats {
+		t, err := time.ParseInLocation(format, value, time.Local)
+		if err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+// newTestOrder creates an order with the pending tests of the params.
+func newTestOrder(params ...string) *Order {
+	order := &Order{Barcode: "BC1", DeviceID: 1}
+	tests := []*OrderTest{}
+	for _, param := range params {
+		tests = append(tests, &OrderTest{Param: param})
+	}
+	order.AddTests(tests)
+
+	return order
+}
+
+// testStatuses returns the statuses of the tests of the order by param.
+func testStatuses(order *Order) map[string]string {
+	statuses := map[string]string{}
+	for _, test := range order.Tests {
+		statuses[test.Param] = test.Status
+	}
+
+	return statuses
+}
+
+func TestOrderAddTests(t *testing.T) {
+	order := &Order{Barcode: "BC1"}
+	order.AddTests([]*OrderTest{{Param: "GLU", Priority: "S"}, {Param: "ALT"}})
+
+	if len(order.Tests) != 2 || order.Status != OrderStatusPending {
+		t.Fatalf("order = %s with %d tests, want pending with 2 tests", order.Status, len(order.Tests))
+	}
+	if glu := order.Test("GLU"); glu.Status != OrderTestStatusPending || glu.Priority != OrderPriorityStat {
+		t.Errorf("GLU = %s %s, want pending stat", glu.Status, glu.Priority)
+	}
+	if alt := order.Test("ALT"); alt.Priority != OrderPriorityRoutine {
+		t.Errorf("ALT priority = %s, want routine", alt.Priority)
+	}
+
+	// the requested tests are kept, the new ones are added
+	order.Reconcile([]*LabData{{Param: "GLU"}})
+	order.AddTests([]*OrderTest{{Param: "GLU", Priority: "R"}, {Param: "K"}})
+
+	if len(order.Tests) != 3 {
+		t.Fatalf("order has %d tests, want 3", len(order.Tests))
+	}
+	if glu := order.Test("GLU"); glu.Status != OrderTestStatusCompleted || glu.Priority != OrderPriorityStat {
+		t.Errorf("GLU = %s %s, want the completed stat test kept", glu.Status, glu.Priority)
+	}
+	if order.Test("K") == nil || order.Status != OrderStatusPending {
+		t.Errorf("order = %s without K %v, want pending with K", order.Status, order.Test("K") == nil)
+	}
+}
+
+func TestOrderReconcile(t *testing.T) {
+	order := newTestOrder("GLU", "ALT", "K")
+
+	// the device sends the results of the sample in two messages
+	completedDate := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	order.Reconcile([]*LabData{{Param: "GLU", CompletedDate: completedDate}, {Param: "CRP"}})
+
+	want := map[string]string{"GLU": OrderTestStatusCompleted, "ALT": OrderTestStatusPending, "K": OrderTestStatusPending}
+	if got := testStatuses(order); !maps.Equal(got, want) || order.Status != OrderStatusPending {
+		t.Fatalf("after the first message order = %s with %v, want pending with %v", order.Status, got, want)
+	}
+	if glu := order.Test("GLU"); glu.CompletedAt == nil || !glu.CompletedAt.Equal(completedDate) {
+		t.Errorf("GLU completed at %v, want %s", glu.CompletedAt, completedDate)
+	}
+	if order.Test("CRP") != nil {
+		t.Error("the result of a test which is not ordered is added to the order")
+	}
+
+	alt := &LabData{Param: "ALT"}
+	alt.ID = 7
+	order.Reconcile([]*LabData{alt, {Param: "K"}})
+
+	if order.Status != OrderStatusCompleted || order.Test("ALT").LabDataID != 7 {
+		t.Errorf("after the second message order = %s with %v, want completed", order.Status, testStatuses(order))
+	}
+}
+
+func TestOrderClose(t *testing.T) {
+	order := newTestOrder("GLU", "ALT")
+	order.Reconcile([]*LabData{{Param: "GLU"}})
+	order.Close()
+
+	want := map[string]string{"GLU": OrderTestStatusCompleted, "ALT": OrderTestStatusMissing}
+	if got := testStatuses(order); !maps.Equal(got, want) || order.Status != OrderStatusIncomplete {
+		t.Fatalf("closed order = %s with %v, want incomplete with %v", order.Status, got, want)
+	}
+
+	// a late result completes the missing test
+	order.Reconcile([]*LabData{{Param: "ALT"}})
+	if order.Status != OrderStatusCompleted {
+		t.Errorf("order = %s with %v after the late result, want completed", order.Status, testStatuses(order))
+	}
+}
+
+func TestOrderUpdateStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{name: "no tests", want: OrderStatusCompleted},
+		{name: "all completed", statuses: []string{OrderTestStatusCompleted, OrderTestStatusCompleted}, want: OrderStatusCompleted},
+		{name: "pending", statuses: []string{OrderTestStatusCompleted, OrderTestStatusPending}, want: OrderStatusPending},
+		{name: "missing", statuses: []string{OrderTestStatusMissing, OrderTestStatusCompleted}, want: OrderStatusIncomplete},
+		{name: "pending before missing", statuses: []string{OrderTestStatusMissing, OrderTestStatusPending}, want: OrderStatusPending},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := &Order{}
+			for _, status := range test.statuses {
+				order.Tests = append(order.Tests, &OrderTest{Status: status})
+			}
+			order.UpdateStatus()
+			if order.Status != test.want {
+				t.Errorf("UpdateStatus() status = %s, want %s", order.Status, test.want)
+			}
+		})
+	}
+}
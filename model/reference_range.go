@@ -0,0 +1,145 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// referenceRangeRegexp matches the reference ranges sent by the devices as "low-high".
+var referenceRangeRegexp = regexp.MustCompile(`^\s*(-?[0-9]+(?:[.,][0-9]+)?)\s*-\s*(-?[0-9]+(?:[.,][0-9]+)?)\s*$`)
+
+// ReferenceRange represents a configured reference range of a HIS test code, used when the device does not send one.
+// Sex is M or F, an empty sex matches both. The ages are in days, AgeToDays 0 is unbounded.
+// An empty unit matches any unit, otherwise the range applies only to the results in the unit.
+type ReferenceRange struct {
+	gorm.Model
+	Param        string   `json:"param" gorm:"not null;index"`
+	Unit         string   `json:"unit"`
+	Sex          string   `json:"sex"`
+	AgeFromDays  uint     `json:"age_from_days"`
+	AgeToDays    uint     `json:"age_to_days"`
+	Low          *float64 `json:"low"`
+	High         *float64 `json:"high"`
+	CriticalLow  *float64 `json:"critical_low"`
+	CriticalHigh *float64 `json:"critical_high"`
+}
+
+// ReferenceRanges represents the reference ranges of the test codes.
+type ReferenceRanges []*ReferenceRange
+
+// Find finds the most specific reference range of the param for the unit and the patient.
+// ageDays is negative when the age of the patient is unknown, then only the ranges for all ages match.
+func (r ReferenceRanges) Find(param, unit, sex string, ageDays int) *ReferenceRange {
+	var found *ReferenceRange
+	for _, referenceRange := range r {
+		if !referenceRange.matches(param, unit, sex, ageDays) {
+			continue
+		}
+		if found == nil || referenceRange.specificity() > found.specificity() {
+			found = referenceRange
+		}
+	}
+
+	return found
+}
+
+// matches checks if the reference range applies to the param, the unit and the patient.
+func (r *ReferenceRange) matches(param, unit, sex string, ageDays int) bool {
+	if r.Param != param {
+		return false
+	}
+	if r.Unit != "" && !strings.EqualFold(r.Unit, unit) {
+		return false
+	}
+	if r.Sex != "" && !strings.EqualFold(r.Sex, sex) {
+		return false
+	}
+	if r.AgeFromDays == 0 && r.AgeToDays == 0 {
+		return true
+	}
+	if ageDays < 0 || uint(ageDays) < r.AgeFromDays {
+		return false
+	}
+
+	return r.AgeToDays == 0 || uint(ageDays) <= r.AgeToDays
+}
+
+// specificity returns how specific the reference range is, the ranges for the unit are preferred
+// as the limits of the other ones may be in another unit, then the ranges for a sex and an age.
+func (r *ReferenceRange) specificity() int {
+	specificity := 0
+	if r.Unit != "" {
+		specificity += 4
+	}
+	if r.Sex != "" {
+		specificity += 2
+	}
+	if r.AgeFromDays != 0 || r.AgeToDays != 0 {
+		specificity++
+	}
+
+	return specificity
+}
+
+// Text returns the reference range as it is shown with the result, e.g. "3.9-6.1", "<5" or ">60".
+func (r *ReferenceRange) Text() string {
+	switch {
+	case r.Low != nil && r.High != nil:
+		return formatLimit(*r.Low) + "-" + formatLimit(*r.High)
+	case r.High != nil:
+		return "<" + formatLimit(*r.High)
+	case r.Low != nil:
+		return ">" + formatLimit(*r.Low)
+	}
+
+	return ""
+}
+
+// Flag returns the abnormal flag of the value, the critical limits are checked first.
+func (r *ReferenceRange) Flag(value float64) string {
+	switch {
+	case r.CriticalLow != nil && value < *r.CriticalLow:
+		return FlagCriticalLow
+	case r.CriticalHigh != nil && value > *r.CriticalHigh:
+		return FlagCriticalHigh
+	case r.Low != nil && value < *r.Low:
+		return FlagLow
+	case r.High != nil && value > *r.High:
+		return FlagHigh
+	}
+
+	return FlagNormal
+}
+
+// ParseReferenceRange parses the reference range sent by a device as "low-high".
+// It returns nil when the range is in another form.
+func ParseReferenceRange(text string) *ReferenceRange {
+	matches := referenceRangeRegexp.FindStringSubmatch(text)
+	if matches == nil {
+		return nil
+	}
+
+	low, err := ParseNumericResult(matches[1])
+	if err != nil {
+		return nil
+	}
+	high, err := ParseNumericResult(matches[2])
+	if err != nil {
+		return nil
+	}
+
+	return &ReferenceRange{Low: &low, High: &high}
+}
+
+// ParseNumericResult parses the numeric result, both "." and "," are accepted as the decimal separator.
+func ParseNumericResult(result string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(result), ",", "."), 64)
+}
+
+// formatLimit formats the limit of a reference range without trailing zeros.
+func formatLimit(limit float64) string {
+	return strconv.FormatFloat(limit, 'f', -1, 64)
+}
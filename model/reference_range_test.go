@@ -0,0 +1,153 @@
+package model
+
+import "testing"
+
+func limit(value float64) *float64 {
+	return &value
+}
+
+func TestParseReferenceRange(t *testing.T) {
+	tests := []struct {
+		text string
+		low  float64
+		high float64
+		ok   bool
+	}{
+		{text: "3.9-6.1", low: 3.9, high: 6.1, ok: true},
+		{text: " 3,9 - 6,1 ", low: 3.9, high: 6.1, ok: true},
+		{text: "0-40", low: 0, high: 40, ok: true},
+		{text: "-2-3", low: -2, high: 3, ok: true},
+		{text: "-5--1", low: -5, high: -1, ok: true},
+		{text: "<5"},
+		{text: ">60"},
+		{text: "negative"},
+		{text: "3.9-"},
+		{text: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			referenceRange := ParseReferenceRange(test.text)
+			if (referenceRange != nil) != test.ok {
+				t.Fatalf("ParseReferenceRange(%q) = %v, want ok %v", test.text, referenceRange, test.ok)
+			}
+			if !test.ok {
+				return
+			}
+			if *referenceRange.Low != test.low || *referenceRange.High != test.high {
+				t.Errorf("ParseReferenceRange(%q) = %v-%v, want %v-%v", test.text, *referenceRange.Low, *referenceRange.High, test.low, test.high)
+			}
+		})
+	}
+}
+
+func TestReferenceRangeText(t *testing.T) {
+	tests := []struct {
+		name           string
+		referenceRange *ReferenceRange
+		want           string
+	}{
+		{name: "low and high", referenceRange: &ReferenceRange{Low: limit(3.9), High: limit(6.10)}, want: "3.9-6.1"},
+		{name: "high only", referenceRange: &ReferenceRange{High: limit(5)}, want: "<5"},
+		{name: "low only", referenceRange: &ReferenceRange{Low: limit(60)}, want: ">60"},
+		{name: "no limits", referenceRange: &ReferenceRange{}, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.referenceRange.Text(); got != test.want {
+				t.Errorf("Text() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReferenceRangeFlag(t *testing.T) {
+	referenceRange := &ReferenceRange{Low: limit(3.9), High: limit(6.1), CriticalLow: limit(2.5), CriticalHigh: limit(25)}
+
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{value: 2, want: FlagCriticalLow},
+		{value: 2.5, want: FlagLow},
+		{value: 3.8, want: FlagLow},
+		{value: 3.9, want: FlagNormal},
+		{value: 6.1, want: FlagNormal},
+		{value: 6.2, want: FlagHigh},
+		{value: 25, want: FlagHigh},
+		{value: 30, want: FlagCriticalHigh},
+	}
+
+	for _, test := range tests {
+		if got := referenceRange.Flag(test.value); got != test.want {
+			t.Errorf("Flag(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+
+	if got := (&ReferenceRange{High: limit(5)}).Flag(-100); got != FlagNormal {
+		t.Errorf("Flag() without a low limit = %q, want %q", got, FlagNormal)
+	}
+}
+
+func TestReferenceRangesFind(t *testing.T) {
+	all := &ReferenceRange{Param: "HGB", Low: limit(120), High: limit(160)}
+	female := &ReferenceRange{Param: "HGB", Sex: "F", Low: limit(120), High: limit(150)}
+	child := &ReferenceRange{Param: "HGB", AgeFromDays: 365, AgeToDays: 365 * 12, Low: limit(110), High: limit(140)}
+	newborn := &ReferenceRange{Param: "HGB", AgeToDays: 28, Low: limit(140), High: limit(220)}
+	girl := &ReferenceRange{Param: "HGB", Sex: "F", AgeFromDays: 365, AgeToDays: 365 * 12, Low: limit(115), High: limit(135)}
+	gramsPerDeciliter := &ReferenceRange{Param: "HGB", Unit: "g/dL", Low: limit(12), High: limit(16)}
+	ranges := ReferenceRanges{all, female, child, newborn, girl, gramsPerDeciliter}
+
+	tests := []struct {
+		name    string
+		param   string
+		unit    string
+		sex     string
+		ageDays int
+		want    *ReferenceRange
+	}{
+		{name: "adult male", param: "HGB", unit: "g/L", sex: "M", ageDays: 365 * 40, want: all},
+		{name: "adult female", param: "HGB", unit: "g/L", sex: "F", ageDays: 365 * 40, want: female},
+		{name: "sex is case insensitive", param: "HGB", unit: "g/L", sex: "f", ageDays: 365 * 40, want: female},
+		{name: "boy", param: "HGB", unit: "g/L", sex: "M", ageDays: 365 * 5, want: child},
+		{name: "girl prefers sex and age", param: "HGB", unit: "g/L", sex: "F", ageDays: 365 * 5, want: girl},
+		{name: "age upper bound is inclusive", param: "HGB", unit: "g/L", ageDays: 28, want: newborn},
+		{name: "unknown age", param: "HGB", unit: "g/L", ageDays: -1, want: all},
+		{name: "range in the unit", param: "HGB", unit: "G/DL", sex: "M", ageDays: 365 * 40, want: gramsPerDeciliter},
+		{name: "unknown param", param: "PLT", unit: "g/L", ageDays: 365 * 40, want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ranges.Find(test.param, test.unit, test.sex, test.ageDays); got != test.want {
+				t.Errorf("Find() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitFlags(t *testing.T) {
+	tests := []struct {
+		name            string
+		flags           []string
+		abnormalFlag    string
+		instrumentFlags string
+	}{
+		{name: "abnormal flag", flags: []string{"h"}, abnormalFlag: FlagHigh},
+		{name: "abnormal flag over normal", flags: []string{"N", "HH"}, abnormalFlag: FlagCriticalHigh},
+		{name: "first abnormal flag wins", flags: []string{"L", "H"}, abnormalFlag: FlagLow},
+		{name: "instrument flags", flags: []string{" W ", "", ">"}, instrumentFlags: "W,>"},
+		{name: "both", flags: []string{"A", "Q"}, abnormalFlag: FlagAbnormal, instrumentFlags: "Q"},
+		{name: "none", flags: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			abnormalFlag, instrumentFlags := SplitFlags(test.flags)
+			if abnormalFlag != test.abnormalFlag || instrumentFlags != test.instrumentFlags {
+				t.Errorf("SplitFlags(%q) = %q, %q, want %q, %q", test.flags, abnormalFlag, instrumentFlags, test.abnormalFlag, test.instrumentFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+// StreamLock represents the lock of a stream read by cursor, the lab data changes or the events.
+// The transactions writing to a stream lock its row before creating their rows and hold it until they are committed,
+// so the IDs of a stream are committed in their order and a reader past an ID never misses a row committed later.
+type StreamLock struct {
+	Name    string `gorm:"primarykey;size:64"`
+	Version uint   `gorm:"not null"`
+}
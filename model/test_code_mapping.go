@@ -0,0 +1,36 @@
+package model
+
+import "gorm.io/gorm"
+
+// TestCodeMapping represents the mapping of a test code of a device model to a test code (indicator) of the HIS.
+type TestCodeMapping struct {
+	gorm.Model
+	DeviceModelID uint   `json:"device_model_id" gorm:"not null;index"`
+	DeviceCode    string `json:"device_code" gorm:"not null;index"`
+	HISCode       string `json:"his_code" gorm:"not null;index"`
+}
+
+// TestCodeMappings represents the test code mappings of a device model.
+type TestCodeMappings []*TestCodeMapping
+
+// ToHIS returns the HIS test code of the device test code or the device test code if it is not mapped.
+func (m TestCodeMappings) ToHIS(deviceCode string) string {
+	for _, mapping := range m {
+		if mapping.DeviceCode == deviceCode {
+			return mapping.HISCode
+		}
+	}
+
+	return deviceCode
+}
+
+// ToDevice returns the device test code of the HIS test code or the HIS test code if it is not mapped.
+func (m TestCodeMappings) ToDevice(hisCode string) string {
+	for _, mapping := range m {
+		if mapping.HISCode == hisCode {
+			return mapping.DeviceCode
+		}
+	}
+
+	return hisCode
+}
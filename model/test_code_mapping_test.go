@@ -0,0 +1,54 @@
+package model
+
+import "testing"
+
+var testMappings = TestCodeMappings{
+	{DeviceCode: "GLU", HISCode: "GLUCOSE"},
+	{DeviceCode: "ALT", HISCode: "ALAT"},
+	{DeviceCode: "ALT2", HISCode: "ALAT"},
+}
+
+func TestTestCodeMappingsToHIS(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "mapped", code: "GLU", want: "GLUCOSE"},
+		{name: "several device codes of a HIS code", code: "ALT2", want: "ALAT"},
+		{name: "not mapped", code: "CRP", want: "CRP"},
+		{name: "case sensitive", code: "glu", want: "glu"},
+		{name: "empty", code: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := testMappings.ToHIS(test.code); got != test.want {
+				t.Errorf("ToHIS(%q) = %q, want %q", test.code, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTestCodeMappingsToDevice(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings TestCodeMappings
+		code     string
+		want     string
+	}{
+		{name: "mapped", mappings: testMappings, code: "GLUCOSE", want: "GLU"},
+		{name: "first of several device codes", mappings: testMappings, code: "ALAT", want: "ALT"},
+		{name: "not mapped", mappings: testMappings, code: "CRP", want: "CRP"},
+		{name: "device code is not a HIS code", mappings: testMappings, code: "GLU", want: "GLU"},
+		{name: "no mappings", mappings: nil, code: "GLUCOSE", want: "GLUCOSE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.mappings.ToDevice(test.code); got != test.want {
+				t.Errorf("ToDevice(%q) = %q, want %q", test.code, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Unit represents a unit of the unit registry.
+// Aliases are the other spellings of the unit sent by the devices separated by ",", e.g. "mmol/l,mM" for "mmol/L".
+type Unit struct {
+	gorm.Model
+	Code    string `json:"code" gorm:"not null;index"`
+	Name    string `json:"name"`
+	Aliases string `json:"aliases"`
+}
+
+// Units represents the unit registry.
+type Units []*Unit
+
+// Normalize returns the registered code of the unit or the unit itself if it is not registered.
+// The codes and the aliases are compared case-insensitively.
+func (u Units) Normalize(unit string) string {
+	unit = strings.TrimSpace(unit)
+	for _, registered := range u {
+		if strings.EqualFold(registered.Code, unit) {
+			return registered.Code
+		}
+		for _, alias := range strings.Split(registered.Aliases, ",") {
+			if alias = strings.TrimSpace(alias); alias != "" && strings.EqualFold(alias, unit) {
+				return registered.Code
+			}
+		}
+	}
+
+	return unit
+}
@@ -0,0 +1,38 @@
+package model
+
+import "gorm.io/gorm"
+
+// UnitConversion represents the conversion of the results of a HIS test code from a unit to another one.
+// The converted value is value * Factor + Offset. An empty param applies to every test code.
+type UnitConversion struct {
+	gorm.Model
+	Param    string  `json:"param" gorm:"index"`
+	FromUnit string  `json:"from_unit" gorm:"not null"`
+	ToUnit   string  `json:"to_unit" gorm:"not null"`
+	Factor   float64 `json:"factor" gorm:"not null"`
+	Offset   float64 `json:"offset"`
+}
+
+// UnitConversions represents the unit conversions.
+type UnitConversions []*UnitConversion
+
+// Converter returns the function converting the values of the param between the units.
+// The conversion of the param is preferred to the one for every test code, a conversion in the other direction is inverted.
+// The units must be normalized with the unit registry.
+func (u UnitConversions) Converter(param, fromUnit, toUnit string) (func(float64) float64, bool) {
+	for _, p := range []string{param, ""} {
+		for _, conversion := range u {
+			if conversion.Param != p || conversion.Factor == 0 {
+				continue
+			}
+			if conversion.FromUnit == fromUnit && conversion.ToUnit == toUnit {
+				return func(value float64) float64 { return value*conversion.Factor + conversion.Offset }, true
+			}
+			if conversion.FromUnit == toUnit && conversion.ToUnit == fromUnit {
+				return func(value float64) float64 { return (value - conversion.Offset) / conversion.Factor }, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnitConversionsConverter(t *testing.T) {
+	conversions := UnitConversions{
+		{Param: "GLU", FromUnit: "mg/dL", ToUnit: "mmol/L", Factor: 0.0555},
+		{Param: "", FromUnit: "mg/dL", ToUnit: "mmol/L", Factor: 0.1},
+		{Param: "", FromUnit: "g/L", ToUnit: "g/dL", Factor: 0.1},
+		{Param: "TEMP", FromUnit: "C", ToUnit: "F", Factor: 1.8, Offset: 32},
+		{Param: "BAD", FromUnit: "a", ToUnit: "b", Factor: 0},
+	}
+
+	tests := []struct {
+		name     string
+		param    string
+		fromUnit string
+		toUnit   string
+		value    float64
+		want     float64
+		ok       bool
+	}{
+		{name: "conversion of the param", param: "GLU", fromUnit: "mg/dL", toUnit: "mmol/L", value: 100, want: 5.55, ok: true},
+		{name: "conversion for every param", param: "CHOL", fromUnit: "mg/dL", toUnit: "mmol/L", value: 100, want: 10, ok: true},
+		{name: "inverted conversion", param: "HGB", fromUnit: "g/dL", toUnit: "g/L", value: 14, want: 140, ok: true},
+		{name: "offset", param: "TEMP", fromUnit: "C", toUnit: "F", value: 37, want: 98.6, ok: true},
+		{name: "inverted offset", param: "TEMP", fromUnit: "F", toUnit: "C", value: 98.6, want: 37, ok: true},
+		{name: "zero factor is skipped", param: "BAD", fromUnit: "a", toUnit: "b"},
+		{name: "no conversion", param: "GLU", fromUnit: "mmol/L", toUnit: "g/L"},
+		{name: "conversion of another param", param: "CHOL", fromUnit: "C", toUnit: "F"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			convert, ok := conversions.Converter(test.param, test.fromUnit, test.toUnit)
+			if ok != test.ok {
+				t.Fatalf("Converter() ok = %v, want %v", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if got := convert(test.value); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("convert(%v) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
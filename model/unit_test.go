@@ -0,0 +1,34 @@
+package model
+
+import "testing"
+
+func TestUnitsNormalize(t *testing.T) {
+	units := Units{
+		{Code: "mmol/L", Aliases: "mmol/l, mM"},
+		{Code: "mg/dL", Aliases: "mg%,mg/100mL"},
+		{Code: "10^9/L", Aliases: ""},
+	}
+
+	tests := []struct {
+		unit string
+		want string
+	}{
+		{unit: "mmol/L", want: "mmol/L"},
+		{unit: "MMOL/L", want: "mmol/L"},
+		{unit: " mM ", want: "mmol/L"},
+		{unit: "mg%", want: "mg/dL"},
+		{unit: "MG/100ML", want: "mg/dL"},
+		{unit: "10^9/l", want: "10^9/L"},
+		{unit: "U/L", want: "U/L"},
+		{unit: " g/L ", want: "g/L"},
+		{unit: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.unit, func(t *testing.T) {
+			if got := units.Normalize(test.unit); got != test.want {
+				t.Errorf("Normalize(%q) = %q, want %q", test.unit, got, test.want)
+			}
+		})
+	}
+}
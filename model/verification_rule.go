@@ -0,0 +1,153 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of the verification rules.
+const (
+	VerificationRuleTypeRange          = "range"
+	VerificationRuleTypeDelta          = "delta"
+	VerificationRuleTypeCritical       = "critical"
+	VerificationRuleTypeInstrumentFlag = "instrument_flag"
+)
+
+// VerificationRule represents a rule the lab datas are checked with before they are released.
+// An empty param applies to every test code, the limits are in the canonical unit of the test code.
+//   - range holds the numeric results out of Low and High.
+//   - critical marks the numeric results out of Low and High as critical.
+//   - delta holds the numeric results differing from the previous result of the patient by more than DeltaAbsolute or DeltaPercent,
+//     the previous results older than DeltaWindowHours are ignored, 0 is unbounded.
+//   - instrument_flag holds the results with one of the instrument flags in Flags separated by ",", an empty Flags matches any instrument flag.
+type VerificationRule struct {
+	gorm.Model
+	Name             string   `json:"name"`
+	Param            string   `json:"param" gorm:"index"`
+	Type             string   `json:"type" gorm:"not null"`
+	Low              *float64 `json:"low"`
+	High             *float64 `json:"high"`
+	DeltaAbsolute    *float64 `json:"delta_absolute"`
+	DeltaPercent     *float64 `json:"delta_percent"`
+	DeltaWindowHours uint     `json:"delta_window_hours"`
+	Flags            string   `json:"flags"`
+	Disabled         bool     `json:"disabled"`
+}
+
+// VerificationRules represents the verification rules.
+type VerificationRules []*VerificationRule
+
+// ForParam returns the enabled rules applying to the param.
+func (r VerificationRules) ForParam(param string) VerificationRules {
+	rules := VerificationRules{}
+	for _, rule := range r {
+		if !rule.Disabled && (rule.Param == "" || rule.Param == param) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// HasType checks if one of the rules is of the type.
+func (r VerificationRules) HasType(ruleType string) bool {
+	for _, rule := range r {
+		if rule.Type == ruleType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check checks the lab data with the rule and returns the verification status it leads to with the reason,
+// or an empty status when the rule is passed. previous is the previous result of the patient, used by the delta rules.
+func (r *VerificationRule) Check(labData *LabData, previous *LabData) (string, string) {
+	switch r.Type {
+	case VerificationRuleTypeRange, VerificationRuleTypeCritical:
+		value, err := ParseNumericResult(labData.Result)
+		if err != nil || !r.outOfLimits(value) {
+			return "", ""
+		}
+		if r.Type == VerificationRuleTypeCritical {
+			return VerificationStatusCritical, fmt.Sprintf("%s: critical value %s", r.name(), labData.Result)
+		}
+		return VerificationStatusHeld, fmt.Sprintf("%s: %s out of range", r.name(), labData.Result)
+	case VerificationRuleTypeDelta:
+		if !r.deltaExceeded(labData, previous) {
+			return "", ""
+		}
+		return VerificationStatusHeld, fmt.Sprintf("%s: %s differs from the previous result %s", r.name(), labData.Result, previous.Result)
+	case VerificationRuleTypeInstrumentFlag:
+		flag := r.matchingFlag(labData.InstrumentFlags)
+		if flag == "" {
+			return "", ""
+		}
+		return VerificationStatusHeld, fmt.Sprintf("%s: instrument flag %s", r.name(), flag)
+	}
+
+	return "", ""
+}
+
+// outOfLimits checks if the value is lower than Low or higher than High.
+func (r *VerificationRule) outOfLimits(value float64) bool {
+	return (r.Low != nil && value < *r.Low) || (r.High != nil && value > *r.High)
+}
+
+// deltaExceeded checks if the numeric result differs from the previous one in the window by more than the allowed delta.
+func (r *VerificationRule) deltaExceeded(labData *LabData, previous *LabData) bool {
+	if previous == nil {
+		return false
+	}
+	if r.DeltaWindowHours != 0 && labData.CompletedDate.Sub(previous.CompletedDate) > time.Duration(r.DeltaWindowHours)*time.Hour {
+		return false
+	}
+
+	value, err := ParseNumericResult(labData.Result)
+	if err != nil {
+		return false
+	}
+	previousValue, err := ParseNumericResult(previous.Result)
+	if err != nil {
+		return false
+	}
+
+	delta := math.Abs(value - previousValue)
+	if r.DeltaAbsolute != nil && delta > *r.DeltaAbsolute {
+		return true
+	}
+
+	return r.DeltaPercent != nil && previousValue != 0 && delta/math.Abs(previousValue)*100 > *r.DeltaPercent
+}
+
+// matchingFlag returns the first of the instrument flags matched by the rule.
+func (r *VerificationRule) matchingFlag(instrumentFlags string) string {
+	for _, flag := range strings.Split(instrumentFlags, ",") {
+		if flag == "" {
+			continue
+		}
+		if strings.TrimSpace(r.Flags) == "" {
+			return flag
+		}
+		for _, ruleFlag := range strings.Split(r.Flags, ",") {
+			if strings.EqualFold(strings.TrimSpace(ruleFlag), flag) {
+				return flag
+			}
+		}
+	}
+
+	return ""
+}
+
+// name returns the name of the rule used in the verification reasons.
+func (r *VerificationRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return fmt.Sprintf("%s rule %d", r.Type, r.ID)
+}
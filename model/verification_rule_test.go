@@ -0,0 +1,148 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestVerificationRuleCheck(t *testing.T) {
+	completed := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	previous := &LabData{Result: "5.0", CompletedDate: completed.Add(-24 * time.Hour)}
+
+	tests := []struct {
+		name     string
+		rule     *VerificationRule
+		labData  *LabData
+		previous *LabData
+		status   string
+		reason   string
+	}{
+		{
+			name:    "range passed",
+			rule:    &VerificationRule{Name: "GLU range", Type: VerificationRuleTypeRange, Low: limit(2), High: limit(20)},
+			labData: &LabData{Result: "20"},
+		},
+		{
+			name:    "range held",
+			rule:    &VerificationRule{Name: "GLU range", Type: VerificationRuleTypeRange, Low: limit(2), High: limit(20)},
+			labData: &LabData{Result: "20,5"},
+			status:  VerificationStatusHeld,
+			reason:  "GLU range: 20,5 out of range",
+		},
+		{
+			name:    "range without a high limit",
+			rule:    &VerificationRule{Type: VerificationRuleTypeRange, Low: limit(2)},
+			labData: &LabData{Result: "1000"},
+		},
+		{
+			name:    "range of a text result",
+			rule:    &VerificationRule{Type: VerificationRuleTypeRange, Low: limit(2)},
+			labData: &LabData{Result: "positive"},
+		},
+		{
+			name:    "critical",
+			rule:    &VerificationRule{Model: gorm.Model{ID: 7}, Type: VerificationRuleTypeCritical, Low: limit(2.5)},
+			labData: &LabData{Result: "1.9"},
+			status:  VerificationStatusCritical,
+			reason:  "critical rule 7: critical value 1.9",
+		},
+		{
+			name:     "delta absolute exceeded",
+			rule:     &VerificationRule{Name: "delta", Type: VerificationRuleTypeDelta, DeltaAbsolute: limit(2)},
+			labData:  &LabData{Result: "7.5", CompletedDate: completed},
+			previous: previous,
+			status:   VerificationStatusHeld,
+			reason:   "delta: 7.5 differs from the previous result 5.0",
+		},
+		{
+			name:     "delta absolute passed",
+			rule:     &VerificationRule{Type: VerificationRuleTypeDelta, DeltaAbsolute: limit(2)},
+			labData:  &LabData{Result: "3", CompletedDate: completed},
+			previous: previous,
+		},
+		{
+			name:     "delta percent exceeded",
+			rule:     &VerificationRule{Name: "delta", Type: VerificationRuleTypeDelta, DeltaPercent: limit(20)},
+			labData:  &LabData{Result: "3.9", CompletedDate: completed},
+			previous: previous,
+			status:   VerificationStatusHeld,
+			reason:   "delta: 3.9 differs from the previous result 5.0",
+		},
+		{
+			name:     "delta out of the window",
+			rule:     &VerificationRule{Type: VerificationRuleTypeDelta, DeltaAbsolute: limit(2), DeltaWindowHours: 12},
+			labData:  &LabData{Result: "10", CompletedDate: completed},
+			previous: previous,
+		},
+		{
+			name:    "delta without a previous result",
+			rule:    &VerificationRule{Type: VerificationRuleTypeDelta, DeltaAbsolute: limit(2)},
+			labData: &LabData{Result: "10", CompletedDate: completed},
+		},
+		{
+			name:     "delta percent of a zero previous result",
+			rule:     &VerificationRule{Type: VerificationRuleTypeDelta, DeltaPercent: limit(20)},
+			labData:  &LabData{Result: "10", CompletedDate: completed},
+			previous: &LabData{Result: "0", CompletedDate: completed},
+		},
+		{
+			name:    "instrument flag matched",
+			rule:    &VerificationRule{Name: "flags", Type: VerificationRuleTypeInstrumentFlag, Flags: "W, >"},
+			labData: &LabData{InstrumentFlags: "Q,w"},
+			status:  VerificationStatusHeld,
+			reason:  "flags: instrument flag w",
+		},
+		{
+			name:    "instrument flag not matched",
+			rule:    &VerificationRule{Type: VerificationRuleTypeInstrumentFlag, Flags: "W"},
+			labData: &LabData{InstrumentFlags: "Q"},
+		},
+		{
+			name:    "any instrument flag",
+			rule:    &VerificationRule{Name: "flags", Type: VerificationRuleTypeInstrumentFlag},
+			labData: &LabData{InstrumentFlags: "Q"},
+			status:  VerificationStatusHeld,
+			reason:  "flags: instrument flag Q",
+		},
+		{
+			name:    "no instrument flags",
+			rule:    &VerificationRule{Type: VerificationRuleTypeInstrumentFlag},
+			labData: &LabData{},
+		},
+		{
+			name:    "unknown type",
+			rule:    &VerificationRule{Type: "unknown", Low: limit(2)},
+			labData: &LabData{Result: "1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, reason := test.rule.Check(test.labData, test.previous)
+			if status != test.status || reason != test.reason {
+				t.Errorf("Check() = %q, %q, want %q, %q", status, reason, test.status, test.reason)
+			}
+		})
+	}
+}
+
+func TestVerificationRulesForParam(t *testing.T) {
+	all := &VerificationRule{Type: VerificationRuleTypeInstrumentFlag}
+	glucose := &VerificationRule{Param: "GLU", Type: VerificationRuleTypeDelta}
+	disabled := &VerificationRule{Param: "GLU", Type: VerificationRuleTypeRange, Disabled: true}
+	cholesterol := &VerificationRule{Param: "CHOL", Type: VerificationRuleTypeRange}
+	rules := VerificationRules{all, glucose, disabled, cholesterol}
+
+	got := rules.ForParam("GLU")
+	if len(got) != 2 || got[0] != all || got[1] != glucose {
+		t.Errorf("ForParam(GLU) = %v, want the rule of every param and the glucose rule", got)
+	}
+	if !got.HasType(VerificationRuleTypeDelta) {
+		t.Error("HasType(delta) = false, want true")
+	}
+	if got.HasType(VerificationRuleTypeRange) {
+		t.Error("HasType(range) = true, want false as the range rule is disabled")
+	}
+}
@@ -0,0 +1,181 @@
+// Package reprocess provides the reprocessing of the stored raw datas with the current drivers of their devices.
+package reprocess
+
+import (
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/driver"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// Result is the outcome of the reprocessing of a raw data.
+type Result struct {
+	RawDataID uint   `json:"raw_data_id"`
+	DeviceID  uint   `json:"device_id"`
+	Processed bool   `json:"processed"`
+	LabDatas  int    `json:"lab_datas"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Reprocessor re-parses the stored raw datas and replaces their lab datas.
+// The new lab datas go through the pipeline of the received ones, the orders are reconciled and the events are recorded.
+// The critical alerts are not raised again, they were raised when the raw datas were received.
+// Nothing is sent to the devices, the post-unmarshal actions and the acknowledgements are skipped.
+type Reprocessor struct {
+	log      *log.Logger
+	store    *store.Store
+	push     bool
+	pipeline *services.LabDataPipeline
+	devices  map[uint]*deviceProcessing
+}
+
+// deviceProcessing is what the raw datas of a device are processed with.
+type deviceProcessing struct {
+	driver   driver.Driver
+	labDatas *services.LabDataService
+	orders   *services.OrderService
+}
+
+// NewReprocessor creates a new Reprocessor running the lab datas through the pipeline.
+// With push the new lab datas are queued for the delivery to the HIS.
+func NewReprocessor(logger *log.Logger, store *store.Store, push bool, pipeline *services.LabDataPipeline) *Reprocessor {
+	return &Reprocessor{
+		log:      logger,
+		store:    store,
+		push:     push,
+		pipeline: pipeline,
+		devices:  map[uint]*deviceProcessing{},
+	}
+}
+
+// NewPipeline creates the pipeline of the reprocessed lab datas with the settings of the device server.
+// The events are stored for the API server to send them, the queued lab datas are delivered by the result delivery of the device server.
+// The critical alerts are skipped, raising them again would duplicate the alerts of the received lab datas and resend their webhooks.
+func NewPipeline(logger *log.Logger, store *store.Store) (*services.LabDataPipeline, error) {
+	settings, err := config.ReadDeviceServerConfig()
+	if err != nil {
+		logger.Err(err, "failed to read the device server config")
+		return nil, err
+	}
+
+	var events *services.EventService
+	if settings.Events.Enabled {
+		events = services.NewEventService(logger, store, settings.Events)
+	}
+
+	return services.NewLabDataPipeline(logger, nil, nil, events), nil
+}
+
+// Reprocess reprocesses the raw datas selected by the filter and returns the outcome of each of them.
+func (r *Reprocessor) Reprocess(filter *store.RawDataFilter) ([]*Result, error) {
+	rawDatas, err := r.store.RawDataStore.GetByFilter(filter)
+	if err != nil {
+		r.log.Err(err, "failed to get the raw datas to reprocess")
+		return nil, err
+	}
+
+	results := make([]*Result, 0, len(rawDatas))
+	for _, rawData := range rawDatas {
+		results = append(results, r.reprocessRawData(rawData))
+	}
+
+	return results, nil
+}
+
+// reprocessRawData unmarshals the raw data with the driver of its device and replaces its lab datas.
+// The lab datas of a raw data which fails again are kept.
+func (r *Reprocessor) reprocessRawData(rawData *model.RawData) *Result {
+	result := &Result{
+		RawDataID: rawData.ID,
+		DeviceID:  rawData.DeviceID,
+	}
+
+	device, err := r.getDevice(rawData.DeviceID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	labDatas, _, err := device.driver.Unmarshal(string(rawData.Data))
+	if err == nil {
+		err = device.labDatas.Prepare(labDatas)
+	}
+	if err != nil {
+		r.log.Err(err, fmt.Sprintf("failed to reprocess the raw data %d", rawData.ID))
+		result.Error = err.Error()
+		result = r.storeProcessed(rawData, false, result)
+		r.pipeline.Stored(rawData, nil, device.orders)
+		return result
+	}
+
+	for _, labData := range labDatas {
+		labData.RawDataID = rawData.ID
+		labData.DeviceID = rawData.DeviceID
+		if r.push {
+			labData.QueueDelivery()
+		}
+	}
+
+	err = r.store.LabDataStore.ReplaceByRawDataID(rawData.ID, labDatas)
+	if err != nil {
+		r.log.Err(err, fmt.Sprintf("failed to store the lab datas of the raw data %d", rawData.ID))
+		result.Error = err.Error()
+		return result
+	}
+
+	result.LabDatas = len(labDatas)
+	result = r.storeProcessed(rawData, true, result)
+	r.pipeline.Stored(rawData, labDatas, device.orders)
+
+	return result
+}
+
+// storeProcessed stores the processed flag of the raw data.
+func (r *Reprocessor) storeProcessed(rawData *model.RawData, processed bool, result *Result) *Result {
+	result.Processed = processed
+	if rawData.Processed == processed {
+		return result
+	}
+
+	rawData.Processed = processed
+	err := r.store.RawDataStore.Update(rawData)
+	if err != nil {
+		r.log.Err(err, fmt.Sprintf("failed to update the raw data %d", rawData.ID))
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// getDevice gets the driver, the lab data service and the order service of the device, creating them on the first use.
+func (r *Reprocessor) getDevice(deviceID uint) (*deviceProcessing, error) {
+	processing, ok := r.devices[deviceID]
+	if ok {
+		return processing, nil
+	}
+
+	device, err := r.store.DeviceStore.GetByID(deviceID)
+	if err != nil {
+		r.log.Err(err, "failed to get the device of the raw data")
+		return nil, err
+	}
+
+	deviceDriver, err := driver.NewDriver(device.DeviceModel.Driver, r.log, r.store, nil)
+	if err != nil {
+		r.log.Err(err, fmt.Sprintf("failed to create a driver for %s with driver name %s", device.Name, device.DeviceModel.Driver))
+		return nil, err
+	}
+
+	processing = &deviceProcessing{
+		driver:   deviceDriver,
+		labDatas: services.NewLabDataService(r.store, device.DeviceModelID),
+		orders:   services.NewOrderService(r.log, r.store, device.ID),
+	}
+	r.devices[deviceID] = processing
+
+	return processing, nil
+}
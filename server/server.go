@@ -2,7 +2,8 @@
 package server
 
 import (
-	"os"
+	"context"
+	"errors"
 
 	"github.com/voidmaindev/doctra_lis_middleware/app"
 	"github.com/voidmaindev/doctra_lis_middleware/log"
@@ -48,11 +49,15 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// Stop stops the server.
-func (s *Server) Stop() {
-	err := s.App.Stop()
+// Stop stops the server, the application finishes its work in progress until the context is done.
+// The log is flushed and closed last.
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.App.Stop(ctx)
 	if err != nil {
-		s.Log.Fatal("failed to stop the application")
+		s.Log.Err(err, "failed to stop the application")
 	}
-	os.Exit(0)
+
+	s.Log.Info("stopped")
+
+	return errors.Join(err, s.Log.Close())
 }
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+const (
+	defaultAlertInterval   = 10 * time.Second
+	defaultAlertMaxBackoff = 10 * time.Minute
+	alertBatchSize         = 100
+	alertSignatureHeader   = "X-Signature"
+	alertSignaturePrefix   = "sha256="
+	alertTimestampHeader   = "X-Timestamp"
+)
+
+// CriticalAlertRequestBody represents the critical alert posted to the webhook
+type CriticalAlertRequestBody struct {
+	AlertID       uint      `json:"alert_id"`
+	LabDataID     uint      `json:"lab_data_id"`
+	Device        string    `json:"device"`
+	HardwareSN    string    `json:"hardware_sn"`
+	Barcode       string    `json:"barcode"`
+	Param         string    `json:"param"`
+	Result        string    `json:"result"`
+	Unit          string    `json:"unit"`
+	AbnormalFlag  string    `json:"abnormal_flag"`
+	Reason        string    `json:"reason"`
+	CompletedDate time.Time `json:"completed_date"`
+	DetectedAt    time.Time `json:"detected_at"`
+}
+
+// CriticalAlertService sends the alerts of the critical lab datas to the webhook, retrying the failed ones with backoff.
+// The alerts are created by the LabDataStore with the lab datas.
+// The body is signed with HMAC-SHA256 of the timestamp and the body joined by ".", the signature is sent in the X-Signature header
+// as "sha256=" followed by the hex digest and the unix timestamp in the X-Timestamp header.
+type CriticalAlertService struct {
+	log        *log.Logger
+	store      *store.Store
+	client     *resty.Client
+	webhookURL string
+	secret     string
+	interval   time.Duration
+	maxBackoff time.Duration
+	started    bool
+	notify     chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewCriticalAlertService creates a new CriticalAlertService, the webhook requests must be signed with a secret
+func NewCriticalAlertService(logger *log.Logger, store *store.Store, settings config.CriticalAlertSettings) (*CriticalAlertService, error) {
+	if settings.Secret == "" {
+		return nil, errors.New("the secret of the critical alerts webhook is empty")
+	}
+
+	s := &CriticalAlertService{
+		log:        logger,
+		store:      store,
+		client:     newHTTPClient(time.Duration(settings.TimeoutSeconds) * time.Second),
+		webhookURL: settings.WebhookURL,
+		secret:     settings.Secret,
+		interval:   time.Duration(settings.IntervalSeconds) * time.Second,
+		maxBackoff: time.Duration(settings.MaxBackoffSeconds) * time.Second,
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultAlertInterval
+	}
+	if s.maxBackoff <= 0 {
+		s.maxBackoff = defaultAlertMaxBackoff
+	}
+
+	return s, nil
+}
+
+// Start starts sending the alerts in the background
+func (s *CriticalAlertService) Start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	go s.run()
+}
+
+// Stop stops sending the alerts and waits for the current one to finish, it does nothing if the sending was not started
+func (s *CriticalAlertService) Stop() {
+	if !s.started {
+		return
+	}
+
+	s.started = false
+	close(s.stop)
+	<-s.done
+}
+
+// Notify wakes the sending up to send the new alerts
+func (s *CriticalAlertService) Notify() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the due alerts on every tick or notification until stopped
+func (s *CriticalAlertService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.notify:
+		}
+	}
+}
+
+// sendDue sends the due alerts one by one and stores their notification state
+func (s *CriticalAlertService) sendDue() {
+	alerts, err := s.store.CriticalAlertStore.GetDueForNotification(time.Now(), alertBatchSize)
+	if err != nil {
+		s.log.Err(err, "failed to get the critical alerts due for notification")
+		return
+	}
+
+	for _, alert := range alerts {
+		alert.NotificationAttempts++
+
+		err := s.send(alert)
+		if err != nil {
+			nextNotificationAt := time.Now().Add(s.backoff(alert.NotificationAttempts))
+			s.log.Err(err, fmt.Sprintf("failed to send the critical alert %d, attempt %d, next at %s", alert.ID, alert.NotificationAttempts, nextNotificationAt.Format(time.RFC3339)))
+			alert.NotificationError = err.Error()
+			alert.NextNotificationAt = &nextNotificationAt
+		} else {
+			notifiedAt := time.Now()
+			alert.NotificationError = ""
+			alert.NextNotificationAt = nil
+			alert.NotifiedAt = &notifiedAt
+		}
+
+		err = s.store.CriticalAlertStore.Update(alert)
+		if err != nil {
+			s.log.Err(err, "failed to store the critical alert notification")
+		}
+	}
+}
+
+// send posts the signed alert to the webhook
+func (s *CriticalAlertService) send(alert *model.CriticalAlert) error {
+	reqBody := &CriticalAlertRequestBody{
+		AlertID:      alert.ID,
+		LabDataID:    alert.LabDataID,
+		Barcode:      alert.Barcode,
+		Param:        alert.Param,
+		Result:       alert.Result,
+		Unit:         alert.Unit,
+		AbnormalFlag: alert.AbnormalFlag,
+		Reason:       alert.Reason,
+		DetectedAt:   alert.CreatedAt,
+	}
+
+	device, err := s.store.DeviceStore.GetByID(alert.DeviceID)
+	if err != nil {
+		s.log.Err(err, "failed to get the device of the critical alert")
+	} else {
+		reqBody.Device = device.Name
+		reqBody.HardwareSN = device.Serial
+	}
+
+	labData, err := s.store.LabDataStore.GetByID(alert.LabDataID)
+	if err != nil {
+		s.log.Err(err, "failed to get the lab data of the critical alert")
+	} else {
+		reqBody.CompletedDate = labData.CompletedDate
+	}
+
+	return postSignedWebhook(s.client, s.webhookURL, s.secret, reqBody)
+}
+
+// postSignedWebhook posts the JSON of the request body to the webhook, signed with the secret
+func postSignedWebhook(client *resty.Client, url, secret string, reqBody interface{}) error {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	resp, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader(alertTimestampHeader, timestamp).
+		SetHeader(alertSignatureHeader, alertSignaturePrefix+signWebhook(secret, timestamp, body)).
+		SetBody(body).
+		Post(url)
+	if err != nil {
+		return err
+	}
+
+	// Check for HTTP status code
+	if resp.IsError() {
+		return errors.New("HTTP error: " + resp.Status())
+	}
+
+	return nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 signature of the timestamp and the body with the secret
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt, doubling the interval on every failed attempt
+func (s *CriticalAlertService) backoff(attempts uint) time.Duration {
+	delay := s.interval
+	for i := uint(1); i < attempts && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.maxBackoff)
+}
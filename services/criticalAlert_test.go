@@ -0,0 +1,147 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+)
+
+func TestPostSignedWebhook(t *testing.T) {
+	const secret = "s3cret"
+
+	var verifyErr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(alertTimestampHeader)
+		signature, ok := strings.CutPrefix(r.Header.Get(alertSignatureHeader), "sha256=")
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		switch {
+		case !ok:
+			verifyErr = "no sha256= prefix in " + r.Header.Get(alertSignatureHeader)
+		case timestamp == "":
+			verifyErr = "no timestamp"
+		case !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))):
+			verifyErr = "wrong signature " + signature
+		case r.Header.Get("Content-Type") != "application/json":
+			verifyErr = "wrong content type " + r.Header.Get("Content-Type")
+		}
+	}))
+	defer server.Close()
+
+	err := postSignedWebhook(newHTTPClient(time.Second), server.URL, secret, &CriticalAlertRequestBody{AlertID: 1, Param: "K", Result: "7.1"})
+	if err != nil {
+		t.Fatalf("postSignedWebhook() error = %v", err)
+	}
+	if verifyErr != "" {
+		t.Errorf("the webhook failed to verify the request: %s", verifyErr)
+	}
+}
+
+func TestPostSignedWebhookErrors(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	err := postSignedWebhook(newHTTPClient(time.Second), server.URL+"/error", "", &CriticalAlertRequestBody{})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("postSignedWebhook() error = %v, want the HTTP error", err)
+	}
+
+	start := time.Now()
+	err = postSignedWebhook(newHTTPClient(time.Second), server.URL+"/slow", "", &CriticalAlertRequestBody{})
+	if err == nil {
+		t.Error("postSignedWebhook() error = nil, want the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("postSignedWebhook() took %v, want it to time out after a second", elapsed)
+	}
+}
+
+func TestNewCriticalAlertServiceWithoutSecret(t *testing.T) {
+	_, err := NewCriticalAlertService(&log.Logger{Disabled: true}, nil, config.CriticalAlertSettings{Enabled: true, WebhookURL: "http://localhost"})
+	if err == nil {
+		t.Error("NewCriticalAlertService() without a secret error = nil, want an error")
+	}
+}
+
+func TestCriticalAlertsStoredWithLabDatas(t *testing.T) {
+	st := storetest.New(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+
+	received := make(chan CriticalAlertRequestBody, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body CriticalAlertRequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer server.Close()
+
+	critical := &model.LabData{RawDataID: 1, DeviceID: device.ID, Barcode: "BC1", Param: "K", Result: "7.1", VerificationStatus: model.VerificationStatusCritical}
+	labDatas := []*model.LabData{
+		{RawDataID: 1, DeviceID: device.ID, Barcode: "BC1", Param: "NA", Result: "140"},
+		critical,
+	}
+	err := st.LabDataStore.CreateAll(labDatas)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+
+	// the alerts are stored with the lab datas whether they are sent or not
+	alerts, err := st.CriticalAlertStore.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].LabDataID != critical.ID || alerts[0].Param != "K" || alerts[0].NotifiedAt != nil {
+		t.Fatalf("alerts = %+v, want the unsent alert of the critical lab data", alerts)
+	}
+
+	s, err := NewCriticalAlertService(&log.Logger{Disabled: true}, st, config.CriticalAlertSettings{Enabled: true, WebhookURL: server.URL, Secret: "secret", TimeoutSeconds: 1})
+	if err != nil {
+		t.Fatalf("NewCriticalAlertService() error = %v", err)
+	}
+	s.sendDue()
+
+	select {
+	case body := <-received:
+		if body.AlertID != alerts[0].ID || body.HardwareSN != "SN1" || body.Result != "7.1" {
+			t.Errorf("alert = %+v, want the alert of the critical lab data from SN1", body)
+		}
+	default:
+		t.Fatal("the critical alert was not sent")
+	}
+
+	alert, err := st.CriticalAlertStore.GetByID(alerts[0].ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if alert.NotifiedAt == nil || alert.NotificationAttempts != 1 {
+		t.Errorf("alert notified at %v after %d attempts, want notified after 1 attempt", alert.NotifiedAt, alert.NotificationAttempts)
+	}
+
+	// the sent alerts are not sent again
+	s.sendDue()
+	if len(received) != 0 {
+		t.Error("the critical alert was sent twice")
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+)
+
+// CSVQueryBackend answers the queries from a static CSV worklist.
+// The header row names the columns by the JSON names of the indicator fields (barcode, indicator, material, dilution, priority, specimen_type, ...)
+// and hardware_sn, the rows without a hardware_sn are ordered on every device.
+// The file is read on every query, so the changes of the worklist are picked up without a restart.
+type CSVQueryBackend struct {
+	file string
+}
+
+// NewCSVQueryBackend creates a new CSVQueryBackend.
+func NewCSVQueryBackend(settings config.QueryBackendSettings) (*CSVQueryBackend, error) {
+	if settings.File == "" {
+		return nil, fmt.Errorf("the file is not set")
+	}
+
+	return &CSVQueryBackend{
+		file: settings.File,
+	}, nil
+}
+
+// Query returns the rows of the worklist with the barcode for the device with the serial.
+func (b *CSVQueryBackend) Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error) {
+	f, err := os.Open(b.file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the worklist: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	_, okBarcode := columns["barcode"]
+	_, okIndicator := columns["indicator"]
+	if !okBarcode || !okIndicator {
+		return nil, fmt.Errorf("the worklist has no barcode or indicator column")
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	indicators := []DeviceQueryIndicator{}
+	for _, record := range records[1:] {
+		if value(record, "barcode") != barcode || value(record, "indicator") == "" {
+			continue
+		}
+
+		hardwareSN := value(record, "hardware_sn")
+		if hardwareSN != "" && hardwareSN != serial {
+			continue
+		}
+
+		material, _ := strconv.Atoi(value(record, "material"))
+
+		indicators = append(indicators, DeviceQueryIndicator{
+			Indicator:        value(record, "indicator"),
+			Material:         material,
+			Dilution:         value(record, "dilution"),
+			Barcode:          barcode,
+			Priority:         value(record, "priority"),
+			SpecimenType:     value(record, "specimen_type"),
+			CollectionTime:   value(record, "collection_time"),
+			PatientID:        value(record, "patient_id"),
+			PatientName:      value(record, "patient_name"),
+			PatientSex:       value(record, "patient_sex"),
+			PatientBirthDate: value(record, "patient_birth_date"),
+		})
+	}
+
+	return indicators, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+)
+
+// newTestCSVQueryBackend writes the worklist to a temporary file and creates a CSVQueryBackend of it.
+func newTestCSVQueryBackend(t *testing.T, worklist string) *CSVQueryBackend {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "worklist.csv")
+	err := os.WriteFile(file, []byte(worklist), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	b, err := NewCSVQueryBackend(config.QueryBackendSettings{File: file})
+	if err != nil {
+		t.Fatalf("NewCSVQueryBackend() error = %v", err)
+	}
+
+	return b
+}
+
+func TestNewCSVQueryBackendWithoutFile(t *testing.T) {
+	_, err := NewCSVQueryBackend(config.QueryBackendSettings{})
+	if err == nil {
+		t.Error("NewCSVQueryBackend() without a file error = nil, want an error")
+	}
+}
+
+func TestCSVQueryBackendQuery(t *testing.T) {
+	b := newTestCSVQueryBackend(t, ""+
+		"Barcode, Indicator, Material, Hardware_SN, Priority, Patient_ID\n"+
+		"BC1, GLU, 2, , S, P1\n"+
+		"BC1, ALT, 1, SN1, R, P1\n"+
+		"BC1, AST, 1, SN2, R, P1\n"+
+		"BC1, , 1, , R, P1\n"+
+		"BC2, NA\n")
+
+	tests := []struct {
+		name    string
+		barcode string
+		serial  string
+		want    []DeviceQueryIndicator
+	}{
+		{
+			name:    "rows of every device and of the serial",
+			barcode: "BC1",
+			serial:  "SN1",
+			want: []DeviceQueryIndicator{
+				{Indicator: "GLU", Material: 2, Barcode: "BC1", Priority: "S", PatientID: "P1"},
+				{Indicator: "ALT", Material: 1, Barcode: "BC1", Priority: "R", PatientID: "P1"},
+			},
+		},
+		{
+			name:    "rows of another device skipped",
+			barcode: "BC1",
+			serial:  "SN3",
+			want: []DeviceQueryIndicator{
+				{Indicator: "GLU", Material: 2, Barcode: "BC1", Priority: "S", PatientID: "P1"},
+			},
+		},
+		{
+			name:    "short row",
+			barcode: "BC2",
+			serial:  "SN1",
+			want:    []DeviceQueryIndicator{{Indicator: "NA", Barcode: "BC2"}},
+		},
+		{
+			name:    "unknown barcode",
+			barcode: "BC3",
+			serial:  "SN1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indicators, err := b.Query(context.Background(), test.barcode, test.serial)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(indicators) != len(test.want) {
+				t.Fatalf("Query() = %+v, want %+v", indicators, test.want)
+			}
+			for i := range test.want {
+				if indicators[i] != test.want[i] {
+					t.Errorf("indicator %d = %+v, want %+v", i, indicators[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCSVQueryBackendRereadsFile(t *testing.T) {
+	b := newTestCSVQueryBackend(t, "barcode,indicator\nBC1,GLU\n")
+
+	err := os.WriteFile(b.file, []byte("barcode,indicator\nBC1,GLU\nBC1,K\n"), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	indicators, err := b.Query(context.Background(), "BC1", "SN1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(indicators) != 2 {
+		t.Errorf("Query() = %+v, want the changed worklist", indicators)
+	}
+}
+
+func TestCSVQueryBackendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		worklist string
+	}{
+		{name: "no indicator column", worklist: "barcode,test\nBC1,GLU\n"},
+		{name: "no barcode column", worklist: "sample,indicator\nBC1,GLU\n"},
+		{name: "invalid CSV", worklist: "barcode,indicator\nBC1,\"GLU\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newTestCSVQueryBackend(t, test.worklist)
+
+			_, err := b.Query(context.Background(), "BC1", "SN1")
+			if err == nil {
+				t.Error("Query() error = nil, want an error")
+			}
+		})
+	}
+
+	b := &CSVQueryBackend{file: filepath.Join(t.TempDir(), "missing.csv")}
+	_, err := b.Query(context.Background(), "BC1", "SN1")
+	if err == nil {
+		t.Error("Query() of a missing file error = nil, want an error")
+	}
+}
@@ -1,16 +1,13 @@
 // Package services provides services for the application
 package services
 
-import (
-	"errors"
-
-	"github.com/go-resty/resty/v2"
-)
+import "github.com/voidmaindev/doctra_lis_middleware/model"
 
 type DeviceQueryService struct {
-	client    *resty.Client
-	queryHost string
+	client    *QueryClient
 	device    string
+	testCodes *TestCodeService
+	orders    *OrderService
 }
 
 // RequestBody represents the structure of your request
@@ -20,11 +17,19 @@ type DeviceQueryRequestBody struct {
 }
 
 // Indicator represents each indicator in the response
+// Priority is "routine", "asap" or "stat" (or their ASTM/HL7 codes), the sample and patient attributes are the same in all the indicators of a barcode
 type DeviceQueryIndicator struct {
-	Indicator string `json:"indicator"`
-	Material  int    `json:"material"`
-	Dilution  string `json:"dilution"`
-	Barcode   string `json:"barcode"`
+	Indicator        string `json:"indicator"`
+	Material         int    `json:"material"`
+	Dilution         string `json:"dilution"`
+	Barcode          string `json:"barcode"`
+	Priority         string `json:"priority"`
+	SpecimenType     string `json:"specimen_type"`
+	CollectionTime   string `json:"collection_time"`
+	PatientID        string `json:"patient_id"`
+	PatientName      string `json:"patient_name"`
+	PatientSex       string `json:"patient_sex"`
+	PatientBirthDate string `json:"patient_birth_date"`
 }
 
 // ResponseBody represents the structure of your response
@@ -33,16 +38,29 @@ type DeviceQueryResponseBody struct {
 }
 
 // DeviceQueryResponce represents the structure of your response
+// Priority is one of the order priorities, Sample holds the sample and patient attributes of the barcode
 type DeviceQueryDataToReturn struct {
-	Param string
+	Param        string
+	Material     int
+	Dilution     string
+	Priority     string
+	SpecimenType string
+	Sample       model.OrderSample
 }
 
 // ToReturn converts the response to the structure you want to return
 func (r *DeviceQueryResponseBody) ToReturn() []DeviceQueryDataToReturn {
+	sample := OrderSampleFromIndicators(r.Indicators)
+
 	var data []DeviceQueryDataToReturn
 	for _, indicator := range r.Indicators {
 		data = append(data, DeviceQueryDataToReturn{
-			Param: indicator.Indicator,
+			Param:        indicator.Indicator,
+			Material:     indicator.Material,
+			Dilution:     indicator.Dilution,
+			Priority:     model.NormalizeOrderPriority(indicator.Priority),
+			SpecimenType: indicator.SpecimenType,
+			Sample:       sample,
 		})
 	}
 
@@ -50,35 +68,75 @@ func (r *DeviceQueryResponseBody) ToReturn() []DeviceQueryDataToReturn {
 }
 
 // NewDeviceQueryService creates a new DeviceQueryService
-func NewDeviceQueryService(queryHost, device string) *DeviceQueryService {
+// The HIS is queried through the shared client, the HIS test codes of the response are translated to the device test codes with testCodes, the answered tests are recorded as orders
+func NewDeviceQueryService(client *QueryClient, device string, testCodes *TestCodeService, orders *OrderService) *DeviceQueryService {
 	return &DeviceQueryService{
-		client:    resty.New(),
-		queryHost: queryHost,
+		client:    client,
 		device:    device,
+		testCodes: testCodes,
+		orders:    orders,
 	}
 }
 
-// Query queries the device
+// Query answers the host query of the device for the barcode.
+// The pending tests ordered by the HIS in advance are answered locally, the HIS is queried only when there are none.
 func (s *DeviceQueryService) Query(barcode string) ([]DeviceQueryDataToReturn, error) {
-	reqBody := &DeviceQueryRequestBody{
-		Barcode:    barcode,
-		HardwareSN: s.device,
+	dataToReturn := s.queryOrders(barcode)
+	if len(dataToReturn) == 0 {
+		var err error
+		dataToReturn, err = s.queryHIS(barcode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.testCodes.MapQueryData(dataToReturn)
+	if err != nil {
+		return nil, err
 	}
 
-	respBody := &DeviceQueryResponseBody{}
+	return dataToReturn, nil
+}
 
-	resp, err := s.client.R().
-		SetBody(reqBody).
-		SetResult(respBody).
-		Post(s.queryHost)
+// queryOrders gets the pending tests of the barcode from the local orders.
+// A failure is only logged and falls back to the HIS query.
+func (s *DeviceQueryService) queryOrders(barcode string) []DeviceQueryDataToReturn {
+	orders, err := s.orders.PendingOrders(barcode)
 	if err != nil {
-		return nil, errors.New("" + err.Error())
+		s.orders.log.Err(err, "failed to get the pending tests of barcode "+barcode)
+		return nil
+	}
+
+	var data []DeviceQueryDataToReturn
+	for _, order := range orders {
+		for _, test := range order.Tests {
+			data = append(data, DeviceQueryDataToReturn{
+				Param:        test.Param,
+				Material:     test.Material,
+				Dilution:     test.Dilution,
+				Priority:     model.NormalizeOrderPriority(test.Priority),
+				SpecimenType: test.SpecimenType,
+				Sample:       order.Sample,
+			})
+		}
 	}
 
-	// Check for HTTP status code
-	if resp.IsError() {
-		return nil, errors.New("HTTP error: " + resp.Status())
+	return data
+}
+
+// queryHIS queries the query backend of the device for the tests of the barcode and records them as an order.
+func (s *DeviceQueryService) queryHIS(barcode string) ([]DeviceQueryDataToReturn, error) {
+	indicators, err := s.client.Query(barcode, s.device)
+	if err != nil {
+		return nil, err
+	}
+	if len(indicators) == 0 {
+		return nil, nil
 	}
 
+	s.orders.RecordQuery(barcode, indicators)
+
+	respBody := &DeviceQueryResponseBody{Indicators: indicators}
+
 	return respBody.ToReturn(), nil
 }
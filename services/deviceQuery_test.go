@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+)
+
+func TestDeviceQueryServiceQuery(t *testing.T) {
+	hisIndicators := []DeviceQueryIndicator{
+		{Indicator: "GLUCOSE", Material: 1, Priority: "S", PatientID: "P1"},
+		{Indicator: "ALAT", Material: 1},
+	}
+
+	tests := []struct {
+		name       string
+		orders     []string
+		backendErr error
+		indicators []DeviceQueryIndicator
+		want       []string
+		wantCalls  int
+		wantErr    bool
+		wantOrder  []string
+	}{
+		{
+			name:       "local pending orders win",
+			orders:     []string{"CRP", "GLUCOSE"},
+			indicators: hisIndicators,
+			want:       []string{"CRP", "GLU"},
+			wantOrder:  []string{"CRP", "GLUCOSE"},
+		},
+		{
+			name:       "HIS answer recorded",
+			indicators: hisIndicators,
+			want:       []string{"GLU", "ALT"},
+			wantCalls:  1,
+			wantOrder:  []string{"GLUCOSE", "ALAT"},
+		},
+		{
+			name:      "HIS without tests",
+			wantCalls: 1,
+		},
+		{
+			name:       "HIS error",
+			backendErr: errors.New("connection refused"),
+			wantCalls:  1,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			st := storetest.New(t)
+			device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+			createTestCodeMappings(t, st, device.DeviceModelID, [2]string{"GLU", "GLUCOSE"}, [2]string{"ALT", "ALAT"})
+
+			orders := NewOrderService(&log.Logger{Disabled: true}, st, device.ID)
+			if len(test.orders) > 0 {
+				_, err := orders.Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests(test.orders...))
+				if err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+			}
+
+			backend := &fakeQueryBackend{errs: []error{test.backendErr}, indicators: test.indicators}
+			client := newTestQueryClient(backend, config.QuerySettings{TimeoutSeconds: 1})
+			s := NewDeviceQueryService(client, device.Serial, NewTestCodeService(st, device.DeviceModelID), orders)
+
+			data, err := s.Query("BC1")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Query() error = %v, want error %v", err, test.wantErr)
+			}
+			if backend.Calls() != test.wantCalls {
+				t.Errorf("the HIS was queried %d times, want %d", backend.Calls(), test.wantCalls)
+			}
+
+			params := []string{}
+			for _, d := range data {
+				params = append(params, d.Param)
+			}
+			if len(params) != len(test.want) {
+				t.Fatalf("Query() params = %v, want %v", params, test.want)
+			}
+			for i := range test.want {
+				if params[i] != test.want[i] {
+					t.Errorf("Query() params = %v, want %v", params, test.want)
+					break
+				}
+			}
+
+			// the recorded order keeps the HIS test codes
+			recorded, err := st.OrderStore.GetByBarcode("BC1")
+			if err != nil {
+				t.Fatalf("GetByBarcode() error = %v", err)
+			}
+			if len(test.wantOrder) == 0 {
+				if len(recorded) != 0 {
+					t.Errorf("orders = %d, want none recorded", len(recorded))
+				}
+				return
+			}
+			if len(recorded) != 1 || recorded[0].DeviceID != device.ID {
+				t.Fatalf("orders = %d, want one order of the device", len(recorded))
+			}
+			if got := orderParams(recorded[0]); len(got) != len(test.wantOrder) || got[0] != test.wantOrder[0] || got[1] != test.wantOrder[1] {
+				t.Errorf("order tests = %v, want %v", got, test.wantOrder)
+			}
+		})
+	}
+}
+
+func TestDeviceQueryServiceHISAnswer(t *testing.T) {
+	st := storetest.New(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+
+	backend := &fakeQueryBackend{indicators: []DeviceQueryIndicator{
+		{Indicator: "GLU", Material: 2, Dilution: "10", Priority: "S", SpecimenType: "serum", CollectionTime: "2024-05-01 08:30:00", PatientID: "P1", PatientSex: "male"},
+	}}
+	client := newTestQueryClient(backend, config.QuerySettings{TimeoutSeconds: 1})
+	s := NewDeviceQueryService(client, device.Serial, NewTestCodeService(st, device.DeviceModelID), NewOrderService(&log.Logger{Disabled: true}, st, device.ID))
+
+	data, err := s.Query("BC1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(data) != 1 {
+		t.Fatalf("Query() = %+v, want the test of the HIS", data)
+	}
+	d := data[0]
+	if d.Material != 2 || d.Dilution != "10" || d.Priority != model.OrderPriorityStat || d.SpecimenType != "serum" {
+		t.Errorf("Query() = %+v, want the material, the dilution, the stat priority and the specimen type of the HIS", d)
+	}
+	if d.Sample.PatientID != "P1" || d.Sample.PatientSex != model.PatientSexMale || d.Sample.CollectedAt == nil {
+		t.Errorf("sample = %+v, want the patient and the collection time of the HIS", d.Sample)
+	}
+
+	// the recorded order answers the next query of the barcode
+	data, err = s.Query("BC1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if backend.Calls() != 1 || len(data) != 1 || data[0].Priority != model.OrderPriorityStat || data[0].Sample.PatientID != "P1" {
+		t.Errorf("second Query() = %+v after %d HIS queries, want the recorded order without querying the HIS", data, backend.Calls())
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+const (
+	defaultStatusFlushInterval = 10 * time.Second
+	defaultIdleAlertMinutes    = 30
+	workTimeFormat             = "15:04"
+)
+
+// DeviceIdleAlertRequestBody represents the alert of an idle device posted to the webhook
+type DeviceIdleAlertRequestBody struct {
+	DeviceID      uint       `json:"device_id"`
+	Device        string     `json:"device"`
+	HardwareSN    string     `json:"hardware_sn"`
+	Connected     bool       `json:"connected"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	IdleSince     time.Time  `json:"idle_since"`
+	IdleMinutes   int        `json:"idle_minutes"`
+	DetectedAt    time.Time  `json:"detected_at"`
+}
+
+// DeviceStatusService keeps the connection states of the devices and raises the alerts of the devices sending nothing during the working hours.
+// The connections and the disconnections are saved at once, the counters and the times of the received data every flush interval.
+// An idle alert is raised once per idle period in the background, the webhook is not retried.
+// The names and the serials of the alerts are those of the devices at the start or at their last connection.
+type DeviceStatusService struct {
+	log        *log.Logger
+	store      *store.Store
+	events     *EventService
+	client     *resty.Client
+	interval   time.Duration
+	idleAlerts config.IdleAlertSettings
+	idleAfter  time.Duration
+	workStart  *time.Duration
+	workEnd    *time.Duration
+	startedAt  time.Time
+	statuses   map[uint]*model.DeviceStatus
+	devices    map[uint]model.Device
+	dirty      map[uint]bool
+	mu         sync.Mutex
+	saveMu     sync.Mutex
+	alerts     sync.WaitGroup
+	started    bool
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewDeviceStatusService creates a new DeviceStatusService
+// The devices left connected by the previous run of the device server are marked disconnected.
+func NewDeviceStatusService(logger *log.Logger, store *store.Store, events *EventService, settings config.DeviceStatusSettings) (*DeviceStatusService, error) {
+	s := &DeviceStatusService{
+		log:        logger,
+		store:      store,
+		events:     events,
+		client:     newHTTPClient(time.Duration(settings.IdleAlerts.TimeoutSeconds) * time.Second),
+		interval:   time.Duration(settings.FlushIntervalSeconds) * time.Second,
+		idleAlerts: settings.IdleAlerts,
+		idleAfter:  time.Duration(settings.IdleAlerts.IdleMinutes) * time.Minute,
+		startedAt:  time.Now(),
+		statuses:   map[uint]*model.DeviceStatus{},
+		devices:    map[uint]model.Device{},
+		dirty:      map[uint]bool{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultStatusFlushInterval
+	}
+	if s.idleAfter <= 0 {
+		s.idleAfter = defaultIdleAlertMinutes * time.Minute
+	}
+
+	var err error
+	if s.workStart, err = parseWorkTime(settings.IdleAlerts.WorkStart); err != nil {
+		return nil, err
+	}
+	if s.workEnd, err = parseWorkTime(settings.IdleAlerts.WorkEnd); err != nil {
+		return nil, err
+	}
+	if s.workStart != nil && s.workEnd != nil && *s.workEnd <= *s.workStart {
+		return nil, errors.New("the end of the working hours must be after their start")
+	}
+
+	err = store.DeviceStatusStore.DisconnectAll(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := store.DeviceStatusStore.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		s.statuses[status.DeviceID] = status
+	}
+
+	devices, err := store.DeviceStore.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		s.devices[device.ID] = device
+	}
+
+	return s, nil
+}
+
+// parseWorkTime parses the "HH:MM" time of the working hours into the duration since midnight, nil if it is empty
+func parseWorkTime(value string) (*time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(workTimeFormat, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the working hours time %s: %v", value, err)
+	}
+
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	return &d, nil
+}
+
+// Start starts saving the statuses and checking the idle devices in the background
+func (s *DeviceStatusService) Start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	go s.run()
+}
+
+// Stop stops the background work, saves the pending statuses and waits for the alerts being sent, it does nothing if the work was not started
+func (s *DeviceStatusService) Stop() {
+	if !s.started {
+		return
+	}
+
+	s.started = false
+	close(s.stop)
+	<-s.done
+	s.alerts.Wait()
+}
+
+// Connected records the connection of the device, its name and serial are kept for its idle alerts
+func (s *DeviceStatusService) Connected(device *model.Device, connString string) {
+	s.update(device.ID, true, func(status *model.DeviceStatus, now time.Time) {
+		s.devices[device.ID] = *device
+		status.Connected = true
+		status.ConnString = connString
+		status.ConnectedSince = &now
+	})
+}
+
+// Disconnected records the disconnection of the device and the error which closed its connection
+// The device stays connected if it has a newer connection.
+func (s *DeviceStatusService) Disconnected(deviceID uint, connString string, err error) {
+	s.update(deviceID, true, func(status *model.DeviceStatus, now time.Time) {
+		if status.ConnString == connString {
+			status.Connected = false
+			status.DisconnectedAt = &now
+		}
+		if err != nil {
+			status.LastError = err.Error()
+			status.LastErrorAt = &now
+		}
+	})
+}
+
+// Received records the data received from the device on the connection, ending its idle period
+func (s *DeviceStatusService) Received(deviceID uint, connString string, bytes int) {
+	s.update(deviceID, false, func(status *model.DeviceStatus, now time.Time) {
+		if !status.Connected || status.ConnString != connString {
+			status.Connected = true
+			status.ConnString = connString
+			status.ConnectedSince = &now
+		}
+		if status.IdleSince != nil {
+			s.log.Info(fmt.Sprintf("device %d sends data again after being idle since %s", deviceID, status.IdleSince.Format(time.RFC3339)))
+			status.IdleSince = nil
+		}
+
+		status.BytesReceived += int64(bytes)
+		status.LastMessageAt = &now
+	})
+}
+
+// MessageReceived counts a complete message received from the device
+func (s *DeviceStatusService) MessageReceived(deviceID uint) {
+	s.update(deviceID, false, func(status *model.DeviceStatus, now time.Time) {
+		status.MessagesReceived++
+	})
+}
+
+// Failed records the failure of the processing of a message of the device
+func (s *DeviceStatusService) Failed(deviceID uint, err error) {
+	s.update(deviceID, false, func(status *model.DeviceStatus, now time.Time) {
+		status.LastError = err.Error()
+		status.LastErrorAt = &now
+	})
+}
+
+// update applies the change to the status of the device, saving it at once or with the next flush
+func (s *DeviceStatusService) update(deviceID uint, saveNow bool, change func(status *model.DeviceStatus, now time.Time)) {
+	if deviceID == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	status, ok := s.statuses[deviceID]
+	if !ok {
+		status = &model.DeviceStatus{DeviceID: deviceID}
+		s.statuses[deviceID] = status
+	}
+	change(status, time.Now())
+	s.dirty[deviceID] = true
+	s.mu.Unlock()
+
+	if saveNow {
+		s.flush()
+	}
+}
+
+// flush saves the changed statuses
+// The saves are serialized, so an older copy of a status never overwrites a newer one.
+func (s *DeviceStatusService) flush() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	statuses := make([]model.DeviceStatus, 0, len(s.dirty))
+	for deviceID := range s.dirty {
+		statuses = append(statuses, *s.statuses[deviceID])
+	}
+	clear(s.dirty)
+	s.mu.Unlock()
+
+	for _, status := range statuses {
+		err := s.store.DeviceStatusStore.Save(&status)
+		if err != nil {
+			s.log.Err(err, fmt.Sprintf("failed to save the status of device %d", status.DeviceID))
+		}
+	}
+}
+
+// run saves the statuses and checks the idle devices on every tick until stopped
+func (s *DeviceStatusService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.flush()
+			return
+		case <-ticker.C:
+		}
+
+		if s.idleAlerts.Enabled {
+			s.checkIdle(time.Now())
+		}
+		s.flush()
+	}
+}
+
+// checkIdle raises the alerts of the devices which sent nothing for the idle time during the working hours
+// The alerts are raised in the background, a slow webhook does not hold the saving of the statuses.
+// A device without a status never connected, it is idle since the start of the working period or of the service.
+// The statuses of the unknown devices, deleted since their last connection, are skipped.
+func (s *DeviceStatusService) checkIdle(now time.Time) {
+	if !s.isWorkingTime(now) {
+		return
+	}
+	periodStart := s.workingPeriodStart(now)
+
+	s.mu.Lock()
+	idle := []model.DeviceStatus{}
+	devices := []model.Device{}
+	for deviceID, device := range s.devices {
+		status, ok := s.statuses[deviceID]
+		if !ok {
+			status = &model.DeviceStatus{DeviceID: deviceID}
+		}
+		if status.IdleSince != nil {
+			continue
+		}
+
+		var since *time.Time
+		for _, t := range []*time.Time{status.LastMessageAt, status.ConnectedSince, periodStart} {
+			if t != nil && (since == nil || t.After(*since)) {
+				since = t
+			}
+		}
+		if since == nil {
+			since = &s.startedAt
+		}
+		if now.Sub(*since) < s.idleAfter {
+			continue
+		}
+
+		idleSince := *since
+		status.IdleSince = &idleSince
+		s.statuses[deviceID] = status
+		s.dirty[deviceID] = true
+		idle = append(idle, *status)
+		devices = append(devices, device)
+	}
+	s.mu.Unlock()
+
+	for i := range idle {
+		s.alerts.Add(1)
+		go func(status model.DeviceStatus, device model.Device) {
+			defer s.alerts.Done()
+
+			s.raise(&status, &device, now)
+		}(idle[i], devices[i])
+	}
+}
+
+// isWorkingTime checks if the time is in the working days and hours
+func (s *DeviceStatusService) isWorkingTime(now time.Time) bool {
+	if len(s.idleAlerts.WorkingDays) > 0 && !slices.Contains(s.idleAlerts.WorkingDays, int(now.Weekday())) {
+		return false
+	}
+
+	timeOfDay := now.Sub(midnight(now))
+	if s.workStart != nil && timeOfDay < *s.workStart {
+		return false
+	}
+	if s.workEnd != nil && timeOfDay >= *s.workEnd {
+		return false
+	}
+
+	return true
+}
+
+// workingPeriodStart returns the start of the working period of the time, the idle time is not counted before it.
+// It is today at the start of the working hours or midnight after a day off, nil if the working time never stops.
+func (s *DeviceStatusService) workingPeriodStart(now time.Time) *time.Time {
+	start := midnight(now)
+	if s.workStart != nil {
+		start = start.Add(*s.workStart)
+		return &start
+	}
+
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+	if len(s.idleAlerts.WorkingDays) > 0 && !slices.Contains(s.idleAlerts.WorkingDays, int(yesterday)) {
+		return &start
+	}
+
+	return nil
+}
+
+// midnight returns the start of the day of the time
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// raise logs the idle alert of the device, writes it to the events and posts it to the webhook if it is set
+func (s *DeviceStatusService) raise(status *model.DeviceStatus, device *model.Device, now time.Time) {
+	alert := &DeviceIdleAlertRequestBody{
+		DeviceID:      status.DeviceID,
+		Device:        device.Name,
+		HardwareSN:    device.Serial,
+		Connected:     status.Connected,
+		LastMessageAt: status.LastMessageAt,
+		IdleSince:     *status.IdleSince,
+		IdleMinutes:   int(now.Sub(*status.IdleSince).Minutes()),
+		DetectedAt:    now,
+	}
+
+	s.log.Warn(fmt.Sprintf("device %s sent nothing for %d minutes since %s", alert.Device, alert.IdleMinutes, alert.IdleSince.Format(time.RFC3339)))
+	s.events.PublishDeviceIdle(alert)
+
+	if s.idleAlerts.WebhookURL == "" {
+		return
+	}
+
+	err := postSignedWebhook(s.client, s.idleAlerts.WebhookURL, s.idleAlerts.Secret, alert)
+	if err != nil {
+		s.log.Err(err, "failed to send the idle alert of device "+alert.Device)
+	}
+}
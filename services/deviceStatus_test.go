@@ -0,0 +1,198 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// newTestDeviceStatusService creates a DeviceStatusService without a store, alerting the devices idle for 30 minutes all day long.
+func newTestDeviceStatusService(webhookURL string, timeout time.Duration) *DeviceStatusService {
+	return &DeviceStatusService{
+		log:        &log.Logger{Disabled: true},
+		client:     newHTTPClient(timeout),
+		idleAlerts: config.IdleAlertSettings{Enabled: true, WebhookURL: webhookURL, Secret: "secret"},
+		idleAfter:  30 * time.Minute,
+		startedAt:  time.Now(),
+		statuses:   map[uint]*model.DeviceStatus{},
+		devices:    map[uint]model.Device{},
+		dirty:      map[uint]bool{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func TestDeviceStatusIdleAlert(t *testing.T) {
+	alerts := make(chan *http.Request, 1)
+	bodies := make(chan DeviceIdleAlertRequestBody, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body DeviceIdleAlertRequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		alerts <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	s := newTestDeviceStatusService(server.URL, time.Second)
+
+	now := time.Now()
+	lastMessageAt := now.Add(-time.Hour)
+	s.devices[7] = model.Device{Model: gorm.Model{ID: 7}, Name: "Analyzer", Serial: "SN7"}
+	s.statuses[7] = &model.DeviceStatus{DeviceID: 7, Connected: true, LastMessageAt: &lastMessageAt}
+
+	s.checkIdle(now)
+	s.alerts.Wait()
+
+	select {
+	case r := <-alerts:
+		if !strings.HasPrefix(r.Header.Get(alertSignatureHeader), alertSignaturePrefix) {
+			t.Errorf("signature = %q, want the %s prefix", r.Header.Get(alertSignatureHeader), alertSignaturePrefix)
+		}
+	default:
+		t.Fatal("the idle alert was not posted")
+	}
+
+	body := <-bodies
+	if body.DeviceID != 7 || body.Device != "Analyzer" || body.HardwareSN != "SN7" {
+		t.Errorf("alert = %+v, want the name and the serial of device 7", body)
+	}
+	if body.IdleMinutes != 60 || !body.IdleSince.Equal(lastMessageAt) {
+		t.Errorf("alert idle since %s for %d minutes, want since the last message for 60 minutes", body.IdleSince, body.IdleMinutes)
+	}
+	if s.statuses[7].IdleSince == nil || !s.dirty[7] {
+		t.Error("the status is not marked idle")
+	}
+
+	// an alert is raised once per idle period
+	s.checkIdle(now.Add(time.Hour))
+	s.alerts.Wait()
+	select {
+	case <-alerts:
+		t.Error("the idle alert was posted twice")
+	default:
+	}
+}
+
+func TestDeviceStatusIdleAlertInBackground(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := newTestDeviceStatusService(server.URL, time.Second)
+
+	now := time.Now()
+	lastMessageAt := now.Add(-time.Hour)
+	s.devices[7] = model.Device{Model: gorm.Model{ID: 7}, Name: "Analyzer"}
+	s.statuses[7] = &model.DeviceStatus{DeviceID: 7, LastMessageAt: &lastMessageAt}
+
+	start := time.Now()
+	s.checkIdle(now)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("checkIdle() took %v, want the webhook posted in the background", elapsed)
+	}
+
+	// the hung webhook times out
+	waited := make(chan struct{})
+	go func() {
+		s.alerts.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle alert did not time out")
+	}
+}
+
+func TestDeviceStatusIdleAlertUnknownDevice(t *testing.T) {
+	s := newTestDeviceStatusService("", time.Second)
+
+	now := time.Now()
+	lastMessageAt := now.Add(-time.Hour)
+	s.statuses[7] = &model.DeviceStatus{DeviceID: 7, LastMessageAt: &lastMessageAt}
+
+	s.checkIdle(now)
+	s.alerts.Wait()
+
+	if s.statuses[7].IdleSince != nil {
+		t.Error("the status of an unknown device is marked idle")
+	}
+}
+
+func TestDeviceStatusIdleAlertWithoutStatus(t *testing.T) {
+	now := time.Now()
+	today := midnight(now)
+	workStart := 8 * time.Hour
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		startedAt time.Time
+		workStart *time.Duration
+		idleSince *time.Time
+	}{
+		{name: "idle since the start of the service", now: now, startedAt: now.Add(-time.Hour), idleSince: timePtr(now.Add(-time.Hour))},
+		{name: "service started recently", now: now, startedAt: now.Add(-10 * time.Minute)},
+		{name: "idle since the start of the working hours", now: today.Add(10 * time.Hour), startedAt: today.Add(9*time.Hour + 50*time.Minute), workStart: &workStart, idleSince: timePtr(today.Add(workStart))},
+		{name: "working hours started recently", now: today.Add(8*time.Hour + 10*time.Minute), startedAt: today, workStart: &workStart},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestDeviceStatusService("", time.Second)
+			s.startedAt = test.startedAt
+			s.workStart = test.workStart
+			s.devices[7] = model.Device{Model: gorm.Model{ID: 7}, Name: "Analyzer"}
+
+			// the device never connected
+			s.checkIdle(test.now)
+			s.alerts.Wait()
+
+			status, ok := s.statuses[7]
+			if test.idleSince == nil {
+				if ok {
+					t.Errorf("status = %+v, want the device not idle yet", status)
+				}
+				return
+			}
+			if !ok || status.IdleSince == nil || !status.IdleSince.Equal(*test.idleSince) || !s.dirty[7] {
+				t.Fatalf("status = %+v, want the device idle since %s", status, test.idleSince)
+			}
+			if status.Connected || status.LastMessageAt != nil {
+				t.Errorf("status = %+v, want the device never connected", status)
+			}
+		})
+	}
+}
+
+// timePtr returns a pointer to the time.
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestDeviceStatusStopWithoutStart(t *testing.T) {
+	s := newTestDeviceStatusService("", time.Second)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked without Start()")
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+const (
+	defaultEventRetention = 24 * time.Hour
+	eventPruneInterval    = time.Hour
+)
+
+// EventService writes the events of the device server to the events table, the outbox streamed live by the API server.
+// The events are best effort, a failed event is logged and never fails the processing of the device messages.
+// A nil EventService publishes nothing.
+type EventService struct {
+	log       *log.Logger
+	store     *store.Store
+	retention time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewEventService creates a new EventService
+func NewEventService(logger *log.Logger, store *store.Store, settings config.EventSettings) *EventService {
+	s := &EventService{
+		log:       logger,
+		store:     store,
+		retention: time.Duration(settings.RetentionHours) * time.Hour,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if s.retention <= 0 {
+		s.retention = defaultEventRetention
+	}
+
+	return s
+}
+
+// Start starts deleting the expired events in the background
+func (s *EventService) Start() {
+	if s == nil {
+		return
+	}
+
+	go s.run()
+}
+
+// Stop stops deleting the expired events
+func (s *EventService) Stop() {
+	if s == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+}
+
+// PublishConnection publishes the connection or the disconnection of the device
+func (s *EventService) PublishConnection(eventType string, deviceID uint, connection model.EventConnection) {
+	if s == nil {
+		return
+	}
+
+	s.publish(eventType, deviceID, connection)
+}
+
+// PublishRawData publishes the raw data received from the device
+func (s *EventService) PublishRawData(rawData *model.RawData) {
+	if s == nil {
+		return
+	}
+
+	s.publish(model.EventTypeRawData, rawData.DeviceID, model.NewRawDataApi(rawData))
+}
+
+// PublishLabDatas publishes the lab datas stored from a raw data, an event per lab data
+func (s *EventService) PublishLabDatas(labDatas []*model.LabData) {
+	if s == nil || len(labDatas) == 0 {
+		return
+	}
+
+	events := make([]*model.Event, 0, len(labDatas))
+	for _, labData := range labDatas {
+		event, err := newEvent(model.EventTypeLabData, labData.DeviceID, labData)
+		if err != nil {
+			s.log.Err(err, "failed to create a lab data event")
+			continue
+		}
+		events = append(events, event)
+	}
+
+	err := s.store.EventStore.CreateAll(events)
+	if err != nil {
+		s.log.Err(err, "failed to store the lab data events")
+	}
+}
+
+// PublishDeviceIdle publishes the idle alert of the device
+func (s *EventService) PublishDeviceIdle(alert *DeviceIdleAlertRequestBody) {
+	if s == nil {
+		return
+	}
+
+	s.publish(model.EventTypeDeviceIdle, alert.DeviceID, alert)
+}
+
+// publish stores the event with the payload
+func (s *EventService) publish(eventType string, deviceID uint, payload interface{}) {
+	event, err := newEvent(eventType, deviceID, payload)
+	if err != nil {
+		s.log.Err(err, "failed to create a "+eventType+" event")
+		return
+	}
+
+	err = s.store.EventStore.CreateAll([]*model.Event{event})
+	if err != nil {
+		s.log.Err(err, "failed to store a "+eventType+" event")
+	}
+}
+
+// newEvent creates the event with the JSON of the payload
+func newEvent(eventType string, deviceID uint, payload interface{}) (*model.Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the event payload: %v", err)
+	}
+
+	return &model.Event{
+		Type:     eventType,
+		DeviceID: deviceID,
+		Payload:  string(data),
+	}, nil
+}
+
+// run deletes the expired events on every tick until stopped
+func (s *EventService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(eventPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.store.EventStore.DeleteBefore(time.Now().Add(-s.retention))
+		if err != nil {
+			s.log.Err(err, "failed to delete the expired events")
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// defaultHTTPTimeout is the timeout of the requests of the HTTP clients when none is set.
+const defaultHTTPTimeout = 30 * time.Second
+
+// newHTTPClient creates a new HTTP client whose requests fail after the timeout, or defaultHTTPTimeout if it is not positive.
+// Every request of the services to the HIS and the webhooks goes through a client of it, a hung server never blocks them.
+func newHTTPClient(timeout time.Duration) *resty.Client {
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	return resty.New().SetTimeout(timeout)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+)
+
+// HTTPQueryBackend queries an HTTP/JSON API with a configurable request and response mapping.
+type HTTPQueryBackend struct {
+	client         *resty.Client
+	url            string
+	method         string
+	headers        map[string]string
+	body           string
+	indicatorsPath string
+	fields         map[string]string
+}
+
+// NewHTTPQueryBackend creates a new HTTPQueryBackend.
+// The request is a GET without a body and a POST with it unless the method is set,
+// the paths of the indicator fields default to the field names of the Doctra API.
+// The queries time out with their context, the timeout of the client only bounds the queries without a deadline.
+func NewHTTPQueryBackend(settings config.QueryBackendSettings) (*HTTPQueryBackend, error) {
+	if settings.URL == "" {
+		return nil, fmt.Errorf("the URL is not set")
+	}
+
+	b := &HTTPQueryBackend{
+		client:         newHTTPClient(defaultHTTPTimeout),
+		url:            settings.URL,
+		method:         strings.ToUpper(settings.Method),
+		headers:        settings.Headers,
+		body:           settings.Body,
+		indicatorsPath: settings.IndicatorsPath,
+		fields: map[string]string{
+			"indicator":          settings.IndicatorField,
+			"material":           settings.MaterialField,
+			"dilution":           settings.DilutionField,
+			"barcode":            settings.BarcodeField,
+			"priority":           settings.PriorityField,
+			"specimen_type":      settings.SpecimenTypeField,
+			"collection_time":    settings.CollectionTimeField,
+			"patient_id":         settings.PatientIDField,
+			"patient_name":       settings.PatientNameField,
+			"patient_sex":        settings.PatientSexField,
+			"patient_birth_date": settings.PatientBirthDateField,
+		},
+	}
+
+	if b.method == "" {
+		b.method = http.MethodGet
+		if b.body != "" {
+			b.method = http.MethodPost
+		}
+	}
+	for name, path := range b.fields {
+		if path == "" {
+			b.fields[name] = name
+		}
+	}
+
+	return b, nil
+}
+
+// Query sends the request with the barcode and the serial and maps the response to the indicators.
+func (b *HTTPQueryBackend) Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error) {
+	urlReplacer := strings.NewReplacer("{barcode}", url.QueryEscape(barcode), "{serial}", url.QueryEscape(serial))
+	bodyReplacer := strings.NewReplacer("{barcode}", jsonEscape(barcode), "{serial}", jsonEscape(serial))
+
+	req := b.client.R().
+		SetContext(ctx).
+		SetHeaders(b.headers)
+	if b.body != "" {
+		req.SetHeader("Content-Type", "application/json").
+			SetBody(bodyReplacer.Replace(b.body))
+	}
+
+	resp, err := req.Execute(b.method, urlReplacer.Replace(b.url))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, &QueryHTTPError{StatusCode: resp.StatusCode(), Status: resp.Status()}
+	}
+
+	return b.mapResponse(resp.Body())
+}
+
+// mapResponse maps the JSON response to the indicators.
+func (b *HTTPQueryBackend) mapResponse(body []byte) ([]DeviceQueryIndicator, error) {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the response: %v", err)
+	}
+
+	value, ok := jsonPathValue(data, b.indicatorsPath)
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the value at %s is not an array", b.indicatorsPath)
+	}
+
+	indicators := []DeviceQueryIndicator{}
+	for _, item := range items {
+		value := func(name string) string {
+			return jsonPathString(item, b.fields[name])
+		}
+
+		if value("indicator") == "" {
+			continue
+		}
+
+		material, _ := strconv.Atoi(value("material"))
+
+		indicators = append(indicators, DeviceQueryIndicator{
+			Indicator:        value("indicator"),
+			Material:         material,
+			Dilution:         value("dilution"),
+			Barcode:          value("barcode"),
+			Priority:         value("priority"),
+			SpecimenType:     value("specimen_type"),
+			CollectionTime:   value("collection_time"),
+			PatientID:        value("patient_id"),
+			PatientName:      value("patient_name"),
+			PatientSex:       value("patient_sex"),
+			PatientBirthDate: value("patient_birth_date"),
+		})
+	}
+
+	return indicators, nil
+}
+
+// jsonPathValue returns the value at the dot separated path of the decoded JSON, the array elements are selected by their index.
+func jsonPathValue(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch value := data.(type) {
+		case map[string]interface{}:
+			var ok bool
+			data, ok = value[key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			data = value[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return data, true
+}
+
+// jsonPathString returns the value at the path of the decoded JSON as a string, an empty string if there is none.
+func jsonPathString(data interface{}, path string) string {
+	value, ok := jsonPathValue(data, path)
+	if !ok || value == nil {
+		return ""
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+
+	return fmt.Sprint(value)
+}
+
+// jsonEscape escapes the string to be placed between the quotes of a JSON string.
+func jsonEscape(s string) string {
+	escaped, _ := json.Marshal(s)
+
+	return string(escaped[1 : len(escaped)-1])
+}
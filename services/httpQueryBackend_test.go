@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+)
+
+// testHTTPRequest is a request received by the test server.
+type testHTTPRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+// newTestHTTPServer answers every request with the status and the body and records the last request.
+func newTestHTTPServer(t *testing.T, status int, body string) (*httptest.Server, *testHTTPRequest) {
+	t.Helper()
+
+	received := &testHTTPRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body)
+		*received = testHTTPRequest{method: r.Method, uri: r.RequestURI, header: r.Header, body: string(reqBody)}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func TestNewHTTPQueryBackend(t *testing.T) {
+	_, err := NewHTTPQueryBackend(config.QueryBackendSettings{})
+	if err == nil {
+		t.Error("NewHTTPQueryBackend() without a URL error = nil, want an error")
+	}
+
+	tests := []struct {
+		name     string
+		settings config.QueryBackendSettings
+		method   string
+	}{
+		{name: "GET without a body", settings: config.QueryBackendSettings{URL: "http://his"}, method: http.MethodGet},
+		{name: "POST with a body", settings: config.QueryBackendSettings{URL: "http://his", Body: "{}"}, method: http.MethodPost},
+		{name: "configured method", settings: config.QueryBackendSettings{URL: "http://his", Method: "put", Body: "{}"}, method: http.MethodPut},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := NewHTTPQueryBackend(test.settings)
+			if err != nil {
+				t.Fatalf("NewHTTPQueryBackend() error = %v", err)
+			}
+			if b.method != test.method {
+				t.Errorf("method = %s, want %s", b.method, test.method)
+			}
+			if b.fields["indicator"] != "indicator" || b.fields["patient_birth_date"] != "patient_birth_date" {
+				t.Errorf("fields = %v, want the Doctra field names by default", b.fields)
+			}
+		})
+	}
+}
+
+func TestHTTPQueryBackendRequest(t *testing.T) {
+	server, received := newTestHTTPServer(t, http.StatusOK, `[]`)
+
+	b, err := NewHTTPQueryBackend(config.QueryBackendSettings{
+		URL:     server.URL + "/orders?barcode={barcode}&sn={serial}",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Body:    `{"barcode":"{barcode}","serial":"{serial}"}`,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPQueryBackend() error = %v", err)
+	}
+
+	_, err = b.Query(context.Background(), `BC"1&2`, "SN 1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if received.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", received.method)
+	}
+	if want := "/orders?barcode=BC%221%262&sn=SN+1"; received.uri != want {
+		t.Errorf("URI = %s, want %s", received.uri, want)
+	}
+	var body map[string]string
+	err = json.Unmarshal([]byte(received.body), &body)
+	if err != nil || body["barcode"] != `BC"1&2` || body["serial"] != "SN 1" {
+		t.Errorf("body = %s, want the escaped barcode and serial", received.body)
+	}
+	if received.header.Get("Authorization") != "Bearer token" || received.header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v, want the configured headers and the JSON content type", received.header)
+	}
+}
+
+func TestHTTPQueryBackendMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings config.QueryBackendSettings
+		response string
+		want     []DeviceQueryIndicator
+	}{
+		{
+			name:     "Doctra field names",
+			settings: config.QueryBackendSettings{IndicatorsPath: "indicators"},
+			response: `{"indicators":[{"indicator":"GLU","material":2,"priority":"S"},{"indicator":"ALT"}]}`,
+			want: []DeviceQueryIndicator{
+				{Indicator: "GLU", Material: 2, Priority: "S"},
+				{Indicator: "ALT"},
+			},
+		},
+		{
+			name: "nested paths",
+			settings: config.QueryBackendSettings{
+				IndicatorsPath:        "data.orders",
+				IndicatorField:        "test.code",
+				MaterialField:         "sample.type",
+				PatientIDField:        "patient.ids.0",
+				PatientBirthDateField: "patient.dob",
+			},
+			response: `{"data":{"orders":[{"test":{"code":"K"},"sample":{"type":"3"},"patient":{"ids":["P1","P2"],"dob":"19800102"}}]}}`,
+			want: []DeviceQueryIndicator{
+				{Indicator: "K", Material: 3, PatientID: "P1", PatientBirthDate: "19800102"},
+			},
+		},
+		{
+			name:     "top level array",
+			response: `[{"indicator":"NA","dilution":10}]`,
+			want:     []DeviceQueryIndicator{{Indicator: "NA", Dilution: "10"}},
+		},
+		{
+			name:     "items without an indicator skipped",
+			settings: config.QueryBackendSettings{IndicatorsPath: "indicators"},
+			response: `{"indicators":[{"material":1},{"indicator":"CL"}]}`,
+			want:     []DeviceQueryIndicator{{Indicator: "CL"}},
+		},
+		{
+			name:     "missing path",
+			settings: config.QueryBackendSettings{IndicatorsPath: "indicators"},
+			response: `{"orders":[]}`,
+		},
+		{
+			name:     "null indicators",
+			settings: config.QueryBackendSettings{IndicatorsPath: "indicators"},
+			response: `{"indicators":null}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := newTestHTTPServer(t, http.StatusOK, test.response)
+
+			test.settings.URL = server.URL
+			b, err := NewHTTPQueryBackend(test.settings)
+			if err != nil {
+				t.Fatalf("NewHTTPQueryBackend() error = %v", err)
+			}
+
+			indicators, err := b.Query(context.Background(), "BC1", "SN1")
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(indicators) != len(test.want) {
+				t.Fatalf("Query() = %+v, want %+v", indicators, test.want)
+			}
+			for i := range test.want {
+				if indicators[i] != test.want[i] {
+					t.Errorf("indicator %d = %+v, want %+v", i, indicators[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPQueryBackendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		httpErr  bool
+	}{
+		{name: "HTTP error", status: http.StatusServiceUnavailable, httpErr: true},
+		{name: "invalid JSON", status: http.StatusOK, response: `{"indicators":`},
+		{name: "indicators not an array", status: http.StatusOK, response: `{"indicators":{"indicator":"GLU"}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := newTestHTTPServer(t, test.status, test.response)
+
+			b, err := NewHTTPQueryBackend(config.QueryBackendSettings{URL: server.URL, IndicatorsPath: "indicators"})
+			if err != nil {
+				t.Fatalf("NewHTTPQueryBackend() error = %v", err)
+			}
+
+			_, err = b.Query(context.Background(), "BC1", "SN1")
+			if err == nil {
+				t.Fatal("Query() error = nil, want an error")
+			}
+
+			var httpErr *QueryHTTPError
+			if errors.As(err, &httpErr) != test.httpErr {
+				t.Errorf("Query() error = %v, want a QueryHTTPError %v", err, test.httpErr)
+			}
+		})
+	}
+}
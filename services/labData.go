@@ -0,0 +1,45 @@
+package services
+
+import (
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// LabDataService prepares the lab datas unmarshalled by the driver of a device model before they are stored.
+type LabDataService struct {
+	TestCodes       *TestCodeService
+	Units           *UnitConversionService
+	ReferenceRanges *ReferenceRangeService
+	Verification    *VerificationService
+}
+
+// NewLabDataService creates a new LabDataService for the device model
+func NewLabDataService(store *store.Store, deviceModelID uint) *LabDataService {
+	return &LabDataService{
+		TestCodes:       NewTestCodeService(store, deviceModelID),
+		Units:           NewUnitConversionService(store),
+		ReferenceRanges: NewReferenceRangeService(store),
+		Verification:    NewVerificationService(store),
+	}
+}
+
+// Prepare maps the test codes of the lab datas to the HIS test codes, normalizes their units, completes their reference ranges and abnormal flags
+// and verifies them with the verification rules
+func (s *LabDataService) Prepare(labDatas []*model.LabData) error {
+	err := s.TestCodes.MapLabDatas(labDatas)
+	if err != nil {
+		return err
+	}
+
+	err = s.Units.NormalizeLabDatas(labDatas)
+	if err != nil {
+		return err
+	}
+
+	err = s.ReferenceRanges.ApplyLabDatas(labDatas)
+	if err != nil {
+		return err
+	}
+
+	return s.Verification.VerifyLabDatas(labDatas)
+}
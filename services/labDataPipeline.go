@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+// LabDataPipeline runs the steps following the storing of a raw data and its lab datas:
+// the reconciliation with the orders, the critical alerts, the delivery to the HIS and the events.
+// The received and the reprocessed raw datas go through the same steps, the unset services are skipped.
+type LabDataPipeline struct {
+	log            *log.Logger
+	resultDelivery *ResultDeliveryService
+	criticalAlerts *CriticalAlertService
+	events         *EventService
+}
+
+// NewLabDataPipeline creates a new LabDataPipeline, any of the services can be nil
+func NewLabDataPipeline(logger *log.Logger, resultDelivery *ResultDeliveryService, criticalAlerts *CriticalAlertService, events *EventService) *LabDataPipeline {
+	return &LabDataPipeline{
+		log:            logger,
+		resultDelivery: resultDelivery,
+		criticalAlerts: criticalAlerts,
+		events:         events,
+	}
+}
+
+// Stored runs the steps for the stored raw data, the lab datas of a processed one are reconciled with the orders and delivered,
+// the alerts of the critical ones stored with them are sent. The steps are best effort, a failed one is logged and the others still run.
+func (p *LabDataPipeline) Stored(rawData *model.RawData, labDatas []*model.LabData, orders *OrderService) {
+	if rawData.Processed && len(labDatas) > 0 {
+		if p.resultDelivery != nil {
+			p.resultDelivery.Notify()
+		}
+
+		err := orders.Reconcile(labDatas)
+		if err != nil {
+			p.log.Err(err, fmt.Sprintf("failed to reconcile the orders with the raw data %d", rawData.ID))
+		}
+
+		if p.criticalAlerts != nil && len(model.NewCriticalAlerts(labDatas)) > 0 {
+			p.criticalAlerts.Notify()
+		}
+	}
+
+	p.events.PublishRawData(rawData)
+	if rawData.Processed {
+		p.events.PublishLabDatas(labDatas)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// OrderService records the orders of a device and reconciles them with the results of the device.
+// The orders are kept per barcode and device, the tests of a new order of the same barcode are added to the open one.
+type OrderService struct {
+	log      *log.Logger
+	store    *store.Store
+	deviceID uint
+}
+
+// NewOrderService creates a new OrderService for the device, deviceID is 0 for the orders whose device is not known
+func NewOrderService(logger *log.Logger, store *store.Store, deviceID uint) *OrderService {
+	return &OrderService{
+		log:      logger,
+		store:    store,
+		deviceID: deviceID,
+	}
+}
+
+// Record records the tests (HIS test codes) ordered for the barcode and the sample they are run on, a service without a store records nothing
+func (s *OrderService) Record(barcode, source string, sample model.OrderSample, tests []*model.OrderTest) (*model.Order, error) {
+	if s == nil || s.store == nil || len(tests) == 0 {
+		return nil, nil
+	}
+
+	return recordOrder(s.store.OrderStore, s.deviceID, barcode, source, sample, tests)
+}
+
+// RecordOrders records the orders in a single transaction, either all of them are recorded or none
+// The tests of an order are added to the open order of its barcode and device if there is one, like Record does
+func RecordOrders(orderStore *store.OrderStore, source string, orders []*model.Order) ([]*model.Order, error) {
+	recorded := make([]*model.Order, 0, len(orders))
+	err := orderStore.Transaction(func(tx *store.OrderStore) error {
+		for _, order := range orders {
+			recordedOrder, err := recordOrder(tx, order.DeviceID, order.Barcode, source, order.Sample, order.Tests)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, recordedOrder)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recorded, nil
+}
+
+// recordOrder adds the tests to the open order of the barcode and the device or creates a new order of them
+func recordOrder(orderStore *store.OrderStore, deviceID uint, barcode, source string, sample model.OrderSample, tests []*model.OrderTest) (*model.Order, error) {
+	orders, err := orderStore.GetOpenByBarcode(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if order.DeviceID == deviceID {
+			order.SetSample(sample)
+			order.AddTests(tests)
+			return order, orderStore.Update(order)
+		}
+	}
+
+	order := &model.Order{
+		Barcode:  barcode,
+		DeviceID: deviceID,
+		Source:   source,
+	}
+	order.SetSample(sample)
+	order.AddTests(tests)
+
+	return order, orderStore.Create(order)
+}
+
+// RecordQuery records the tests answered to the host query of the device
+// A failure is only logged, the answer to the device does not depend on it
+func (s *OrderService) RecordQuery(barcode string, indicators []DeviceQueryIndicator) {
+	_, err := s.Record(barcode, model.OrderSourceQuery, OrderSampleFromIndicators(indicators), OrderTestsFromIndicators(indicators))
+	if err != nil {
+		s.log.Err(err, "failed to record the order of barcode "+barcode)
+	}
+}
+
+// PendingOrders gets the open orders of the barcode on the device or on any device with their pending tests only
+func (s *OrderService) PendingOrders(barcode string) ([]*model.Order, error) {
+	if s == nil || s.store == nil {
+		return nil, nil
+	}
+
+	orders, err := s.store.OrderStore.GetOpenByBarcode(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingOrders := []*model.Order{}
+	for _, order := range orders {
+		if order.DeviceID != 0 && order.DeviceID != s.deviceID {
+			continue
+		}
+
+		tests := []*model.OrderTest{}
+		for _, test := range order.Tests {
+			if test.Status == model.OrderTestStatusPending {
+				tests = append(tests, test)
+			}
+		}
+		if len(tests) == 0 {
+			continue
+		}
+
+		order.Tests = tests
+		pendingOrders = append(pendingOrders, order)
+	}
+
+	return pendingOrders, nil
+}
+
+// OrderTestsFromIndicators converts the indicators in the format of the HIS query response to the order tests
+func OrderTestsFromIndicators(indicators []DeviceQueryIndicator) []*model.OrderTest {
+	tests := make([]*model.OrderTest, 0, len(indicators))
+	for _, indicator := range indicators {
+		tests = append(tests, &model.OrderTest{
+			Param:        indicator.Indicator,
+			Material:     indicator.Material,
+			Dilution:     indicator.Dilution,
+			Priority:     indicator.Priority,
+			SpecimenType: indicator.SpecimenType,
+		})
+	}
+
+	return tests
+}
+
+// OrderSampleFromIndicators gets the sample attributes from the indicators in the format of the HIS query response
+// Every attribute is taken from the first indicator which has it
+func OrderSampleFromIndicators(indicators []DeviceQueryIndicator) model.OrderSample {
+	sample := model.OrderSample{}
+	for _, indicator := range indicators {
+		if sample.CollectedAt == nil {
+			sample.CollectedAt = model.ParseOrderTime(indicator.CollectionTime)
+		}
+		if sample.PatientID == "" {
+			sample.PatientID = indicator.PatientID
+		}
+		if sample.PatientName == "" {
+			sample.PatientName = indicator.PatientName
+		}
+		if sample.PatientSex == "" {
+			sample.PatientSex = model.NormalizePatientSex(indicator.PatientSex)
+		}
+		if sample.PatientBirthDate == nil {
+			sample.PatientBirthDate = model.ParseOrderTime(indicator.PatientBirthDate)
+		}
+	}
+
+	return sample
+}
+
+// Reconcile completes the tests of the open orders with the stored lab datas of the device
+func (s *OrderService) Reconcile(labDatas []*model.LabData) error {
+	if s == nil || s.store == nil || len(labDatas) == 0 {
+		return nil
+	}
+
+	barcodes := []string{}
+	byBarcode := map[string][]*model.LabData{}
+	for _, labData := range labDatas {
+		if _, ok := byBarcode[labData.Barcode]; !ok {
+			barcodes = append(barcodes, labData.Barcode)
+		}
+		byBarcode[labData.Barcode] = append(byBarcode[labData.Barcode], labData)
+	}
+
+	for _, barcode := range barcodes {
+		orders, err := s.store.OrderStore.GetOpenByBarcode(barcode)
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			order.Reconcile(byBarcode[barcode])
+			err = s.store.OrderStore.Update(order)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
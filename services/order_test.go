@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+	"gorm.io/gorm"
+)
+
+// orderTests creates the order tests of the params.
+func orderTests(params ...string) []*model.OrderTest {
+	tests := []*model.OrderTest{}
+	for _, param := range params {
+		tests = append(tests, &model.OrderTest{Param: param})
+	}
+
+	return tests
+}
+
+// orderParams returns the params of the tests of the order.
+func orderParams(order *model.Order) []string {
+	params := []string{}
+	for _, test := range order.Tests {
+		params = append(params, test.Param)
+	}
+
+	return params
+}
+
+// getOrder gets the stored order.
+func getOrder(t *testing.T, st *store.Store, id uint) *model.Order {
+	t.Helper()
+
+	order, err := st.OrderStore.GetByID(id)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	return order
+}
+
+func TestOrderServiceRecord(t *testing.T) {
+	st := storetest.New(t)
+	device1 := storetest.CreateDevice(t, st, "Analyzer 1", "SN1")
+	device2 := storetest.CreateDevice(t, st, "Analyzer 2", "SN2")
+	orders1 := NewOrderService(&log.Logger{Disabled: true}, st, device1.ID)
+
+	order, err := orders1.Record("BC1", model.OrderSourceHIS, model.OrderSample{PatientID: "P1"}, orderTests("GLU", "ALT"))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// the tests of the same barcode and device are added to the open order
+	added, err := orders1.Record("BC1", model.OrderSourceQuery, model.OrderSample{PatientID: "P2", PatientSex: "female"}, orderTests("ALT", "K"))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if added.ID != order.ID {
+		t.Fatalf("Record() created order %d, want the tests added to order %d", added.ID, order.ID)
+	}
+
+	stored := getOrder(t, st, order.ID)
+	if len(stored.Tests) != 3 || stored.Source != model.OrderSourceHIS || stored.DeviceID != device1.ID {
+		t.Errorf("order = %s of device %d with %v, want the HIS order of device %d with GLU, ALT and K", stored.Source, stored.DeviceID, orderParams(stored), device1.ID)
+	}
+	if stored.Sample.PatientID != "P1" || stored.Sample.PatientSex != model.PatientSexFemale {
+		t.Errorf("sample = %+v, want the patient ID kept and the missing sex set", stored.Sample)
+	}
+
+	// another device has its own order of the barcode
+	other, err := NewOrderService(&log.Logger{Disabled: true}, st, device2.ID).Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests("GLU"))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if other.ID == order.ID || other.DeviceID != device2.ID {
+		t.Errorf("order of device 2 = %d of device %d, want a new order of device %d", other.ID, other.DeviceID, device2.ID)
+	}
+
+	// nothing is recorded without tests or a store
+	none, err := orders1.Record("BC2", model.OrderSourceHIS, model.OrderSample{}, nil)
+	if err != nil || none != nil {
+		t.Errorf("Record() without tests = %v, %v, want nothing", none, err)
+	}
+	none, err = NewOrderService(&log.Logger{Disabled: true}, nil, device1.ID).Record("BC2", model.OrderSourceHIS, model.OrderSample{}, orderTests("GLU"))
+	if err != nil || none != nil {
+		t.Errorf("Record() without a store = %v, %v, want nothing", none, err)
+	}
+}
+
+func TestOrderServicePendingOrders(t *testing.T) {
+	st := storetest.New(t)
+	device1 := storetest.CreateDevice(t, st, "Analyzer 1", "SN1")
+	device2 := storetest.CreateDevice(t, st, "Analyzer 2", "SN2")
+	orders1 := NewOrderService(&log.Logger{Disabled: true}, st, device1.ID)
+
+	_, err := orders1.Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests("GLU", "ALT"))
+	if err == nil {
+		_, err = NewOrderService(&log.Logger{Disabled: true}, st, 0).Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests("CRP"))
+	}
+	if err == nil {
+		_, err = NewOrderService(&log.Logger{Disabled: true}, st, device2.ID).Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests("K"))
+	}
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// the orders of the device and of any device, not of the other devices
+	pending, err := orders1.PendingOrders("BC1")
+	if err != nil {
+		t.Fatalf("PendingOrders() error = %v", err)
+	}
+	if len(pending) != 2 || len(pending[0].Tests) != 2 || len(pending[1].Tests) != 1 || pending[1].Tests[0].Param != "CRP" {
+		t.Fatalf("PendingOrders() = %d orders, want the order of the device with GLU and ALT and the order of any device with CRP", len(pending))
+	}
+
+	// the re-query after the first results gets the tests still pending
+	err = orders1.Reconcile([]*model.LabData{{DeviceID: device1.ID, Barcode: "BC1", Param: "GLU"}, {DeviceID: device1.ID, Barcode: "BC1", Param: "CRP"}})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	pending, err = orders1.PendingOrders("BC1")
+	if err != nil {
+		t.Fatalf("PendingOrders() error = %v", err)
+	}
+	if len(pending) != 1 || len(pending[0].Tests) != 1 || pending[0].Tests[0].Param != "ALT" {
+		t.Errorf("PendingOrders() after the first results = %d orders, want the order of the device with ALT", len(pending))
+	}
+
+	none, err := orders1.PendingOrders("BC2")
+	if err != nil || len(none) != 0 {
+		t.Errorf("PendingOrders() of an unknown barcode = %v, %v, want none", none, err)
+	}
+}
+
+func TestOrderServiceReconcile(t *testing.T) {
+	st := storetest.New(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+	orders := NewOrderService(&log.Logger{Disabled: true}, st, device.ID)
+
+	order1, err := orders.Record("BC1", model.OrderSourceHIS, model.OrderSample{}, orderTests("GLU", "ALT", "K"))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	order2, err := orders.Record("BC2", model.OrderSourceHIS, model.OrderSample{}, orderTests("GLU"))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// the device sends the results of BC1 in two messages
+	err = orders.Reconcile([]*model.LabData{
+		{DeviceID: device.ID, Barcode: "BC1", Param: "GLU"},
+		{DeviceID: device.ID, Barcode: "BC2", Param: "GLU"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	stored := getOrder(t, st, order1.ID)
+	if stored.Status != model.OrderStatusPending || stored.Test("ALT").Status != model.OrderTestStatusPending || stored.Test("GLU").Status != model.OrderTestStatusCompleted {
+		t.Fatalf("order of BC1 after the first message = %s, want pending with GLU completed and ALT and K pending", stored.Status)
+	}
+	if stored := getOrder(t, st, order2.ID); stored.Status != model.OrderStatusCompleted {
+		t.Errorf("order of BC2 = %s, want completed", stored.Status)
+	}
+
+	err = orders.Reconcile([]*model.LabData{{DeviceID: device.ID, Barcode: "BC1", Param: "ALT"}, {DeviceID: device.ID, Barcode: "BC1", Param: "K"}})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if stored := getOrder(t, st, order1.ID); stored.Status != model.OrderStatusCompleted {
+		t.Errorf("order of BC1 after the second message = %s, want completed", stored.Status)
+	}
+}
+
+func TestRecordOrders(t *testing.T) {
+	db := storetest.Open(t)
+	st := storetest.NewWithDB(t, db)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+
+	// the orders of the failing barcode are not created
+	err := db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		if order, ok := tx.Statement.Dest.(*model.Order); ok && order.Barcode == "FAIL" {
+			tx.AddError(errors.New("failed to create the order"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	recorded, err := RecordOrders(st.OrderStore, model.OrderSourceHIS, []*model.Order{
+		{Barcode: "BC1", DeviceID: device.ID, Tests: orderTests("GLU")},
+		{Barcode: "BC2", Tests: orderTests("ALT", "K")},
+	})
+	if err != nil {
+		t.Fatalf("RecordOrders() error = %v", err)
+	}
+	if len(recorded) != 2 || recorded[0].ID == 0 || recorded[1].ID == 0 || recorded[0].DeviceID != device.ID {
+		t.Fatalf("RecordOrders() = %+v, want the two orders created", recorded)
+	}
+
+	// the worklist is recorded all or none
+	_, err = RecordOrders(st.OrderStore, model.OrderSourceHIS, []*model.Order{
+		{Barcode: "BC1", DeviceID: device.ID, Tests: orderTests("CRP")},
+		{Barcode: "BC3", Tests: orderTests("NA")},
+		{Barcode: "FAIL", Tests: orderTests("CL")},
+	})
+	if err == nil {
+		t.Fatal("RecordOrders() error = nil, want the error of the failing order")
+	}
+
+	orders, err := st.OrderStore.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(orders) != 2 || len(orders[0].Tests) != 1 {
+		t.Errorf("orders after the failed worklist = %d, the first with %v, want the first worklist only", len(orders), orderParams(orders[0]))
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+)
+
+// DefaultQueryBackend is the name of the query backend of the devices without one.
+const DefaultQueryBackend = "default"
+
+// Types of the query backends.
+const (
+	QueryBackendTypeDoctra = "doctra"
+	QueryBackendTypeHTTP   = "http"
+	QueryBackendTypeSQL    = "sql"
+	QueryBackendTypeCSV    = "csv"
+)
+
+// QueryBackend looks up the tests ordered for a barcode on the device with the serial.
+type QueryBackend interface {
+	Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error)
+}
+
+// NewQueryBackend creates a new QueryBackend of the type of the settings.
+func NewQueryBackend(settings config.QueryBackendSettings) (QueryBackend, error) {
+	switch settings.Type {
+	case QueryBackendTypeDoctra, "":
+		if settings.URL == "" {
+			return nil, fmt.Errorf("the URL is not set")
+		}
+
+		return NewDoctraQueryBackend(settings.URL), nil
+	case QueryBackendTypeHTTP:
+		return NewHTTPQueryBackend(settings)
+	case QueryBackendTypeSQL:
+		return NewSQLQueryBackend(settings)
+	case QueryBackendTypeCSV:
+		return NewCSVQueryBackend(settings)
+	}
+
+	return nil, fmt.Errorf("unknown query backend type %s", settings.Type)
+}
+
+// DoctraQueryBackend queries the Doctra JSON API.
+type DoctraQueryBackend struct {
+	client    *resty.Client
+	queryHost string
+}
+
+// NewDoctraQueryBackend creates a new DoctraQueryBackend for the query host.
+// The queries time out with their context, the timeout of the client only bounds the queries without a deadline.
+func NewDoctraQueryBackend(queryHost string) *DoctraQueryBackend {
+	return &DoctraQueryBackend{
+		client:    newHTTPClient(defaultHTTPTimeout),
+		queryHost: queryHost,
+	}
+}
+
+// Query posts the barcode and the serial to the query host.
+func (b *DoctraQueryBackend) Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error) {
+	reqBody := &DeviceQueryRequestBody{
+		Barcode:    barcode,
+		HardwareSN: serial,
+	}
+
+	respBody := &DeviceQueryResponseBody{}
+
+	resp, err := b.client.R().
+		SetContext(ctx).
+		SetBody(reqBody).
+		SetResult(respBody).
+		Post(b.queryHost)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, &QueryHTTPError{StatusCode: resp.StatusCode(), Status: resp.Status()}
+	}
+
+	return respBody.Indicators, nil
+}
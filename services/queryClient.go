@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+)
+
+const (
+	defaultQueryTimeout            = 5 * time.Second
+	defaultQueryBreakerFailures    = 5
+	defaultQueryBreakerCooldown    = 30 * time.Second
+	defaultQueryMetricsLogInterval = 5 * time.Minute
+)
+
+// ErrQueryCircuitOpen is returned while the circuit breaker rejects the queries after the query backend failed repeatedly.
+var ErrQueryCircuitOpen = errors.New("query circuit breaker is open")
+
+// QueryHTTPError is returned when an HTTP query backend answers the query with an HTTP error status.
+type QueryHTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error returns the message of the error.
+func (e *QueryHTTPError) Error() string {
+	return "HTTP error: " + e.Status
+}
+
+// QueryMetrics represents the counters of the host queries sent to the query backend.
+// The latencies are measured for the queries sent to the backend, including the retries.
+type QueryMetrics struct {
+	Queries      uint64
+	Failures     uint64
+	CacheHits    uint64
+	Rejected     uint64
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// AverageLatency returns the average latency of the queries sent to the query backend.
+func (m QueryMetrics) AverageLatency() time.Duration {
+	if m.Queries == 0 {
+		return 0
+	}
+
+	return m.TotalLatency / time.Duration(m.Queries)
+}
+
+// String returns the metrics as a log line.
+func (m QueryMetrics) String() string {
+	return fmt.Sprintf("queries: %d, failures: %d, cache hits: %d, rejected: %d, average latency: %s, max latency: %s",
+		m.Queries, m.Failures, m.CacheHits, m.Rejected, m.AverageLatency(), m.MaxLatency)
+}
+
+// queryCacheEntry is a cached answer of the query backend.
+type queryCacheEntry struct {
+	indicators []DeviceQueryIndicator
+	expiresAt  time.Time
+}
+
+// QueryClient sends the host queries of the devices to a query backend.
+// The queries time out and are retried on errors other than HTTP client errors,
+// a circuit breaker rejects the queries while the backend keeps failing and the answers are cached shortly by barcode and serial.
+type QueryClient struct {
+	log                *log.Logger
+	name               string
+	backend            QueryBackend
+	timeout            time.Duration
+	retries            int
+	retryWait          time.Duration
+	breakerFailures    int
+	breakerCooldown    time.Duration
+	cacheTTL           time.Duration
+	metricsLogInterval time.Duration
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+	halfOpen            bool
+	cache               map[string]queryCacheEntry
+	metrics             QueryMetrics
+	started             bool
+	stop                chan struct{}
+	done                chan struct{}
+}
+
+// NewQueryClient creates a new QueryClient for the query backend with the name
+func NewQueryClient(logger *log.Logger, name string, backend QueryBackend, settings config.QuerySettings) *QueryClient {
+	c := &QueryClient{
+		log:                logger,
+		name:               name,
+		backend:            backend,
+		timeout:            time.Duration(settings.TimeoutSeconds) * time.Second,
+		retries:            max(settings.Retries, 0),
+		retryWait:          time.Duration(settings.RetryWaitMilliseconds) * time.Millisecond,
+		breakerFailures:    settings.BreakerFailures,
+		breakerCooldown:    time.Duration(settings.BreakerCooldownSeconds) * time.Second,
+		cacheTTL:           time.Duration(settings.CacheSeconds) * time.Second,
+		metricsLogInterval: time.Duration(settings.MetricsLogIntervalSeconds) * time.Second,
+		cache:              map[string]queryCacheEntry{},
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+
+	if c.timeout <= 0 {
+		c.timeout = defaultQueryTimeout
+	}
+	if c.breakerFailures <= 0 {
+		c.breakerFailures = defaultQueryBreakerFailures
+	}
+	if c.breakerCooldown <= 0 {
+		c.breakerCooldown = defaultQueryBreakerCooldown
+	}
+	if c.metricsLogInterval <= 0 {
+		c.metricsLogInterval = defaultQueryMetricsLogInterval
+	}
+
+	return c
+}
+
+// Start starts logging the metrics in the background
+func (c *QueryClient) Start() {
+	if c.started {
+		return
+	}
+
+	c.started = true
+	go c.run()
+}
+
+// Stop stops logging the metrics and logs them for the last time, it does nothing if the logging was not started
+func (c *QueryClient) Stop() {
+	if !c.started {
+		return
+	}
+
+	c.started = false
+	close(c.stop)
+	<-c.done
+}
+
+// Metrics returns the current metrics of the queries
+func (c *QueryClient) Metrics() QueryMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.metrics
+}
+
+// Query queries the backend for the tests of the barcode on the device with the serial
+// A nil client answers nothing
+func (c *QueryClient) Query(barcode, serial string) ([]DeviceQueryIndicator, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	key := serial + "|" + barcode
+	if indicators, ok := c.cached(key); ok {
+		return indicators, nil
+	}
+
+	if !c.allow() {
+		return nil, ErrQueryCircuitOpen
+	}
+
+	start := time.Now()
+	indicators, err := c.query(barcode, serial)
+
+	c.record(key, indicators, err, time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the %s backend for barcode %s: %w", c.name, barcode, err)
+	}
+
+	return indicators, nil
+}
+
+// query sends the query to the backend, every attempt times out and the failed attempts are retried
+func (c *QueryClient) query(barcode, serial string) ([]DeviceQueryIndicator, error) {
+	var indicators []DeviceQueryIndicator
+	var err error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.retryWait)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		indicators, err = c.backend.Query(ctx, barcode, serial)
+		cancel()
+		if err == nil || isQueryClientError(err) {
+			break
+		}
+	}
+
+	return indicators, err
+}
+
+// isQueryClientError checks if the error is an HTTP client error, the backend answered and a retry would not change the answer
+func isQueryClientError(err error) bool {
+	var httpErr *QueryHTTPError
+
+	return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+}
+
+// cached returns the cached answer of the key if it is not expired
+func (c *QueryClient) cached(key string) ([]DeviceQueryIndicator, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.cache, key)
+		return nil, false
+	}
+
+	c.metrics.CacheHits++
+
+	return entry.indicators, true
+}
+
+// allow checks if the circuit breaker lets the query through.
+// After the cooldown one query is let through to probe the backend, the others are rejected until it is answered.
+func (c *QueryClient) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.consecutiveFailures < c.breakerFailures {
+		return true
+	}
+	if c.halfOpen || time.Now().Before(c.openUntil) {
+		c.metrics.Rejected++
+		return false
+	}
+
+	c.halfOpen = true
+
+	return true
+}
+
+// record records the outcome of the query in the metrics, the circuit breaker and the cache.
+// An HTTP client error is an answer of the backend, it does not count for the circuit breaker.
+func (c *QueryClient) record(key string, indicators []DeviceQueryIndicator, err error, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics.Queries++
+	c.metrics.TotalLatency += latency
+	c.metrics.MaxLatency = max(c.metrics.MaxLatency, latency)
+	c.halfOpen = false
+
+	if err != nil {
+		c.metrics.Failures++
+		if isQueryClientError(err) {
+			return
+		}
+
+		c.consecutiveFailures++
+		if c.consecutiveFailures >= c.breakerFailures {
+			c.openUntil = time.Now().Add(c.breakerCooldown)
+			c.log.Warn(fmt.Sprintf("circuit breaker of the %s query backend opened for %s after %d failures", c.name, c.breakerCooldown, c.consecutiveFailures))
+		}
+		return
+	}
+
+	if c.consecutiveFailures >= c.breakerFailures {
+		c.log.Info(fmt.Sprintf("circuit breaker of the %s query backend closed", c.name))
+	}
+	c.consecutiveFailures = 0
+
+	if c.cacheTTL > 0 {
+		c.cache[key] = queryCacheEntry{indicators: indicators, expiresAt: time.Now().Add(c.cacheTTL)}
+	}
+}
+
+// run logs the metrics on every tick until stopped, the expired cache entries are dropped on the way
+func (c *QueryClient) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.metricsLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			c.log.Info(fmt.Sprintf("%s query backend metrics: %s", c.name, c.Metrics()))
+			return
+		case <-ticker.C:
+			c.log.Info(fmt.Sprintf("%s query backend metrics: %s", c.name, c.Metrics()))
+			c.dropExpired()
+		}
+	}
+}
+
+// dropExpired drops the expired cache entries
+func (c *QueryClient) dropExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.cache {
+		if now.After(entry.expiresAt) {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// QueryClients holds the query clients by the name of their backend.
+type QueryClients map[string]*QueryClient
+
+// NewQueryClients creates the query clients of the configured backends.
+// The Doctra backend of the query host is the default backend unless a backend is configured with the default name.
+func NewQueryClients(logger *log.Logger, queryHost string, backends map[string]config.QueryBackendSettings, settings config.QuerySettings) (QueryClients, error) {
+	clients := QueryClients{}
+	for name, backendSettings := range backends {
+		backend, err := NewQueryBackend(backendSettings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the %s query backend: %v", name, err)
+		}
+
+		clients[name] = NewQueryClient(logger, name, backend, settings)
+	}
+
+	_, ok := clients[DefaultQueryBackend]
+	if !ok && queryHost != "" {
+		clients[DefaultQueryBackend] = NewQueryClient(logger, DefaultQueryBackend, NewDoctraQueryBackend(queryHost), settings)
+	}
+
+	return clients, nil
+}
+
+// Get returns the query client of the backend with the name, the default backend for an empty name.
+// It returns nil if the backend is not configured.
+func (c QueryClients) Get(name string) *QueryClient {
+	if name == "" {
+		name = DefaultQueryBackend
+	}
+
+	return c[name]
+}
+
+// Start starts the query clients
+func (c QueryClients) Start() {
+	for _, client := range c {
+		client.Start()
+	}
+}
+
+// Stop stops the query clients
+func (c QueryClients) Stop() {
+	for _, client := range c {
+		client.Stop()
+	}
+}
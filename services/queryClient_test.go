@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+)
+
+// fakeQueryBackend answers the queries with the errors in order, then with the indicators.
+type fakeQueryBackend struct {
+	mu         sync.Mutex
+	errs       []error
+	indicators []DeviceQueryIndicator
+	calls      int
+	block      chan struct{}
+}
+
+func (b *fakeQueryBackend) Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error) {
+	b.mu.Lock()
+	b.calls++
+	call := b.calls
+	block := b.block
+	b.mu.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if call <= len(b.errs) && b.errs[call-1] != nil {
+		return nil, b.errs[call-1]
+	}
+
+	return b.indicators, nil
+}
+
+func (b *fakeQueryBackend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls
+}
+
+func newTestQueryClient(backend QueryBackend, settings config.QuerySettings) *QueryClient {
+	return NewQueryClient(&log.Logger{Disabled: true}, "test", backend, settings)
+}
+
+func TestQueryClientRetries(t *testing.T) {
+	errServer := &QueryHTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}
+	errClient := &QueryHTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	errNetwork := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		errs    []error
+		retries int
+		calls   int
+		wantErr bool
+	}{
+		{name: "answered", retries: 2, calls: 1},
+		{name: "network error retried", errs: []error{errNetwork}, retries: 2, calls: 2},
+		{name: "server error retried", errs: []error{errServer, errServer}, retries: 2, calls: 3},
+		{name: "retries exhausted", errs: []error{errServer, errServer, errServer}, retries: 2, calls: 3, wantErr: true},
+		{name: "client error not retried", errs: []error{errClient}, retries: 2, calls: 1, wantErr: true},
+		{name: "no retries", errs: []error{errNetwork}, calls: 1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := &fakeQueryBackend{errs: test.errs, indicators: []DeviceQueryIndicator{{Indicator: "GLU"}}}
+			c := newTestQueryClient(backend, config.QuerySettings{Retries: test.retries})
+
+			indicators, err := c.Query("BC1", "SN1")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Query() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (len(indicators) != 1 || indicators[0].Indicator != "GLU") {
+				t.Errorf("Query() = %v, want the GLU indicator", indicators)
+			}
+			if calls := backend.Calls(); calls != test.calls {
+				t.Errorf("backend called %d times, want %d", calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestQueryClientTimeout(t *testing.T) {
+	backend := &fakeQueryBackend{block: make(chan struct{})}
+	defer close(backend.block)
+
+	c := newTestQueryClient(backend, config.QuerySettings{})
+	c.timeout = 50 * time.Millisecond
+
+	_, err := c.Query("BC1", "SN1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Query() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestQueryClientCircuitBreaker(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+	backend := &fakeQueryBackend{errs: []error{errNetwork, errNetwork, errNetwork}}
+
+	c := newTestQueryClient(backend, config.QuerySettings{BreakerFailures: 2})
+	c.breakerCooldown = 50 * time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Query("BC1", "SN1")
+		if err == nil || errors.Is(err, ErrQueryCircuitOpen) {
+			t.Fatalf("Query() %d error = %v, want the backend error", i, err)
+		}
+	}
+
+	_, err := c.Query("BC1", "SN1")
+	if !errors.Is(err, ErrQueryCircuitOpen) {
+		t.Fatalf("Query() after the failures error = %v, want %v", err, ErrQueryCircuitOpen)
+	}
+	if calls := backend.Calls(); calls != 2 {
+		t.Errorf("backend called %d times while open, want 2", calls)
+	}
+
+	// the failed probe after the cooldown opens the breaker again
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Query("BC1", "SN1")
+	if err == nil || errors.Is(err, ErrQueryCircuitOpen) {
+		t.Fatalf("Query() probe error = %v, want the backend error", err)
+	}
+	_, err = c.Query("BC1", "SN1")
+	if !errors.Is(err, ErrQueryCircuitOpen) {
+		t.Fatalf("Query() after the failed probe error = %v, want %v", err, ErrQueryCircuitOpen)
+	}
+
+	// the answered probe closes the breaker
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Query("BC1", "SN1")
+	if err != nil {
+		t.Fatalf("Query() probe error = %v", err)
+	}
+	_, err = c.Query("BC2", "SN1")
+	if err != nil {
+		t.Fatalf("Query() after the answered probe error = %v", err)
+	}
+
+	metrics := c.Metrics()
+	if metrics.Queries != 5 || metrics.Failures != 3 || metrics.Rejected != 2 {
+		t.Errorf("Metrics() = %+v, want 5 queries, 3 failures and 2 rejected", metrics)
+	}
+}
+
+func TestQueryClientHalfOpenSingleProbe(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+	backend := &fakeQueryBackend{errs: []error{errNetwork}}
+
+	c := newTestQueryClient(backend, config.QuerySettings{BreakerFailures: 1})
+	c.breakerCooldown = 10 * time.Millisecond
+
+	_, err := c.Query("BC1", "SN1")
+	if err == nil {
+		t.Fatal("Query() error = nil, want the backend error")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	backend.mu.Lock()
+	backend.block = make(chan struct{})
+	backend.mu.Unlock()
+
+	probed := make(chan error)
+	go func() {
+		_, err := c.Query("BC1", "SN1")
+		probed <- err
+	}()
+
+	for backend.Calls() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the other queries are rejected while the probe is pending
+	_, err = c.Query("BC2", "SN1")
+	if !errors.Is(err, ErrQueryCircuitOpen) {
+		t.Errorf("Query() during the probe error = %v, want %v", err, ErrQueryCircuitOpen)
+	}
+
+	close(backend.block)
+	if err := <-probed; err != nil {
+		t.Errorf("Query() probe error = %v", err)
+	}
+}
+
+func TestQueryClientClientErrorsKeepBreakerClosed(t *testing.T) {
+	errClient := &QueryHTTPError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}
+	backend := &fakeQueryBackend{errs: []error{errClient, errClient, errClient}}
+
+	c := newTestQueryClient(backend, config.QuerySettings{BreakerFailures: 2})
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Query("BC1", "SN1")
+		if errors.Is(err, ErrQueryCircuitOpen) {
+			t.Fatalf("Query() %d error = %v, want the client error", i, err)
+		}
+	}
+	if calls := backend.Calls(); calls != 3 {
+		t.Errorf("backend called %d times, want 3", calls)
+	}
+}
+
+func TestQueryClientCache(t *testing.T) {
+	backend := &fakeQueryBackend{
+		errs:       []error{errors.New("connection refused")},
+		indicators: []DeviceQueryIndicator{{Indicator: "GLU"}},
+	}
+
+	c := newTestQueryClient(backend, config.QuerySettings{})
+	c.cacheTTL = 50 * time.Millisecond
+
+	// the failed answers are not cached
+	_, err := c.Query("BC1", "SN1")
+	if err == nil {
+		t.Fatal("Query() error = nil, want the backend error")
+	}
+
+	for i := 0; i < 2; i++ {
+		indicators, err := c.Query("BC1", "SN1")
+		if err != nil || len(indicators) != 1 {
+			t.Fatalf("Query() %d = %v, %v, want the GLU indicator", i, indicators, err)
+		}
+	}
+	if calls := backend.Calls(); calls != 2 {
+		t.Errorf("backend called %d times, want the second answer cached", calls)
+	}
+
+	// the cache is keyed by the barcode and the serial
+	_, err = c.Query("BC1", "SN2")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if calls := backend.Calls(); calls != 3 {
+		t.Errorf("backend called %d times, want another serial queried", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Query("BC1", "SN1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if calls := backend.Calls(); calls != 4 {
+		t.Errorf("backend called %d times, want the expired answer queried", calls)
+	}
+
+	metrics := c.Metrics()
+	if metrics.Queries != 4 || metrics.CacheHits != 1 || metrics.Failures != 1 {
+		t.Errorf("Metrics() = %+v, want 4 queries, 1 cache hit and 1 failure", metrics)
+	}
+}
+
+func TestQueryClientNil(t *testing.T) {
+	var c *QueryClient
+
+	indicators, err := c.Query("BC1", "SN1")
+	if indicators != nil || err != nil {
+		t.Errorf("Query() = %v, %v, want nothing", indicators, err)
+	}
+}
+
+func TestQueryClientStopWithoutStart(t *testing.T) {
+	c := newTestQueryClient(&fakeQueryBackend{}, config.QuerySettings{})
+
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked without Start()")
+	}
+}
+
+func TestDoctraQueryBackend(t *testing.T) {
+	var reqBody DeviceQueryRequestBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		if reqBody.Barcode == "MISSING" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"indicators":[{"indicator":"GLU","material":2}]}`))
+	}))
+	defer server.Close()
+
+	b := NewDoctraQueryBackend(server.URL)
+
+	indicators, err := b.Query(context.Background(), "BC1", "SN1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if reqBody.Barcode != "BC1" || reqBody.HardwareSN != "SN1" {
+		t.Errorf("request body = %+v, want the barcode BC1 and the serial SN1", reqBody)
+	}
+	if len(indicators) != 1 || indicators[0].Indicator != "GLU" || indicators[0].Material != 2 {
+		t.Errorf("Query() = %+v, want the GLU indicator of material 2", indicators)
+	}
+
+	_, err = b.Query(context.Background(), "MISSING", "SN1")
+	var httpErr *QueryHTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Query() error = %v, want a 404 QueryHTTPError", err)
+	}
+}
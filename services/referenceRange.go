@@ -0,0 +1,79 @@
+package services
+
+import (
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// ReferenceRangeService completes the reference ranges and the abnormal flags of the lab datas.
+// The configured reference ranges are used for the results the device sent without a range,
+// the flag is computed for the numeric results the device sent without a flag.
+type ReferenceRangeService struct {
+	store *store.Store
+}
+
+// NewReferenceRangeService creates a new ReferenceRangeService
+func NewReferenceRangeService(store *store.Store) *ReferenceRangeService {
+	return &ReferenceRangeService{
+		store: store,
+	}
+}
+
+// ApplyLabDatas sets the reference ranges and the abnormal flags of the lab datas, the lab datas must have the HIS test codes
+func (s *ReferenceRangeService) ApplyLabDatas(labDatas []*model.LabData) error {
+	if len(labDatas) == 0 {
+		return nil
+	}
+
+	referenceRanges, err := s.referenceRanges(labDatas)
+	if err != nil {
+		return err
+	}
+
+	for _, labData := range labDatas {
+		referenceRange := model.ParseReferenceRange(labData.ReferenceRange)
+		if labData.ReferenceRange == "" {
+			referenceRange = referenceRanges.Find(labData.Param, labData.Unit, labData.PatientSex, ageDays(labData))
+			if referenceRange != nil {
+				labData.ReferenceRange = referenceRange.Text()
+			}
+		}
+
+		if labData.AbnormalFlag != "" || referenceRange == nil {
+			continue
+		}
+
+		value, err := model.ParseNumericResult(labData.Result)
+		if err != nil {
+			continue
+		}
+		labData.AbnormalFlag = referenceRange.Flag(value)
+	}
+
+	return nil
+}
+
+// referenceRanges gets the configured reference ranges of the lab datas, a service without a store has none
+func (s *ReferenceRangeService) referenceRanges(labDatas []*model.LabData) (model.ReferenceRanges, error) {
+	if s == nil || s.store == nil {
+		return nil, nil
+	}
+
+	params := make([]string, 0, len(labDatas))
+	for _, labData := range labDatas {
+		params = append(params, labData.Param)
+	}
+
+	return s.store.ReferenceRangeStore.GetByParams(params)
+}
+
+// ageDays returns the age of the patient in days at the completion of the lab data or -1 if it is unknown
+func ageDays(labData *model.LabData) int {
+	if labData.PatientBirthDate == nil || labData.PatientBirthDate.After(labData.CompletedDate) {
+		return -1
+	}
+
+	return int(labData.CompletedDate.Sub(*labData.PatientBirthDate) / (24 * time.Hour))
+}
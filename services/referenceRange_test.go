@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+func TestReferenceRangeServiceDeviceRanges(t *testing.T) {
+	tests := []struct {
+		name           string
+		labData        *model.LabData
+		referenceRange string
+		abnormalFlag   string
+	}{
+		{
+			name:           "flag from the device range",
+			labData:        &model.LabData{Param: "GLU", Result: "7,2", ReferenceRange: "3.9-6.1"},
+			referenceRange: "3.9-6.1",
+			abnormalFlag:   model.FlagHigh,
+		},
+		{
+			name:           "device flag kept",
+			labData:        &model.LabData{Param: "GLU", Result: "7.2", ReferenceRange: "3.9-6.1", AbnormalFlag: model.FlagCriticalHigh},
+			referenceRange: "3.9-6.1",
+			abnormalFlag:   model.FlagCriticalHigh,
+		},
+		{
+			name:           "text result",
+			labData:        &model.LabData{Param: "HCG", Result: "positive", ReferenceRange: "0-5"},
+			referenceRange: "0-5",
+		},
+		{
+			name:           "range in another form",
+			labData:        &model.LabData{Param: "CRP", Result: "12", ReferenceRange: "<5"},
+			referenceRange: "<5",
+		},
+		{
+			name:    "no range",
+			labData: &model.LabData{Param: "CRP", Result: "12"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewReferenceRangeService(nil).ApplyLabDatas([]*model.LabData{test.labData})
+			if err != nil {
+				t.Fatalf("ApplyLabDatas() error = %v", err)
+			}
+			if test.labData.ReferenceRange != test.referenceRange || test.labData.AbnormalFlag != test.abnormalFlag {
+				t.Errorf("ApplyLabDatas() = %q, %q, want %q, %q", test.labData.ReferenceRange, test.labData.AbnormalFlag, test.referenceRange, test.abnormalFlag)
+			}
+		})
+	}
+}
+
+func TestAgeDays(t *testing.T) {
+	completed := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	birthDate := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	unborn := completed.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name      string
+		birthDate *time.Time
+		want      int
+	}{
+		{name: "a year", birthDate: &birthDate, want: 366},
+		{name: "born on the day", birthDate: &completed, want: 0},
+		{name: "unknown", birthDate: nil, want: -1},
+		{name: "born after the completion", birthDate: &unborn, want: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ageDays(&model.LabData{CompletedDate: completed, PatientBirthDate: test.birthDate}); got != test.want {
+				t.Errorf("ageDays() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
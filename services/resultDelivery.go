@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+const (
+	defaultPushInterval   = 10 * time.Second
+	defaultPushMaxBackoff = 10 * time.Minute
+	defaultPushBatchSize  = 500
+)
+
+// ResultPushRequestBody represents the result batch of a barcode posted to the HIS
+type ResultPushRequestBody struct {
+	Barcode    string             `json:"barcode"`
+	HardwareSN string             `json:"hardware_sn"`
+	Results    []ResultPushResult `json:"results"`
+}
+
+// ResultPushResult represents each result in the pushed batch
+type ResultPushResult struct {
+	ID              uint      `json:"id"`
+	Index           uint      `json:"index"`
+	Param           string    `json:"param"`
+	Result          string    `json:"result"`
+	Unit            string    `json:"unit"`
+	OriginalResult  string    `json:"original_result"`
+	OriginalUnit    string    `json:"original_unit"`
+	CompletedDate   time.Time `json:"completed_date"`
+	ReferenceRange  string    `json:"reference_range"`
+	AbnormalFlag    string    `json:"abnormal_flag"`
+	ResultStatus    string    `json:"result_status"`
+	InstrumentFlags string    `json:"instrument_flags"`
+	Verification    string    `json:"verification_status"`
+}
+
+// ResultDeliveryService pushes the stored lab data to the HIS and retries the failed batches with backoff
+type ResultDeliveryService struct {
+	log        *log.Logger
+	store      *store.Store
+	client     *resty.Client
+	pushHost   string
+	interval   time.Duration
+	maxBackoff time.Duration
+	batchSize  int
+	started    bool
+	notify     chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewResultDeliveryService creates a new ResultDeliveryService
+func NewResultDeliveryService(logger *log.Logger, store *store.Store, settings config.ResultPushSettings) *ResultDeliveryService {
+	s := &ResultDeliveryService{
+		log:        logger,
+		store:      store,
+		client:     newHTTPClient(time.Duration(settings.TimeoutSeconds) * time.Second),
+		pushHost:   settings.Host,
+		interval:   time.Duration(settings.IntervalSeconds) * time.Second,
+		maxBackoff: time.Duration(settings.MaxBackoffSeconds) * time.Second,
+		batchSize:  settings.BatchSize,
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultPushInterval
+	}
+	if s.maxBackoff <= 0 {
+		s.maxBackoff = defaultPushMaxBackoff
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultPushBatchSize
+	}
+
+	return s
+}
+
+// Start starts delivering the pending lab data in the background
+func (s *ResultDeliveryService) Start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	go s.run()
+}
+
+// Stop stops the delivery and waits for the current batch to finish, it does nothing if the delivery was not started
+func (s *ResultDeliveryService) Stop() {
+	if !s.started {
+		return
+	}
+
+	s.started = false
+	close(s.stop)
+	<-s.done
+}
+
+// Notify wakes the delivery up after new lab data were stored
+func (s *ResultDeliveryService) Notify() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the due lab data on every tick or notification until stopped
+func (s *ResultDeliveryService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.notify:
+		}
+	}
+}
+
+// deliverDue pushes the due lab data grouped by device and barcode
+func (s *ResultDeliveryService) deliverDue() {
+	labDatas, err := s.store.LabDataStore.GetDueForDelivery(time.Now(), s.batchSize)
+	if err != nil {
+		s.log.Err(err, "failed to get the lab data due for delivery")
+		return
+	}
+
+	batches := map[string][]*model.LabData{}
+	keys := []string{}
+	for _, labData := range labDatas {
+		key := fmt.Sprintf("%d|%s", labData.DeviceID, labData.Barcode)
+		if _, ok := batches[key]; !ok {
+			keys = append(keys, key)
+		}
+		batches[key] = append(batches[key], labData)
+	}
+
+	serials := map[uint]string{}
+	for _, key := range keys {
+		batch := batches[key]
+
+		serial, ok := serials[batch[0].DeviceID]
+		if !ok {
+			device, err := s.store.DeviceStore.GetByID(batch[0].DeviceID)
+			if err != nil {
+				s.log.Err(err, "failed to get the device of the lab data")
+			} else {
+				serial = device.Serial
+			}
+			serials[batch[0].DeviceID] = serial
+		}
+
+		s.deliverBatch(batch, serial)
+	}
+}
+
+// deliverBatch pushes the batch and stores its delivery state
+func (s *ResultDeliveryService) deliverBatch(batch []*model.LabData, serial string) {
+	ids := make([]uint, 0, len(batch))
+	attempts := uint(0)
+	for _, labData := range batch {
+		ids = append(ids, labData.ID)
+		attempts = max(attempts, labData.DeliveryAttempts)
+	}
+	attempts++
+
+	err := s.push(batch, serial)
+	if err != nil {
+		nextDeliveryAt := time.Now().Add(s.backoff(attempts))
+		s.log.Err(err, fmt.Sprintf("failed to push the results of barcode %s, attempt %d, next at %s", batch[0].Barcode, attempts, nextDeliveryAt.Format(time.RFC3339)))
+
+		err = s.store.LabDataStore.UpdateDelivery(ids, model.DeliveryStatusFailed, attempts, err.Error(), &nextDeliveryAt, nil)
+		if err != nil {
+			s.log.Err(err, "failed to store the delivery failure")
+		}
+		return
+	}
+
+	deliveredAt := time.Now()
+	err = s.store.LabDataStore.UpdateDelivery(ids, model.DeliveryStatusDelivered, attempts, "", nil, &deliveredAt)
+	if err != nil {
+		s.log.Err(err, "failed to store the delivery")
+	}
+}
+
+// push posts the batch to the HIS
+func (s *ResultDeliveryService) push(batch []*model.LabData, serial string) error {
+	reqBody := &ResultPushRequestBody{
+		Barcode:    batch[0].Barcode,
+		HardwareSN: serial,
+	}
+	for _, labData := range batch {
+		reqBody.Results = append(reqBody.Results, ResultPushResult{
+			ID:              labData.ID,
+			Index:           labData.Index,
+			Param:           labData.Param,
+			Result:          labData.Result,
+			Unit:            labData.Unit,
+			OriginalResult:  labData.OriginalResult,
+			OriginalUnit:    labData.OriginalUnit,
+			CompletedDate:   labData.CompletedDate,
+			ReferenceRange:  labData.ReferenceRange,
+			AbnormalFlag:    labData.AbnormalFlag,
+			ResultStatus:    labData.ResultStatus,
+			InstrumentFlags: labData.InstrumentFlags,
+			Verification:    labData.VerificationStatus,
+		})
+	}
+
+	resp, err := s.client.R().
+		SetBody(reqBody).
+		Post(s.pushHost)
+	if err != nil {
+		return err
+	}
+
+	// Check for HTTP status code
+	if resp.IsError() {
+		return errors.New("HTTP error: " + resp.Status())
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt, doubling the interval on every failed attempt
+func (s *ResultDeliveryService) backoff(attempts uint) time.Duration {
+	delay := s.interval
+	for i := uint(1); i < attempts && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.maxBackoff)
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+)
+
+func TestResultDeliveryPushTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := NewResultDeliveryService(&log.Logger{Disabled: true}, nil, config.ResultPushSettings{Host: server.URL, TimeoutSeconds: 1})
+
+	start := time.Now()
+	err := s.push([]*model.LabData{{Barcode: "BC1"}}, "SN1")
+	if err == nil {
+		t.Fatal("push() error = nil, want the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("push() took %v, want it to time out after a second", elapsed)
+	}
+}
+
+func TestResultDeliveryStopWithoutStart(t *testing.T) {
+	s := NewResultDeliveryService(&log.Logger{Disabled: true}, nil, config.ResultPushSettings{})
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked without Start()")
+	}
+}
+
+// testPushServer records the batches pushed to it and fails the pushes of the barcodes in failing.
+type testPushServer struct {
+	mu      sync.Mutex
+	batches []ResultPushRequestBody
+	failing map[string]bool
+}
+
+func (s *testPushServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var batch ResultPushRequestBody
+	json.NewDecoder(r.Body).Decode(&batch)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, batch)
+	if s.failing[batch.Barcode] {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// Batches returns the batches pushed since the last call.
+func (s *testPushServer) Batches() []ResultPushRequestBody {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batches := s.batches
+	s.batches = nil
+
+	return batches
+}
+
+// newTestResultDelivery creates a ResultDeliveryService pushing to the test server on a test store.
+// The failed pushes are retried after 10 seconds doubled on every attempt up to a minute.
+func newTestResultDelivery(t *testing.T) (*ResultDeliveryService, *testPushServer, *store.Store) {
+	t.Helper()
+
+	st := storetest.New(t)
+	pushServer := &testPushServer{failing: map[string]bool{}}
+	server := httptest.NewServer(pushServer)
+	t.Cleanup(server.Close)
+
+	s := NewResultDeliveryService(&log.Logger{Disabled: true}, st, config.ResultPushSettings{
+		Host:              server.URL,
+		IntervalSeconds:   10,
+		MaxBackoffSeconds: 60,
+		TimeoutSeconds:    1,
+	})
+
+	return s, pushServer, st
+}
+
+// createPendingLabDatas stores the lab datas queued for the delivery.
+func createPendingLabDatas(t *testing.T, st *store.Store, labDatas ...*model.LabData) {
+	t.Helper()
+
+	for _, labData := range labDatas {
+		labData.RawDataID = 1
+		labData.CompletedDate = time.Now()
+		labData.QueueDelivery()
+	}
+
+	err := st.LabDataStore.CreateAll(labDatas)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+}
+
+// getLabData gets the stored state of the lab data.
+func getLabData(t *testing.T, st *store.Store, id uint) *model.LabData {
+	t.Helper()
+
+	labData, err := st.LabDataStore.GetByID(id)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	return labData
+}
+
+func TestResultDeliveryBatches(t *testing.T) {
+	s, pushServer, st := newTestResultDelivery(t)
+	device1 := storetest.CreateDevice(t, st, "Analyzer 1", "SN1")
+	device2 := storetest.CreateDevice(t, st, "Analyzer 2", "SN2")
+
+	held := &model.LabData{DeviceID: device1.ID, Barcode: "BC3", Param: "NA", VerificationStatus: model.VerificationStatusHeld}
+	createPendingLabDatas(t, st,
+		&model.LabData{DeviceID: device1.ID, Barcode: "BC1", Param: "GLU", Result: "5.1"},
+		&model.LabData{DeviceID: device1.ID, Barcode: "BC2", Param: "ALT", Result: "30"},
+		&model.LabData{DeviceID: device2.ID, Barcode: "BC1", Param: "GLU", Result: "5.3"},
+		&model.LabData{DeviceID: device1.ID, Barcode: "BC1", Param: "K", Result: "4.2"},
+		held,
+	)
+
+	s.deliverDue()
+
+	// a batch per device and barcode in the order of their first lab data, the held lab data is not pushed
+	want := []struct {
+		barcode string
+		serial  string
+		params  []string
+	}{
+		{barcode: "BC1", serial: "SN1", params: []string{"GLU", "K"}},
+		{barcode: "BC2", serial: "SN1", params: []string{"ALT"}},
+		{barcode: "BC1", serial: "SN2", params: []string{"GLU"}},
+	}
+	batches := pushServer.Batches()
+	if len(batches) != len(want) {
+		t.Fatalf("pushed %d batches, want %d: %+v", len(batches), len(want), batches)
+	}
+	for i, batch := range batches {
+		params := []string{}
+		for _, result := range batch.Results {
+			params = append(params, result.Param)
+		}
+		if batch.Barcode != want[i].barcode || batch.HardwareSN != want[i].serial || !slices.Equal(params, want[i].params) {
+			t.Errorf("batch %d = %s %s %v, want %s %s %v", i, batch.Barcode, batch.HardwareSN, params, want[i].barcode, want[i].serial, want[i].params)
+		}
+	}
+
+	for id := uint(1); id <= 4; id++ {
+		labData := getLabData(t, st, id)
+		if labData.DeliveryStatus != model.DeliveryStatusDelivered || labData.DeliveryAttempts != 1 || labData.DeliveredAt == nil || labData.NextDeliveryAt != nil {
+			t.Errorf("lab data %d delivery = %s after %d attempts, delivered at %v, next at %v, want delivered after 1 attempt",
+				id, labData.DeliveryStatus, labData.DeliveryAttempts, labData.DeliveredAt, labData.NextDeliveryAt)
+		}
+	}
+	if labData := getLabData(t, st, held.ID); labData.DeliveryStatus != model.DeliveryStatusHeld {
+		t.Errorf("held lab data delivery = %s, want held", labData.DeliveryStatus)
+	}
+
+	// the delivered lab datas are not pushed again
+	s.deliverDue()
+	if batches := pushServer.Batches(); len(batches) != 0 {
+		t.Errorf("pushed %d batches again, want none", len(batches))
+	}
+}
+
+func TestResultDeliveryRetries(t *testing.T) {
+	s, pushServer, st := newTestResultDelivery(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+
+	labData := &model.LabData{DeviceID: device.ID, Barcode: "BC1", Param: "GLU", Result: "5.1"}
+	createPendingLabDatas(t, st, labData)
+	pushServer.failing["BC1"] = true
+
+	start := time.Now()
+	s.deliverDue()
+
+	failed := getLabData(t, st, labData.ID)
+	if failed.DeliveryStatus != model.DeliveryStatusFailed || failed.DeliveryAttempts != 1 || !strings.Contains(failed.DeliveryError, "503") {
+		t.Fatalf("delivery = %s after %d attempts with %q, want failed after 1 attempt with the HTTP error", failed.DeliveryStatus, failed.DeliveryAttempts, failed.DeliveryError)
+	}
+	if failed.NextDeliveryAt == nil || failed.NextDeliveryAt.Before(start.Add(10*time.Second)) || failed.NextDeliveryAt.After(time.Now().Add(10*time.Second)) {
+		t.Errorf("next delivery at %v, want 10 seconds after the failure", failed.NextDeliveryAt)
+	}
+
+	// the failed lab data waits for its next delivery time
+	s.deliverDue()
+	if batches := pushServer.Batches(); len(batches) != 1 {
+		t.Fatalf("pushed %d batches, want the failed one only once", len(batches))
+	}
+
+	// the fourth attempt waits 80 seconds capped to the minute
+	past := time.Now().Add(-time.Second)
+	err := st.LabDataStore.UpdateDelivery([]uint{labData.ID}, model.DeliveryStatusFailed, 3, "HTTP error", &past, nil)
+	if err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+
+	start = time.Now()
+	s.deliverDue()
+
+	failed = getLabData(t, st, labData.ID)
+	if failed.DeliveryAttempts != 4 || failed.NextDeliveryAt == nil || failed.NextDeliveryAt.Before(start.Add(time.Minute)) || failed.NextDeliveryAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("delivery after %d attempts next at %v, want 4 attempts and the next a minute later", failed.DeliveryAttempts, failed.NextDeliveryAt)
+	}
+
+	// the answered retry is delivered
+	err = st.LabDataStore.UpdateDelivery([]uint{labData.ID}, model.DeliveryStatusFailed, 4, "HTTP error", &past, nil)
+	if err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+	pushServer.failing["BC1"] = false
+	s.deliverDue()
+
+	delivered := getLabData(t, st, labData.ID)
+	if delivered.DeliveryStatus != model.DeliveryStatusDelivered || delivered.DeliveryAttempts != 5 || delivered.DeliveryError != "" || delivered.NextDeliveryAt != nil || delivered.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want delivered after 5 attempts without an error", delivered)
+	}
+}
+
+func TestResultDeliveryBackoff(t *testing.T) {
+	s := NewResultDeliveryService(&log.Logger{Disabled: true}, nil, config.ResultPushSettings{IntervalSeconds: 10, MaxBackoffSeconds: 60})
+
+	tests := []struct {
+		attempts uint
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := s.backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLQueryBackend queries the HIS database directly.
+// The SQL returns a row per test with the columns aliased as the JSON names of the DeviceQueryIndicator fields,
+// e.g. SELECT t.code AS indicator, t.material AS material FROM ... The material is an integer column, the other columns are text.
+// The collection_time and patient_birth_date columns are parsed by model.ParseOrderTime, a datetime column is read in the text format
+// of the database driver, which may not be one of its formats, so cast them to text in the SQL,
+// e.g. CONVERT(varchar(19), s.collected_at, 120) on SQL Server or to_char(s.collected_at, 'YYYY-MM-DD HH24:MI:SS') on PostgreSQL.
+type SQLQueryBackend struct {
+	db  *gorm.DB
+	sql string
+}
+
+// NewSQLQueryBackend creates a new SQLQueryBackend.
+// The database is connected lazily, an unreachable HIS database fails the queries instead of the start.
+func NewSQLQueryBackend(settings config.QueryBackendSettings) (*SQLQueryBackend, error) {
+	if settings.SQL == "" {
+		return nil, fmt.Errorf("the SQL is not set")
+	}
+
+	var dialector gorm.Dialector
+	switch settings.DriverName {
+	case "sqlserver":
+		dialector = sqlserver.Open(settings.DSN)
+	case "postgres":
+		dialector = postgres.Open(settings.DSN)
+	case "mysql":
+		dialector = mysql.New(mysql.Config{DSN: settings.DSN, SkipInitializeWithVersion: true})
+	default:
+		return nil, fmt.Errorf("unknown driver name %s", settings.DriverName)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the HIS database: %v", err)
+	}
+
+	return &SQLQueryBackend{
+		db:  db,
+		sql: settings.SQL,
+	}, nil
+}
+
+// Query runs the SQL with the barcode and the serial as the @barcode and @serial parameters.
+// The rows without an indicator are skipped.
+func (b *SQLQueryBackend) Query(ctx context.Context, barcode, serial string) ([]DeviceQueryIndicator, error) {
+	rows := []DeviceQueryIndicator{}
+
+	err := b.db.WithContext(ctx).
+		Raw(b.sql, sql.Named("barcode", barcode), sql.Named("serial", serial)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	indicators := []DeviceQueryIndicator{}
+	for _, row := range rows {
+		if row.Indicator == "" {
+			continue
+		}
+
+		indicators = append(indicators, row)
+	}
+
+	return indicators, nil
+}
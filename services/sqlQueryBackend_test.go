@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/config"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+)
+
+func TestNewSQLQueryBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings config.QueryBackendSettings
+	}{
+		{name: "without SQL", settings: config.QueryBackendSettings{DriverName: "postgres"}},
+		{name: "unknown driver", settings: config.QueryBackendSettings{DriverName: "oracle", SQL: "SELECT 1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewSQLQueryBackend(test.settings)
+			if err == nil {
+				t.Error("NewSQLQueryBackend() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSQLQueryBackendQuery(t *testing.T) {
+	db := storetest.Open(t)
+	err := db.Exec(`CREATE TABLE his_orders (barcode TEXT, serial TEXT, code TEXT, material INTEGER, patient TEXT, collected TEXT)`).Error
+	if err == nil {
+		err = db.Exec(`INSERT INTO his_orders VALUES
+			('BC1', 'SN1', 'GLU', 2, 'P1', '2024-05-01 08:30:00'),
+			('BC1', 'SN1', NULL, 2, 'P1', NULL),
+			('BC1', 'SN1', '', 1, 'P1', NULL),
+			('BC1', '', 'ALT', 1, 'P1', NULL),
+			('BC1', 'SN2', 'K', 1, 'P1', NULL),
+			('BC2', 'SN1', 'NA', 1, 'P2', NULL)`).Error
+	}
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	// the tests of the barcode on the device or on any device, aliased as the indicator fields
+	b := &SQLQueryBackend{
+		db: db,
+		sql: `SELECT code AS indicator, material, patient AS patient_id, collected AS collection_time
+			FROM his_orders WHERE barcode = @barcode AND (serial = @serial OR serial = '') ORDER BY rowid`,
+	}
+
+	indicators, err := b.Query(context.Background(), "BC1", "SN1")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	// the rows without an indicator are skipped
+	want := []DeviceQueryIndicator{
+		{Indicator: "GLU", Material: 2, PatientID: "P1", CollectionTime: "2024-05-01 08:30:00"},
+		{Indicator: "ALT", Material: 1, PatientID: "P1"},
+	}
+	if len(indicators) != len(want) {
+		t.Fatalf("Query() = %+v, want %+v", indicators, want)
+	}
+	for i := range want {
+		if indicators[i] != want[i] {
+			t.Errorf("indicator %d = %+v, want %+v", i, indicators[i], want[i])
+		}
+	}
+
+	indicators, err = b.Query(context.Background(), "BC3", "SN1")
+	if err != nil || len(indicators) != 0 {
+		t.Errorf("Query() of an unknown barcode = %+v, %v, want none", indicators, err)
+	}
+
+	b.sql = "SELECT code AS indicator FROM missing_table"
+	_, err = b.Query(context.Background(), "BC1", "SN1")
+	if err == nil {
+		t.Error("Query() of invalid SQL error = nil, want an error")
+	}
+}
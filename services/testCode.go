@@ -0,0 +1,67 @@
+package services
+
+import (
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// TestCodeService translates the test codes between a device model and the HIS with the test code mappings.
+// The mappings are read on every translation, so their changes apply without reconnecting the devices.
+type TestCodeService struct {
+	store         *store.Store
+	deviceModelID uint
+}
+
+// NewTestCodeService creates a new TestCodeService for the device model
+func NewTestCodeService(store *store.Store, deviceModelID uint) *TestCodeService {
+	return &TestCodeService{
+		store:         store,
+		deviceModelID: deviceModelID,
+	}
+}
+
+// MapLabDatas replaces the device test codes of the lab datas with the HIS test codes, the device test codes are kept in DeviceParam
+func (s *TestCodeService) MapLabDatas(labDatas []*model.LabData) error {
+	if len(labDatas) == 0 {
+		return nil
+	}
+
+	mappings, err := s.mappings()
+	if err != nil {
+		return err
+	}
+
+	for _, labData := range labDatas {
+		labData.DeviceParam = labData.Param
+		labData.Param = mappings.ToHIS(labData.Param)
+	}
+
+	return nil
+}
+
+// MapQueryData replaces the HIS test codes of the query data with the device test codes
+func (s *TestCodeService) MapQueryData(data []DeviceQueryDataToReturn) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	mappings, err := s.mappings()
+	if err != nil {
+		return err
+	}
+
+	for i := range data {
+		data[i].Param = mappings.ToDevice(data[i].Param)
+	}
+
+	return nil
+}
+
+// mappings gets the test code mappings of the device model, a service without a store maps nothing
+func (s *TestCodeService) mappings() (model.TestCodeMappings, error) {
+	if s == nil || s.store == nil {
+		return nil, nil
+	}
+
+	return s.store.TestCodeMappingStore.GetByDeviceModelID(s.deviceModelID)
+}
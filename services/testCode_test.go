@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/store/storetest"
+)
+
+func TestTestCodeServiceWithoutStore(t *testing.T) {
+	labDatas := []*model.LabData{{Param: "GLU"}, {Param: "ALT"}}
+	err := NewTestCodeService(nil, 1).MapLabDatas(labDatas)
+	if err != nil {
+		t.Fatalf("MapLabDatas() error = %v", err)
+	}
+	for _, labData := range labDatas {
+		if labData.Param != labData.DeviceParam || labData.DeviceParam == "" {
+			t.Errorf("MapLabDatas() param = %q, device param = %q, want both the device code", labData.Param, labData.DeviceParam)
+		}
+	}
+
+	data := []DeviceQueryDataToReturn{{Param: "GLUCOSE"}}
+	err = (*TestCodeService)(nil).MapQueryData(data)
+	if err != nil {
+		t.Fatalf("MapQueryData() error = %v", err)
+	}
+	if data[0].Param != "GLUCOSE" {
+		t.Errorf("MapQueryData() param = %q, want GLUCOSE", data[0].Param)
+	}
+}
+
+// createTestCodeMappings stores the mappings of the device model, the pairs are the device code and the HIS code.
+func createTestCodeMappings(t *testing.T, st *store.Store, deviceModelID uint, pairs ...[2]string) {
+	t.Helper()
+
+	for _, pair := range pairs {
+		err := st.TestCodeMappingStore.Create(&model.TestCodeMapping{DeviceModelID: deviceModelID, DeviceCode: pair[0], HISCode: pair[1]})
+		if err != nil {
+			t.Fatalf("TestCodeMappingStore.Create() error = %v", err)
+		}
+	}
+}
+
+func TestTestCodeServiceMapLabDatas(t *testing.T) {
+	st := storetest.New(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+	other := storetest.CreateDevice(t, st, "Other", "SN2")
+	createTestCodeMappings(t, st, device.DeviceModelID, [2]string{"GLU", "GLUCOSE"}, [2]string{"ALT", "ALAT"})
+	createTestCodeMappings(t, st, other.DeviceModelID, [2]string{"K", "POTASSIUM"})
+
+	labDatas := []*model.LabData{{Param: "GLU"}, {Param: "ALT"}, {Param: "K"}}
+	err := NewTestCodeService(st, device.DeviceModelID).MapLabDatas(labDatas)
+	if err != nil {
+		t.Fatalf("MapLabDatas() error = %v", err)
+	}
+
+	// the mappings of the other device models are not used
+	want := []struct{ param, deviceParam string }{
+		{param: "GLUCOSE", deviceParam: "GLU"},
+		{param: "ALAT", deviceParam: "ALT"},
+		{param: "K", deviceParam: "K"},
+	}
+	for i, labData := range labDatas {
+		if labData.Param != want[i].param || labData.DeviceParam != want[i].deviceParam {
+			t.Errorf("lab data %d param = %q, device param = %q, want %q and %q", i, labData.Param, labData.DeviceParam, want[i].param, want[i].deviceParam)
+		}
+	}
+}
+
+func TestTestCodeServiceMapQueryData(t *testing.T) {
+	st := storetest.New(t)
+	device := storetest.CreateDevice(t, st, "Analyzer", "SN1")
+	// the HIS code of the glucose is measured by two device tests, the first mapping is queried
+	createTestCodeMappings(t, st, device.DeviceModelID, [2]string{"GLU", "GLUCOSE"}, [2]string{"GLU2", "GLUCOSE"}, [2]string{"ALT", "ALAT"})
+
+	data := []DeviceQueryDataToReturn{{Param: "GLUCOSE"}, {Param: "ALAT"}, {Param: "CRP"}}
+	err := NewTestCodeService(st, device.DeviceModelID).MapQueryData(data)
+	if err != nil {
+		t.Fatalf("MapQueryData() error = %v", err)
+	}
+
+	want := []string{"GLU", "ALT", "CRP"}
+	for i := range data {
+		if data[i].Param != want[i] {
+			t.Errorf("query data %d param = %q, want %q", i, data[i].Param, want[i])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// UnitConversionService normalizes the results of the lab datas to the canonical units of their test codes.
+// The units are first normalized with the unit registry, then the numeric results are converted with the unit conversions.
+// The results which can't be converted keep the unit sent by the device.
+type UnitConversionService struct {
+	store *store.Store
+}
+
+// NewUnitConversionService creates a new UnitConversionService
+func NewUnitConversionService(store *store.Store) *UnitConversionService {
+	return &UnitConversionService{
+		store: store,
+	}
+}
+
+// NormalizeLabDatas converts the results of the lab datas to the canonical units, the results and the units sent by the device are kept in OriginalResult and OriginalUnit.
+// The lab datas must have the HIS test codes. A reference range sent by the device is converted too or dropped when it can't be, so that the configured one applies.
+func (s *UnitConversionService) NormalizeLabDatas(labDatas []*model.LabData) error {
+	if len(labDatas) == 0 {
+		return nil
+	}
+
+	units, conversions, canonicalUnits, err := s.settings(labDatas)
+	if err != nil {
+		return err
+	}
+
+	normalizeLabDatas(labDatas, units, conversions, canonicalUnits)
+
+	return nil
+}
+
+// normalizeLabDatas converts the results of the lab datas with the unit registry, the unit conversions and the canonical units
+func normalizeLabDatas(labDatas []*model.LabData, units model.Units, conversions model.UnitConversions, canonicalUnits model.CanonicalUnits) {
+	for _, labData := range labDatas {
+		labData.OriginalResult = labData.Result
+		labData.OriginalUnit = labData.Unit
+		labData.Unit = units.Normalize(labData.Unit)
+
+		canonicalUnit := canonicalUnits.Find(labData.Param)
+		if canonicalUnit == nil {
+			continue
+		}
+		toUnit := units.Normalize(canonicalUnit.Unit)
+		if toUnit == labData.Unit {
+			continue
+		}
+
+		convert, ok := conversions.Converter(labData.Param, labData.Unit, toUnit)
+		if !ok {
+			continue
+		}
+
+		result, ok := canonicalUnit.ConvertResult(labData.Result, convert)
+		if !ok {
+			continue
+		}
+		labData.Result = result
+		labData.Unit = toUnit
+
+		if labData.ReferenceRange != "" {
+			labData.ReferenceRange, _ = canonicalUnit.ConvertReferenceRange(labData.ReferenceRange, convert)
+		}
+	}
+}
+
+// settings gets the unit registry, the unit conversions and the canonical units of the lab datas, a service without a store has none
+// The units of the conversions are normalized with the unit registry.
+func (s *UnitConversionService) settings(labDatas []*model.LabData) (model.Units, model.UnitConversions, model.CanonicalUnits, error) {
+	if s == nil || s.store == nil {
+		return nil, nil, nil, nil
+	}
+
+	params := make([]string, 0, len(labDatas))
+	for _, labData := range labDatas {
+		params = append(params, labData.Param)
+	}
+
+	units, err := s.store.UnitStore.GetAll()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	canonicalUnits, err := s.store.CanonicalUnitStore.GetByParams(params)
+	if err != nil || len(canonicalUnits) == 0 {
+		return units, nil, nil, err
+	}
+
+	conversions, err := s.store.UnitConversionStore.GetByParams(params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, conversion := range conversions {
+		conversion.FromUnit = units.Normalize(conversion.FromUnit)
+		conversion.ToUnit = units.Normalize(conversion.ToUnit)
+	}
+
+	return units, conversions, canonicalUnits, nil
+}
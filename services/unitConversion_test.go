@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+func TestNormalizeLabDatas(t *testing.T) {
+	units := model.Units{
+		{Code: "mmol/L", Aliases: "mmol/l"},
+		{Code: "mg/dL", Aliases: "mg%"},
+	}
+	conversions := model.UnitConversions{
+		{Param: "GLU", FromUnit: "mg/dL", ToUnit: "mmol/L", Factor: 0.0555},
+	}
+	canonicalUnits := model.CanonicalUnits{
+		{Param: "GLU", Unit: "mmol/l"},
+		{Param: "CHOL", Unit: "mmol/L"},
+	}
+
+	tests := []struct {
+		name           string
+		labData        *model.LabData
+		result         string
+		unit           string
+		referenceRange string
+	}{
+		{
+			name:           "converted with the range",
+			labData:        &model.LabData{Param: "GLU", Result: "100", Unit: "mg%", ReferenceRange: "70-110"},
+			result:         "5.55",
+			unit:           "mmol/L",
+			referenceRange: "3.89-6.11",
+		},
+		{
+			name:    "range in another form dropped",
+			labData: &model.LabData{Param: "GLU", Result: "100", Unit: "mg/dL", ReferenceRange: "<110"},
+			result:  "5.55",
+			unit:    "mmol/L",
+		},
+		{
+			name:    "already in the canonical unit",
+			labData: &model.LabData{Param: "GLU", Result: "5.5", Unit: "mmol/l"},
+			result:  "5.5",
+			unit:    "mmol/L",
+		},
+		{
+			name:           "text result kept",
+			labData:        &model.LabData{Param: "GLU", Result: "hemolysed", Unit: "mg/dL", ReferenceRange: "70-110"},
+			result:         "hemolysed",
+			unit:           "mg/dL",
+			referenceRange: "70-110",
+		},
+		{
+			name:    "no conversion",
+			labData: &model.LabData{Param: "CHOL", Result: "200", Unit: "mg/dL"},
+			result:  "200",
+			unit:    "mg/dL",
+		},
+		{
+			name:    "no canonical unit",
+			labData: &model.LabData{Param: "ALT", Result: "35", Unit: "U/L"},
+			result:  "35",
+			unit:    "U/L",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			originalResult, originalUnit := test.labData.Result, test.labData.Unit
+
+			normalizeLabDatas([]*model.LabData{test.labData}, units, conversions, canonicalUnits)
+
+			labData := test.labData
+			if labData.Result != test.result || labData.Unit != test.unit || labData.ReferenceRange != test.referenceRange {
+				t.Errorf("normalizeLabDatas() = %q %q %q, want %q %q %q", labData.Result, labData.Unit, labData.ReferenceRange, test.result, test.unit, test.referenceRange)
+			}
+			if labData.OriginalResult != originalResult || labData.OriginalUnit != originalUnit {
+				t.Errorf("normalizeLabDatas() original = %q %q, want %q %q", labData.OriginalResult, labData.OriginalUnit, originalResult, originalUnit)
+			}
+		})
+	}
+}
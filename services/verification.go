@@ -0,0 +1,93 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+)
+
+// VerificationService checks the lab datas with the verification rules and sets their verification statuses.
+// A lab data passing all the rules is auto-verified, one failing a rule is held for a review or marked critical.
+// The critical abnormal flags (LL and HH) always mark the lab data critical.
+type VerificationService struct {
+	store *store.Store
+}
+
+// NewVerificationService creates a new VerificationService
+func NewVerificationService(store *store.Store) *VerificationService {
+	return &VerificationService{
+		store: store,
+	}
+}
+
+// VerifyLabDatas sets the verification statuses and reasons of the lab datas, the lab datas must have the HIS test codes and the canonical units
+func (s *VerificationService) VerifyLabDatas(labDatas []*model.LabData) error {
+	if len(labDatas) == 0 {
+		return nil
+	}
+
+	rules, err := s.rules(labDatas)
+	if err != nil {
+		return err
+	}
+
+	for _, labData := range labDatas {
+		err := s.verifyLabData(labData, rules.ForParam(labData.Param))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyLabData checks the lab data with the rules, the most severe status of the failed rules wins
+func (s *VerificationService) verifyLabData(labData *model.LabData, rules model.VerificationRules) error {
+	status := model.VerificationStatusAutoVerified
+	reasons := []string{}
+
+	if labData.AbnormalFlag == model.FlagCriticalLow || labData.AbnormalFlag == model.FlagCriticalHigh {
+		status = model.VerificationStatusCritical
+		reasons = append(reasons, "critical abnormal flag "+labData.AbnormalFlag)
+	}
+
+	var previous *model.LabData
+	if rules.HasType(model.VerificationRuleTypeDelta) && labData.PatientID != "" {
+		var err error
+		previous, err = s.store.LabDataStore.GetPreviousByPatientIDAndParam(labData.PatientID, labData.Param, labData.CompletedDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range rules {
+		ruleStatus, reason := rule.Check(labData, previous)
+		if ruleStatus == "" {
+			continue
+		}
+		if status != model.VerificationStatusCritical {
+			status = ruleStatus
+		}
+		reasons = append(reasons, reason)
+	}
+
+	labData.VerificationStatus = status
+	labData.VerificationReason = strings.Join(reasons, "; ")
+
+	return nil
+}
+
+// rules gets the verification rules of the lab datas, a service without a store has none
+func (s *VerificationService) rules(labDatas []*model.LabData) (model.VerificationRules, error) {
+	if s == nil || s.store == nil {
+		return nil, nil
+	}
+
+	params := make([]string, 0, len(labDatas))
+	for _, labData := range labDatas {
+		params = append(params, labData.Param)
+	}
+
+	return s.store.VerificationRuleStore.GetByParams(params)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+func TestVerifyLabData(t *testing.T) {
+	low := 2.5
+	high := 20.0
+	rangeRule := &model.VerificationRule{Name: "range", Type: model.VerificationRuleTypeRange, Low: &low, High: &high}
+	criticalRule := &model.VerificationRule{Name: "critical", Type: model.VerificationRuleTypeCritical, Low: &low}
+	flagRule := &model.VerificationRule{Name: "flags", Type: model.VerificationRuleTypeInstrumentFlag, Flags: "W"}
+
+	tests := []struct {
+		name    string
+		labData *model.LabData
+		rules   model.VerificationRules
+		status  string
+		reason  string
+	}{
+		{
+			name:    "no rules",
+			labData: &model.LabData{Result: "5"},
+			status:  model.VerificationStatusAutoVerified,
+		},
+		{
+			name:    "rules passed",
+			labData: &model.LabData{Result: "5"},
+			rules:   model.VerificationRules{rangeRule, criticalRule, flagRule},
+			status:  model.VerificationStatusAutoVerified,
+		},
+		{
+			name:    "held",
+			labData: &model.LabData{Result: "5", InstrumentFlags: "W"},
+			rules:   model.VerificationRules{rangeRule, flagRule},
+			status:  model.VerificationStatusHeld,
+			reason:  "flags: instrument flag W",
+		},
+		{
+			name:    "critical wins over held",
+			labData: &model.LabData{Result: "1", InstrumentFlags: "W"},
+			rules:   model.VerificationRules{criticalRule, rangeRule, flagRule},
+			status:  model.VerificationStatusCritical,
+			reason:  "critical: critical value 1; range: 1 out of range; flags: instrument flag W",
+		},
+		{
+			name:    "critical abnormal flag",
+			labData: &model.LabData{Result: "30", AbnormalFlag: model.FlagCriticalHigh},
+			rules:   model.VerificationRules{rangeRule},
+			status:  model.VerificationStatusCritical,
+			reason:  "critical abnormal flag HH; range: 30 out of range",
+		},
+		{
+			name:    "high abnormal flag",
+			labData: &model.LabData{Result: "7", AbnormalFlag: model.FlagHigh},
+			status:  model.VerificationStatusAutoVerified,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewVerificationService(nil).verifyLabData(test.labData, test.rules)
+			if err != nil {
+				t.Fatalf("verifyLabData() error = %v", err)
+			}
+			if test.labData.VerificationStatus != test.status || test.labData.VerificationReason != test.reason {
+				t.Errorf("verifyLabData() = %q, %q, want %q, %q", test.labData.VerificationStatus, test.labData.VerificationReason, test.status, test.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyLabDatasWithoutStore(t *testing.T) {
+	labDatas := []*model.LabData{{Param: "GLU", Result: "1", AbnormalFlag: model.FlagCriticalLow}, {Param: "GLU", Result: "5"}}
+
+	err := NewVerificationService(nil).VerifyLabDatas(labDatas)
+	if err != nil {
+		t.Fatalf("VerifyLabDatas() error = %v", err)
+	}
+	if labDatas[0].VerificationStatus != model.VerificationStatusCritical || labDatas[1].VerificationStatus != model.VerificationStatusAutoVerified {
+		t.Errorf("VerifyLabDatas() = %q, %q, want critical, auto_verified", labDatas[0].VerificationStatus, labDatas[1].VerificationStatus)
+	}
+}
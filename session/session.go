@@ -0,0 +1,235 @@
+// Package session provides the per-connection session of a device.
+package session
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/driver"
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/services"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
+)
+
+// receiveTimeout is the time after which a started but unfinished message is discarded.
+const receiveTimeout = 30 * time.Second
+
+// State is the protocol state of the session.
+type State int
+
+// Protocol states of the session.
+const (
+	StateIdle State = iota
+	StateReceiving
+	StateSending
+	StateWaitingAck
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateReceiving:
+		return "receiving"
+	case StateSending:
+		return "sending"
+	case StateWaitingAck:
+		return "waiting-ack"
+	}
+
+	return "unknown"
+}
+
+// Session is the long-lived state of a device connection.
+// It is created once per accepted connection and owns the driver instance and the partially received data.
+type Session struct {
+	Log      *log.Logger
+	ConnData *tcp.ConnData
+	Device   *model.Device
+	Driver   driver.Driver
+	LabDatas *services.LabDataService
+	Orders   *services.OrderService
+	PrevData *tcp.PrevData
+
+	store        *store.Store
+	queryClients services.QueryClients
+	conn         *sessionConn
+	state        State
+	timer        *time.Timer
+	mu           sync.Mutex
+	dataMu       sync.Mutex
+}
+
+// NewSession creates a new session for the connection and the device.
+// The host queries of the device are sent through the shared client of its query backend in queryClients.
+func NewSession(logger *log.Logger, store *store.Store, queryClients services.QueryClients, connData *tcp.ConnData, device *model.Device) (*Session, error) {
+	s := &Session{
+		Log:          logger,
+		ConnData:     connData,
+		PrevData:     &tcp.PrevData{},
+		store:        store,
+		queryClients: queryClients,
+	}
+
+	err := s.Rebind(device)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn = &sessionConn{Conn: connData.Conn, session: s}
+	s.timer = time.AfterFunc(receiveTimeout, s.receiveTimedOut)
+
+	return s, nil
+}
+
+// Rebind binds the session to the device, creating the driver of its model.
+// It is used when the device of the connection is known only after its messages identified it,
+// the partially received data is kept as the driver of the same format continues it.
+// The device and the driver are swapped under the data lock, the receive timer reads them concurrently.
+func (s *Session) Rebind(device *model.Device) error {
+	labDatas := services.NewLabDataService(s.store, device.DeviceModelID)
+	orders := services.NewOrderService(s.Log, s.store, device.ID)
+	queryClient := s.queryClients.Get(device.QueryBackend)
+	if queryClient == nil && device.QueryBackend != "" {
+		s.Log.Warn(fmt.Sprintf("the query backend %s of %s is not configured, its host queries are answered from the orders only", device.QueryBackend, device.Name))
+	}
+	deviceQueryService := services.NewDeviceQueryService(queryClient, device.Serial, labDatas.TestCodes, orders)
+
+	deviceDriver, err := driver.NewDriver(device.DeviceModel.Driver, s.Log, s.store, deviceQueryService)
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("failed to create a driver for %s with driver name %s", device.Name, device.DeviceModel.Driver))
+		return err
+	}
+
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+
+	s.Device = device
+	s.Driver = deviceDriver
+	s.LabDatas = labDatas
+	s.Orders = orders
+
+	return nil
+}
+
+// Conn returns the connection the drivers write to.
+// Writes and reads on it move the session to the sending and waiting-ack states.
+func (s *Session) Conn() transport.Conn {
+	return s.conn
+}
+
+// State returns the protocol state of the session.
+func (s *Session) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// setState sets the protocol state of the session.
+func (s *Session) setState(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+}
+
+// RawDatas prepares the received data with the driver and returns the complete raw datas.
+// The data of an unfinished message is kept in the session until the next read.
+func (s *Session) RawDatas(data []byte) ([]string, error) {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+
+	s.timer.Reset(receiveTimeout)
+
+	msg := string(data)
+	for k, v := range s.Driver.DataToBeReplaced() {
+		msg = strings.ReplaceAll(msg, k, v)
+	}
+
+	if s.Driver.ReceivedSimpleACK(msg) {
+		s.setState(StateIdle)
+		return nil, nil
+	}
+
+	msg, err := s.Driver.UnwrapFrames(s.conn, msg, s.PrevData)
+	if err != nil {
+		s.Log.Err(err, "failed to unwrap the frames from "+s.Device.Name)
+		return nil, err
+	}
+
+	err = s.Driver.SendSimpleACK(s.conn)
+	if err != nil {
+		s.Log.Err(err, "failed to send an ACK message to "+s.Device.Name)
+		return nil, err
+	}
+
+	rawDatas := driver.GetRawDatas(s.Driver, msg, s.PrevData)
+
+	if s.PrevData.Started {
+		s.setState(StateReceiving)
+	} else {
+		s.setState(StateIdle)
+	}
+
+	return rawDatas, nil
+}
+
+// PostUnmarshalActions performs the post-unmarshal actions of the driver and returns the session to the idle state.
+func (s *Session) PostUnmarshalActions(data map[string]interface{}) error {
+	defer s.setState(StateIdle)
+
+	return s.Driver.PostUnmarshalActions(s.conn, data)
+}
+
+// Acknowledge acknowledges the processed message with the driver and returns the session to the idle state.
+func (s *Session) Acknowledge(data map[string]interface{}, processErr error) error {
+	defer s.setState(StateIdle)
+
+	return s.Driver.Acknowledge(s.conn, data, processErr)
+}
+
+// Close stops the timers of the session.
+func (s *Session) Close() {
+	s.timer.Stop()
+}
+
+// receiveTimedOut discards the unfinished message when the device stopped sending it.
+func (s *Session) receiveTimedOut() {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+
+	if s.State() != StateReceiving {
+		return
+	}
+
+	s.Log.Warn(fmt.Sprintf("receive timeout from %s, discarding the unfinished message", s.Device.Name))
+	*s.PrevData = tcp.PrevData{}
+	s.setState(StateIdle)
+}
+
+// sessionConn is the connection that tracks the protocol state of the session.
+type sessionConn struct {
+	transport.Conn
+	session *Session
+}
+
+// Write writes to the connection in the sending state.
+func (c *sessionConn) Write(b []byte) (int, error) {
+	c.session.setState(StateSending)
+
+	return c.Conn.Write(b)
+}
+
+// Read reads from the connection in the waiting-ack state.
+func (c *sessionConn) Read(b []byte) (int, error) {
+	c.session.setState(StateWaitingAck)
+
+	return c.Conn.Read(b)
+}
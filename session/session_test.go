@@ -0,0 +1,76 @@
+package session
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/tcp"
+	"gorm.io/gorm"
+)
+
+func newTestDevice(id uint, name string) *model.Device {
+	return &model.Device{
+		Model:       gorm.Model{ID: id},
+		Name:        name,
+		DeviceModel: model.DeviceModel{Driver: "astm"},
+	}
+}
+
+func TestRebindDuringReceiveTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	connData := &tcp.ConnData{Conn: server, ConnString: "pipe", Wg: &sync.WaitGroup{}}
+	s, err := NewSession(&log.Logger{Disabled: true}, nil, nil, connData, newTestDevice(1, "first"))
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.setState(StateReceiving)
+			s.receiveTimedOut()
+		}()
+		go func() {
+			defer wg.Done()
+			err := s.Rebind(newTestDevice(2, "second"))
+			if err != nil {
+				t.Errorf("Rebind() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Device.Name != "second" {
+		t.Errorf("Device = %s, want second", s.Device.Name)
+	}
+	if s.State() != StateIdle {
+		t.Errorf("State() = %s, want idle", s.State())
+	}
+}
+
+func TestRebindUnknownDriverKeepsDevice(t *testing.T) {
+	connData := &tcp.ConnData{ConnString: "none", Wg: &sync.WaitGroup{}}
+	s, err := NewSession(&log.Logger{Disabled: true}, nil, nil, connData, newTestDevice(1, "first"))
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	defer s.Close()
+
+	device := newTestDevice(2, "second")
+	device.DeviceModel.Driver = "unknown"
+	if err := s.Rebind(device); err == nil {
+		t.Fatal("Rebind() error = nil, want an error")
+	}
+	if s.Device.Name != "first" {
+		t.Errorf("Device = %s, want first", s.Device.Name)
+	}
+}
@@ -0,0 +1,144 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/voidmaindev/doctra_lis_middleware/driver/driver_astm"
+)
+
+// maxFrameRetries is the number of attempts to send a frame the device server answers with NAK.
+const maxFrameRetries = 6
+
+// sendASTM sends the records of the message in an ENQ ... EOT session of E1381 frames.
+// A message with a query (Q) record waits for the answer of the device server as the instrument would.
+func (s *Simulator) sendASTM(records []string) error {
+	err := s.write([]byte{enq})
+	if err != nil {
+		return err
+	}
+
+	err = s.expectAck()
+	if err != nil {
+		return fmt.Errorf("establishment: %v", err)
+	}
+
+	for _, frame := range driver_astm.BuildFrames(records) {
+		err = s.sendFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.write([]byte{eot})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if strings.HasPrefix(record, "Q|") {
+			return s.receiveASTM()
+		}
+	}
+
+	return nil
+}
+
+// sendFrame sends the frame and retransmits it while the device server answers with NAK.
+func (s *Simulator) sendFrame(frame string) error {
+	for i := 0; i < maxFrameRetries; i++ {
+		err := s.write([]byte(frame))
+		if err != nil {
+			return err
+		}
+
+		err = s.expectAck()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errNAK) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("frame was not accepted after %d attempts", maxFrameRetries)
+}
+
+// errNAK is returned when the device server answered with NAK.
+var errNAK = errors.New("received NAK")
+
+// expectAck reads the answer of the device server to the ENQ or the frame.
+func (s *Simulator) expectAck() error {
+	b, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	s.logf("< %s", encodeMnemonics([]byte{b}))
+
+	switch b {
+	case ack:
+		return nil
+	case nak:
+		return errNAK
+	}
+
+	return fmt.Errorf("expected ACK, received %s", encodeMnemonics([]byte{b}))
+}
+
+// receiveASTM receives the answer of the device server to a query, acknowledging its frames as the instrument.
+func (s *Simulator) receiveASTM() error {
+	b, err := s.readByte()
+	if err != nil {
+		return fmt.Errorf("no answer to the query: %v", err)
+	}
+	if b != enq {
+		return fmt.Errorf("expected ENQ, received %s", encodeMnemonics([]byte{b}))
+	}
+	s.logf("< [ENQ]")
+
+	err = s.write([]byte{ack})
+	if err != nil {
+		return err
+	}
+
+	var record strings.Builder
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case eot:
+			s.logf("< [EOT]")
+			return nil
+		case stx:
+			rest, err := s.readUntil(lf)
+			if err != nil {
+				return err
+			}
+			frame := string(append([]byte{stx}, rest...))
+			s.logf("< %s", encodeMnemonics([]byte(frame)))
+
+			reply := byte(ack)
+			_, text, _, err := driver_astm.ParseFrame(frame)
+			if err != nil {
+				s.logf("%v", err)
+				reply = nak
+			} else {
+				record.WriteString(text)
+			}
+
+			err = s.write([]byte{reply})
+			if err != nil {
+				return err
+			}
+		}
+
+		if strings.HasSuffix(record.String(), "\r") {
+			s.logf("received record: %s", strings.TrimSuffix(record.String(), "\r"))
+			record.Reset()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sendHL7 sends the segments of the message wrapped in the MLLP blocks and reads the answers of the device server.
+// A QRY^Q02 query is answered with QCK^Q02 and, when the orders are found, DSR^Q03, other messages with one answer.
+func (s *Simulator) sendHL7(segments []string) error {
+	data := append([]byte{vt}, strings.Join(segments, "\r")+"\r"...)
+	data = append(data, fs, cr)
+
+	err := s.write(data)
+	if err != nil {
+		return err
+	}
+
+	for {
+		answer, err := s.readHL7()
+		if err != nil {
+			return fmt.Errorf("no answer: %v", err)
+		}
+
+		if !isHL7Query(segments) || hl7MessageType(answer) != "QCK" || strings.Contains(answer, "QAK|SR|NF") {
+			return nil
+		}
+	}
+}
+
+// readHL7 reads an MLLP message of the device server and returns it without the MLLP blocks.
+func (s *Simulator) readHL7() (string, error) {
+	var msg []byte
+	for len(msg) < 2 || msg[len(msg)-2] != fs {
+		data, err := s.readUntil(cr)
+		if err != nil {
+			return "", err
+		}
+		msg = append(msg, data...)
+	}
+	s.logf("< %s", encodeMnemonics(msg))
+
+	return strings.TrimLeft(string(msg[:len(msg)-2]), string([]byte{vt})), nil
+}
+
+// isHL7Query checks if the message is a QRY^Q02 query.
+func isHL7Query(segments []string) bool {
+	return hl7MessageType(strings.Join(segments, "\r")) == "QRY"
+}
+
+// hl7MessageType returns the message type (MSH-9.1) of the message.
+func hl7MessageType(msg string) string {
+	msh := strings.SplitN(msg, "\r", 2)[0]
+	fields := strings.Split(msh, "|")
+	if len(fields) < 9 {
+		return ""
+	}
+
+	return strings.SplitN(fields[8], "^", 2)[0]
+}
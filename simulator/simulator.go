@@ -0,0 +1,238 @@
+// Package simulator provides the instrument side of the device protocols,
+// it replays the conversations of the fixture files against the device server without an analyzer.
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
+)
+
+// Protocols of the fixtures.
+const (
+	ProtocolASTM   = "astm"
+	ProtocolHL7    = "hl7"
+	ProtocolText   = "text"
+	ProtocolScript = "script"
+)
+
+// Control characters of the device protocols.
+const (
+	stx = 0x02
+	etx = 0x03
+	eot = 0x04
+	enq = 0x05
+	ack = 0x06
+	nak = 0x15
+	etb = 0x17
+	vt  = 0x0b
+	fs  = 0x1c
+	cr  = '\r'
+	lf  = '\n'
+)
+
+// mnemonics are the names of the control characters written in brackets in the fixtures, e.g. "[STX]".
+var mnemonics = map[string]byte{
+	"STX": stx, "ETX": etx, "EOT": eot, "ENQ": enq, "ACK": ack, "NAK": nak,
+	"ETB": etb, "VT": vt, "FS": fs, "CR": cr, "LF": lf,
+}
+
+// Simulator plays the instrument side of a connection to the device server.
+type Simulator struct {
+	conn    transport.Conn
+	reader  *bufio.Reader
+	out     io.Writer
+	timeout time.Duration
+}
+
+// NewSimulator creates a new Simulator writing the conversation to out.
+// The timeout limits every wait for the device server.
+func NewSimulator(conn transport.Conn, out io.Writer, timeout time.Duration) *Simulator {
+	return &Simulator{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		out:     out,
+		timeout: timeout,
+	}
+}
+
+// Run replays the fixture with the protocol.
+//
+// The fixture lines are records (ASTM), segments (HL7) or lines (text), a blank line separates the messages
+// and the lines starting with "#" are comments. The control characters are written as mnemonics, e.g. "[STX]".
+// A script fixture is a raw conversation of lines starting with ">" sent as is and lines starting with "<"
+// expected from the device server, e.g. "< [ACK]".
+func (s *Simulator) Run(protocol string, fixture string) error {
+	switch protocol {
+	case ProtocolASTM:
+		return s.runMessages(parseFixture(fixture), s.sendASTM)
+	case ProtocolHL7:
+		return s.runMessages(parseFixture(fixture), s.sendHL7)
+	case ProtocolText:
+		return s.runMessages(parseFixture(fixture), s.sendText)
+	case ProtocolScript:
+		return s.runScript(fixture)
+	}
+
+	return fmt.Errorf("unknown protocol: %s", protocol)
+}
+
+// runMessages sends the messages one by one.
+func (s *Simulator) runMessages(messages [][]string, send func([]string) error) error {
+	if len(messages) == 0 {
+		return errors.New("fixture has no messages")
+	}
+
+	for i, message := range messages {
+		s.logf("message %d of %d", i+1, len(messages))
+
+		err := send(message)
+		if err != nil {
+			return fmt.Errorf("message %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// sendText sends the lines of the message terminated by CR LF, the text formats are not acknowledged.
+func (s *Simulator) sendText(lines []string) error {
+	return s.write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+}
+
+// runScript replays the raw conversation of the script fixture.
+func (s *Simulator) runScript(fixture string) error {
+	for i, line := range strings.Split(strings.ReplaceAll(fixture, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		data := []byte(decodeMnemonics(strings.TrimSpace(line[1:])))
+
+		var err error
+		switch line[0] {
+		case '>':
+			err = s.write(data)
+		case '<':
+			err = s.expect(data)
+		default:
+			err = errors.New("line does not start with > or <")
+		}
+		if err != nil {
+			return fmt.Errorf("script line %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// write writes the data to the device server.
+func (s *Simulator) write(data []byte) error {
+	s.logf("> %s", encodeMnemonics(data))
+
+	_, err := s.conn.Write(data)
+
+	return err
+}
+
+// expect reads from the device server until the expected data are received.
+func (s *Simulator) expect(expected []byte) error {
+	received := []byte{}
+	for !bytes.HasSuffix(received, expected) {
+		b, err := s.readByte()
+		if err != nil {
+			return fmt.Errorf("expected %s, received %s: %v", encodeMnemonics(expected), encodeMnemonics(received), err)
+		}
+		received = append(received, b)
+	}
+	s.logf("< %s", encodeMnemonics(received))
+
+	return nil
+}
+
+// readByte reads a byte from the device server within the timeout.
+func (s *Simulator) readByte() (byte, error) {
+	s.setDeadline()
+
+	return s.reader.ReadByte()
+}
+
+// readUntil reads from the device server until the delimiter within the timeout.
+func (s *Simulator) readUntil(delimiter byte) ([]byte, error) {
+	s.setDeadline()
+
+	return s.reader.ReadBytes(delimiter)
+}
+
+// setDeadline sets the read deadline of the connections which support it, network connections and pseudo-terminals.
+func (s *Simulator) setDeadline() {
+	if conn, ok := s.conn.(interface{ SetReadDeadline(time.Time) error }); ok && s.timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.timeout))
+	}
+}
+
+// logf writes a line of the conversation.
+func (s *Simulator) logf(format string, args ...interface{}) {
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// parseFixture splits the fixture into messages of lines.
+func parseFixture(fixture string) [][]string {
+	messages := [][]string{}
+	message := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(fixture, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(message) > 0 {
+				messages = append(messages, message)
+				message = []string{}
+			}
+			continue
+		}
+
+		message = append(message, decodeMnemonics(line))
+	}
+	if len(message) > 0 {
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// decodeMnemonics replaces the mnemonics of the control characters with the characters.
+func decodeMnemonics(line string) string {
+	for name, c := range mnemonics {
+		line = strings.ReplaceAll(line, "["+name+"]", string(c))
+	}
+
+	return line
+}
+
+// encodeMnemonics replaces the control characters with their mnemonics for printing.
+func encodeMnemonics(data []byte) string {
+	names := map[byte]string{}
+	for name, c := range mnemonics {
+		names[c] = name
+	}
+
+	var sb strings.Builder
+	for _, b := range data {
+		if name, ok := names[b]; ok {
+			sb.WriteString("[" + name + "]")
+			continue
+		}
+		sb.WriteByte(b)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// CanonicalUnitStore is the store for the CanonicalUnit model.
+type CanonicalUnitStore struct {
+	db *gorm.DB
+}
+
+// NewCanonicalUnitStore creates a new CanonicalUnitStore.
+func NewCanonicalUnitStore(db *gorm.DB) (*CanonicalUnitStore, error) {
+	store := &CanonicalUnitStore{db: db}
+	err := store.db.AutoMigrate(&model.CanonicalUnit{})
+	if err != nil {
+		return nil, errors.New("failed to migrate CanonicalUnit model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new canonical unit.
+func (s *CanonicalUnitStore) Create(canonicalUnit *model.CanonicalUnit) error {
+	err := s.db.Create(canonicalUnit).Error
+	if err != nil {
+		return fmt.Errorf("failed to create canonical unit for param: %v", canonicalUnit.Param)
+	}
+
+	return nil
+}
+
+// GetByID gets a canonical unit by ID.
+func (s *CanonicalUnitStore) GetByID(id uint) (*model.CanonicalUnit, error) {
+	canonicalUnit := &model.CanonicalUnit{}
+	err := s.db.First(canonicalUnit, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canonical unit by ID: %v", id)
+	}
+
+	return canonicalUnit, nil
+}
+
+// GetByParams gets the canonical units of the params.
+func (s *CanonicalUnitStore) GetByParams(params []string) (model.CanonicalUnits, error) {
+	canonicalUnits := model.CanonicalUnits{}
+	err := s.db.Where("param IN ?", params).Order("id").Find(&canonicalUnits).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canonical units by params: %v", params)
+	}
+
+	return canonicalUnits, nil
+}
+
+// GetAll gets all canonical units.
+func (s *CanonicalUnitStore) GetAll() (model.CanonicalUnits, error) {
+	canonicalUnits := model.CanonicalUnits{}
+	err := s.db.Order("id").Find(&canonicalUnits).Error
+	if err != nil {
+		return nil, errors.New("failed to get all canonical units")
+	}
+
+	return canonicalUnits, nil
+}
+
+// canonicalUnitListSchema is the list schema of the canonical units.
+var canonicalUnitListSchema = listSchema{
+	sortFields:  []string{"param"},
+	paramColumn: "param",
+}
+
+// List gets a page of the canonical units of the query.
+func (s *CanonicalUnitStore) List(query *ListQuery) ([]*model.CanonicalUnit, *Page, error) {
+	canonicalUnits := []*model.CanonicalUnit{}
+	page, err := list(s.db, canonicalUnitListSchema, query, &canonicalUnits)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list canonical units")
+	}
+
+	return canonicalUnits, page, nil
+}
+
+// Update updates a canonical unit.
+func (s *CanonicalUnitStore) Update(canonicalUnit *model.CanonicalUnit) error {
+	err := s.db.Save(canonicalUnit).Error
+	if err != nil {
+		return fmt.Errorf("failed to update canonical unit: %v", canonicalUnit.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a canonical unit.
+func (s *CanonicalUnitStore) Delete(canonicalUnit *model.CanonicalUnit) error {
+	err := s.db.Delete(canonicalUnit).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete canonical unit: %v", canonicalUnit.ID)
+	}
+
+	return nil
+}
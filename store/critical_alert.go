@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// CriticalAlertStore is the store for the CriticalAlert model.
+type CriticalAlertStore struct {
+	db *gorm.DB
+}
+
+// NewCriticalAlertStore creates a new CriticalAlertStore.
+func NewCriticalAlertStore(db *gorm.DB) (*CriticalAlertStore, error) {
+	store := &CriticalAlertStore{db: db}
+	err := store.db.AutoMigrate(&model.CriticalAlert{})
+	if err != nil {
+		return nil, errors.New("failed to migrate CriticalAlert model")
+	}
+
+	return store, nil
+}
+
+// CreateAll creates the critical alerts.
+func (s *CriticalAlertStore) CreateAll(alerts []*model.CriticalAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	err := s.db.Create(alerts).Error
+	if err != nil {
+		return errors.New("failed to create critical alerts")
+	}
+
+	return nil
+}
+
+// GetByID gets a critical alert by ID.
+func (s *CriticalAlertStore) GetByID(id uint) (*model.CriticalAlert, error) {
+	alert := &model.CriticalAlert{}
+	err := s.db.First(alert, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get critical alert by ID: %v", id)
+	}
+
+	return alert, nil
+}
+
+// GetUnacknowledged gets a page of the critical alerts of the query which are not acknowledged yet.
+func (s *CriticalAlertStore) GetUnacknowledged(query *ListQuery) ([]*model.CriticalAlert, *Page, error) {
+	alerts := []*model.CriticalAlert{}
+	page, err := list(s.db.Where("acknowledged_at IS NULL"), criticalAlertListSchema, query, &alerts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to get unacknowledged critical alerts")
+	}
+
+	return alerts, page, nil
+}
+
+// GetDueForNotification gets the critical alerts waiting to be sent to the webhook whose next notification time has come.
+func (s *CriticalAlertStore) GetDueForNotification(now time.Time, limit int) ([]*model.CriticalAlert, error) {
+	alerts := []*model.CriticalAlert{}
+	err := s.db.Where("notified_at IS NULL").
+		Where("next_notification_at IS NULL OR next_notification_at <= ?", now).
+		Order("id").Limit(limit).Find(&alerts).Error
+	if err != nil {
+		return nil, errors.New("failed to get critical alerts due for notification")
+	}
+
+	return alerts, nil
+}
+
+// GetAll gets all critical alerts.
+func (s *CriticalAlertStore) GetAll() ([]*model.CriticalAlert, error) {
+	alerts := []*model.CriticalAlert{}
+	err := s.db.Order("id").Find(&alerts).Error
+	if err != nil {
+		return nil, errors.New("failed to get all critical alerts")
+	}
+
+	return alerts, nil
+}
+
+// criticalAlertListSchema is the list schema of the critical alerts.
+var criticalAlertListSchema = listSchema{
+	sortFields:     []string{"device_id", "barcode", "param", "notified_at", "acknowledged_at"},
+	dateFields:     []string{"notified_at", "acknowledged_at"},
+	deviceIDColumn: "device_id",
+	barcodeColumn:  "barcode",
+	paramColumn:    "param",
+}
+
+// List gets a page of the critical alerts of the query.
+func (s *CriticalAlertStore) List(query *ListQuery) ([]*model.CriticalAlert, *Page, error) {
+	alerts := []*model.CriticalAlert{}
+	page, err := list(s.db, criticalAlertListSchema, query, &alerts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list critical alerts")
+	}
+
+	return alerts, page, nil
+}
+
+// Update updates a critical alert.
+func (s *CriticalAlertStore) Update(alert *model.CriticalAlert) error {
+	err := s.db.Save(alert).Error
+	if err != nil {
+		return fmt.Errorf("failed to update critical alert: %v", alert.ID)
+	}
+
+	return nil
+}
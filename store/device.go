@@ -3,6 +3,7 @@ package store
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/voidmaindev/doctra_lis_middleware/model"
 	"gorm.io/gorm"
@@ -56,10 +57,18 @@ func (s *DeviceStore) GetBySerial(serial string) (*model.Device, error) {
 	return device, nil
 }
 
-// GetByNetworkAddress gets a device by network address.
-func (s *DeviceStore) GetByNetAddress(networkAddress string) (*model.Device, error) {
+// GetByNetAddressAndIdentifyBy gets a device by network address among the devices with one of the identification modes.
+// The empty identification mode is treated as identification by IP.
+func (s *DeviceStore) GetByNetAddressAndIdentifyBy(networkAddress string, identifyBy ...string) (*model.Device, error) {
 	device := &model.Device{}
-	err := s.db.Preload("DeviceModel").Where("net_address = ?", networkAddress).First(device).Error
+	query := s.db.Preload("DeviceModel").Where("net_address = ?", networkAddress)
+	if slices.Contains(identifyBy, model.IdentifyByIP) {
+		query = query.Where("identify_by IN ? OR identify_by IS NULL", append(slices.Clone(identifyBy), ""))
+	} else {
+		query = query.Where("identify_by IN ?", identifyBy)
+	}
+
+	err := query.First(device).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get device by network address: %v", networkAddress)
 	}
@@ -67,6 +76,28 @@ func (s *DeviceStore) GetByNetAddress(networkAddress string) (*model.Device, err
 	return device, nil
 }
 
+// GetByListenPort gets a device identified by the local port it connects to.
+func (s *DeviceStore) GetByListenPort(port string) (*model.Device, error) {
+	device := &model.Device{}
+	err := s.db.Preload("DeviceModel").Where("identify_by = ? AND listen_port = ?", model.IdentifyByListenPort, port).First(device).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device by listen port: %v", port)
+	}
+
+	return device, nil
+}
+
+// GetBySenderID gets a device identified by the sender ID of its messages.
+func (s *DeviceStore) GetBySenderID(senderID string) (*model.Device, error) {
+	device := &model.Device{}
+	err := s.db.Preload("DeviceModel").Where("identify_by = ? AND sender_id = ?", model.IdentifyBySenderID, senderID).First(device).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device by sender ID: %v", senderID)
+	}
+
+	return device, nil
+}
+
 // GetByModelID gets all devices by model ID.
 func (s *DeviceStore) GetByModelID(modelID uint) ([]model.Device, error) {
 	devices := []model.Device{}
@@ -89,6 +120,26 @@ func (s *DeviceStore) GetAll() ([]model.Device, error) {
 	return devices, nil
 }
 
+// deviceListSchema is the list schema of the devices.
+var deviceListSchema = listSchema{
+	sortFields: []string{"name", "device_model_id"},
+	preloads:   []string{"DeviceModel"},
+}
+
+// List gets a page of the devices of the query.
+func (s *DeviceStore) List(query *ListQuery) ([]model.Device, *Page, error) {
+	devices := []model.Device{}
+	page, err := list(s.db, deviceListSchema, query, &devices)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list devices")
+	}
+
+	return devices, page, nil
+}
+
 // Update updates a device.
 func (s *DeviceStore) Update(device *model.Device) error {
 	err := s.db.Save(device).Error
@@ -56,6 +56,25 @@ func (s *DeviceModelStore) GetAll() ([]model.DeviceModel, error) {
 	return deviceModels, nil
 }
 
+// deviceModelListSchema is the list schema of the device models.
+var deviceModelListSchema = listSchema{
+	sortFields: []string{"name"},
+}
+
+// List gets a page of the device models of the query.
+func (s *DeviceModelStore) List(query *ListQuery) ([]model.DeviceModel, *Page, error) {
+	deviceModels := []model.DeviceModel{}
+	page, err := list(s.db, deviceModelListSchema, query, &deviceModels)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list device models")
+	}
+
+	return deviceModels, page, nil
+}
+
 // Update updates a device model.
 func (s *DeviceModelStore) Update(deviceModel *model.DeviceModel) error {
 	err := s.db.Save(deviceModel).Error
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// DeviceStatusStore is the store for the DeviceStatus model.
+type DeviceStatusStore struct {
+	db *gorm.DB
+}
+
+// NewDeviceStatusStore creates a new DeviceStatusStore.
+func NewDeviceStatusStore(db *gorm.DB) (*DeviceStatusStore, error) {
+	store := &DeviceStatusStore{db: db}
+	err := store.db.AutoMigrate(&model.DeviceStatus{})
+	if err != nil {
+		return nil, errors.New("failed to migrate DeviceStatus model")
+	}
+
+	return store, nil
+}
+
+// Save creates or updates the device status.
+func (s *DeviceStatusStore) Save(status *model.DeviceStatus) error {
+	err := s.db.Save(status).Error
+	if err != nil {
+		return fmt.Errorf("failed to save device status: %v", status.DeviceID)
+	}
+
+	return nil
+}
+
+// GetByDeviceID gets the status of the device, an empty status if the device never connected.
+func (s *DeviceStatusStore) GetByDeviceID(deviceID uint) (*model.DeviceStatus, error) {
+	status := &model.DeviceStatus{}
+	err := s.db.Where("device_id = ?", deviceID).First(status).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.DeviceStatus{DeviceID: deviceID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device status by device ID: %v", deviceID)
+	}
+
+	return status, nil
+}
+
+// GetAll gets the statuses of all the devices which ever connected.
+func (s *DeviceStatusStore) GetAll() ([]*model.DeviceStatus, error) {
+	statuses := []*model.DeviceStatus{}
+	err := s.db.Order("device_id").Find(&statuses).Error
+	if err != nil {
+		return nil, errors.New("failed to get device statuses")
+	}
+
+	return statuses, nil
+}
+
+// DisconnectAll marks the connected devices disconnected at the time, their connections did not survive a restart of the device server.
+func (s *DeviceStatusStore) DisconnectAll(at time.Time) error {
+	err := s.db.Model(&model.DeviceStatus{}).Where("connected = ?", true).
+		Updates(map[string]interface{}{"connected": false, "disconnected_at": at}).Error
+	if err != nil {
+		return errors.New("failed to disconnect device statuses")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// EventStore is the store for the Event model, the outbox of the events of the device server.
+type EventStore struct {
+	db *gorm.DB
+}
+
+// NewEventStore creates a new EventStore.
+func NewEventStore(db *gorm.DB) (*EventStore, error) {
+	store := &EventStore{db: db}
+	err := store.db.AutoMigrate(&model.Event{})
+	if err != nil {
+		return nil, errors.New("failed to migrate Event model")
+	}
+
+	err = migrateStreamLock(store.db, streamEvents)
+	if err != nil {
+		return nil, errors.New("failed to migrate the lock of the events")
+	}
+
+	return store, nil
+}
+
+// CreateAll creates the events under the lock of their stream, so their IDs are committed in order for the readers.
+func (s *EventStore) CreateAll(events []*model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := lockStream(tx, streamEvents)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(events).Error
+	})
+	if err != nil {
+		return errors.New("failed to create events")
+	}
+
+	return nil
+}
+
+// GetAfter gets the events after the ID in the order of their IDs.
+// The events are created under the lock of their stream, an event after the ID cannot be committed after they are read.
+func (s *EventStore) GetAfter(id uint, limit int) ([]*model.Event, error) {
+	events := []*model.Event{}
+	err := s.db.Where("id > ?", id).Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, errors.New("failed to get events")
+	}
+
+	return events, nil
+}
+
+// GetLastID gets the ID of the last event, 0 if there are none.
+func (s *EventStore) GetLastID() (uint, error) {
+	var id uint
+	err := s.db.Model(&model.Event{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	if err != nil {
+		return 0, errors.New("failed to get the last event ID")
+	}
+
+	return id, nil
+}
+
+// DeleteBefore deletes the events created before the time.
+func (s *EventStore) DeleteBefore(before time.Time) error {
+	err := s.db.Where("created_at < ?", before).Delete(&model.Event{}).Error
+	if err != nil {
+		return errors.New("failed to delete events")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+)
+
+func TestEventStoreCreateAllLocksStream(t *testing.T) {
+	db, statements := newDryRunDB(t)
+	s := &EventStore{db: db}
+
+	err := s.CreateAll([]*model.Event{{Type: model.EventTypeLabData}, {Type: model.EventTypeRawData}})
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+
+	// the lock is taken before the IDs of the events, they are committed in their order
+	if len(*statements) != 2 {
+		t.Fatalf("statements = %q, want the lock and the insert", *statements)
+	}
+	lock, insert := (*statements)[0], (*statements)[1]
+	if !strings.HasPrefix(lock, "UPDATE") || !strings.Contains(lock, "stream_locks") {
+		t.Errorf("first statement = %s, want the update of the stream lock", lock)
+	}
+	if !strings.HasPrefix(insert, "INSERT") || !strings.Contains(insert, "events") {
+		t.Errorf("second statement = %s, want the insert of the events", insert)
+	}
+}
+
+func TestEventStoreCreateAllWithoutEvents(t *testing.T) {
+	db, statements := newDryRunDB(t)
+	s := &EventStore{db: db}
+
+	err := s.CreateAll(nil)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+	if len(*statements) != 0 {
+		t.Errorf("statements = %q, want none without events", *statements)
+	}
+}
+
+func TestEventStoreGetAfterReadsCommittedEvents(t *testing.T) {
+	db, statements := newDryRunDB(t)
+	s := &EventStore{db: db}
+
+	_, err := s.GetAfter(10, 100)
+	if err != nil {
+		t.Fatalf("GetAfter() error = %v", err)
+	}
+
+	// the committed events are read at once, without waiting for them to age
+	if len(*statements) != 1 || strings.Contains((*statements)[0], "created_at") {
+		t.Errorf("statements = %q, want a query by the ID only", *statements)
+	}
+}
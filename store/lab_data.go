@@ -3,12 +3,14 @@ package store
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/voidmaindev/doctra_lis_middleware/model"
 	"gorm.io/gorm"
 )
 
 // LabDataStore is the store for the LabData model.
+// Every change of a lab data is recorded as a LabDataChange in the same transaction for the change feed.
 type LabDataStore struct {
 	db *gorm.DB
 }
@@ -21,12 +23,29 @@ func NewLabDataStore(db *gorm.DB) (*LabDataStore, error) {
 		return nil, errors.New("failed to migrate LabData model")
 	}
 
+	err = store.db.AutoMigrate(&model.LabDataChange{})
+	if err != nil {
+		return nil, errors.New("failed to migrate LabDataChange model")
+	}
+
+	err = migrateStreamLock(store.db, streamLabDataChanges)
+	if err != nil {
+		return nil, errors.New("failed to migrate the lock of the lab data changes")
+	}
+
 	return store, nil
 }
 
 // Create creates a new lab data.
 func (s *LabDataStore) Create(labData *model.LabData) error {
-	err := s.db.Create(labData).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(labData).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, model.LabDataChangeCreated, labData.ID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create lab data for device: %v and barcode: %v", labData.DeviceID, labData.Barcode)
 	}
@@ -34,6 +53,75 @@ func (s *LabDataStore) Create(labData *model.LabData) error {
 	return nil
 }
 
+// CreateAll creates the lab datas and the alerts of the critical ones in a single transaction.
+func (s *LabDataStore) CreateAll(labDatas []*model.LabData) error {
+	if len(labDatas) == 0 {
+		return nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(labDatas).Error
+		if err != nil {
+			return err
+		}
+
+		err = recordLabDataChanges(tx, model.LabDataChangeCreated, labDataIDs(labDatas)...)
+		if err != nil {
+			return err
+		}
+
+		alerts := model.NewCriticalAlerts(labDatas)
+		if len(alerts) == 0 {
+			return nil
+		}
+
+		return tx.Create(alerts).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create lab data for device: %v and barcode: %v", labDatas[0].DeviceID, labDatas[0].Barcode)
+	}
+
+	return nil
+}
+
+// ReplaceByRawDataID replaces the lab datas of the raw data with the new ones in a single transaction.
+// No critical alerts are created, they were created with the replaced lab datas.
+func (s *LabDataStore) ReplaceByRawDataID(rawDataID uint, labDatas []*model.LabData) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var deletedIDs []uint
+		err := tx.Model(&model.LabData{}).Where("raw_data_id = ?", rawDataID).Pluck("id", &deletedIDs).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("raw_data_id = ?", rawDataID).Delete(&model.LabData{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = recordLabDataChanges(tx, model.LabDataChangeDeleted, deletedIDs...)
+		if err != nil {
+			return err
+		}
+
+		if len(labDatas) == 0 {
+			return nil
+		}
+
+		err = tx.Create(labDatas).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, model.LabDataChangeCreated, labDataIDs(labDatas)...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace lab data of raw data: %v", rawDataID)
+	}
+
+	return nil
+}
+
 // CreateOrUpdate creates or updates a lab data.
 func (s *LabDataStore) CreateOrUpdate(labData *model.LabData) error {
 	labDataOld, err := s.GetByDeviceIDAndBarcodeAndParam(labData.DeviceID, labData.Barcode, labData.Param)
@@ -50,7 +138,19 @@ func (s *LabDataStore) CreateOrUpdate(labData *model.LabData) error {
 		return nil
 	}
 
-	err = s.db.Save(labData).Error
+	operation := model.LabDataChangeUpdated
+	if labData.ID == 0 {
+		operation = model.LabDataChangeCreated
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(labData).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, operation, labData.ID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create or update lab data for device: %v and barcode: %v", labData.DeviceID, labData.Barcode)
 	}
@@ -72,7 +172,7 @@ func (s *LabDataStore) GetByID(id uint) (*model.LabData, error) {
 // GetByBarcode gets a lab data by barcode.
 func (s *LabDataStore) GetByBarcode(barcode string) ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Where("barcode = ?", barcode).Find(&labData).Error
+	err := s.db.Where("barcode = ?", barcode).Order("id").Find(&labData).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab data by barcode: %v", barcode)
 	}
@@ -83,7 +183,7 @@ func (s *LabDataStore) GetByBarcode(barcode string) ([]*model.LabData, error) {
 // GetByDeviceID gets lab data by device ID.
 func (s *LabDataStore) GetByDeviceID(deviceID uint) ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Where("device_id = ?", deviceID).Find(&labData).Order("ID").Error
+	err := s.db.Where("device_id = ?", deviceID).Order("id").Find(&labData).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab data by device ID: %v", deviceID)
 	}
@@ -94,7 +194,7 @@ func (s *LabDataStore) GetByDeviceID(deviceID uint) ([]*model.LabData, error) {
 // GetByDeviceIDAndBarcode gets a lab data by device ID and barcode.
 func (s *LabDataStore) GetByDeviceIDAndBarcode(deviceID uint, barcode string) ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Where("device_id = ? AND barcode = ?", deviceID, barcode).Find(&labData).Order("ID").Error
+	err := s.db.Where("device_id = ? AND barcode = ?", deviceID, barcode).Order("id").Find(&labData).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab data by device ID: %v and barcode: %v", deviceID, barcode)
 	}
@@ -116,7 +216,7 @@ func (s *LabDataStore) GetByDeviceIDAndBarcodeAndParam(deviceID uint, barcode, p
 // GetBySerial gets a lab data by serial.
 func (s *LabDataStore) GetBySerial(serial string) ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Joins("JOIN devices ON lab_data.device_id = devices.id").Where("devices.serial = ?", serial).Find(&labData).Order("ID").Error
+	err := s.db.Joins("JOIN devices ON lab_data.device_id = devices.id").Where("devices.serial = ?", serial).Order("lab_data.id").Find(&labData).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab data by serial: %v", serial)
 	}
@@ -127,7 +227,7 @@ func (s *LabDataStore) GetBySerial(serial string) ([]*model.LabData, error) {
 // GetBySerialAndBarcode gets a lab data by serial and barcode.
 func (s *LabDataStore) GetBySerialAndBarcode(serial, barcode string) ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Joins("JOIN devices ON lab_data.device_id = devices.id").Where("devices.serial = ? AND barcode = ?", serial, barcode).Find(&labData).Order("ID").Error
+	err := s.db.Joins("JOIN devices ON lab_data.device_id = devices.id").Where("devices.serial = ? AND lab_data.barcode = ?", serial, barcode).Order("lab_data.id").Find(&labData).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab data by serial: %v and barcode: %v", serial, barcode)
 	}
@@ -135,10 +235,39 @@ func (s *LabDataStore) GetBySerialAndBarcode(serial, barcode string) ([]*model.L
 	return labData, nil
 }
 
+// GetByVerificationStatus gets a page of the lab data of the query with the verification status.
+func (s *LabDataStore) GetByVerificationStatus(status string, query *ListQuery) ([]*model.LabData, *Page, error) {
+	labData := []*model.LabData{}
+	page, err := list(s.db.Where("verification_status = ?", status), labDataListSchema, query, &labData)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to get lab data by verification status: %v", status)
+	}
+
+	return labData, page, nil
+}
+
+// GetPreviousByPatientIDAndParam gets the last lab data of the patient and the param completed before the date, nil if there is none.
+func (s *LabDataStore) GetPreviousByPatientIDAndParam(patientID, param string, before time.Time) (*model.LabData, error) {
+	labData := []*model.LabData{}
+	err := s.db.Where("patient_id = ? AND param = ? AND completed_date < ?", patientID, param, before).
+		Order("completed_date DESC").Limit(1).Find(&labData).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous lab data by patient ID: %v and param: %v", patientID, param)
+	}
+	if len(labData) == 0 {
+		return nil, nil
+	}
+
+	return labData[0], nil
+}
+
 // GetAll gets all lab data.
 func (s *LabDataStore) GetAll() ([]*model.LabData, error) {
 	labData := []*model.LabData{}
-	err := s.db.Find(&labData).Order("ID").Error
+	err := s.db.Order("id").Find(&labData).Error
 	if err != nil {
 		return nil, errors.New("failed to get all lab data")
 	}
@@ -146,9 +275,75 @@ func (s *LabDataStore) GetAll() ([]*model.LabData, error) {
 	return labData, nil
 }
 
+// labDataListSchema is the list schema of the lab data.
+var labDataListSchema = listSchema{
+	sortFields:     []string{"completed_date", "device_id", "barcode", "param"},
+	dateFields:     []string{"completed_date"},
+	deviceIDColumn: "device_id",
+	barcodeColumn:  "barcode",
+	paramColumn:    "param",
+}
+
+// List gets a page of the lab data of the query.
+func (s *LabDataStore) List(query *ListQuery) ([]*model.LabData, *Page, error) {
+	labData := []*model.LabData{}
+	page, err := list(s.db, labDataListSchema, query, &labData)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list lab data")
+	}
+
+	return labData, page, nil
+}
+
+// GetDueForDelivery gets the lab data waiting to be pushed to the HIS whose next delivery time has come.
+func (s *LabDataStore) GetDueForDelivery(now time.Time, limit int) ([]*model.LabData, error) {
+	labData := []*model.LabData{}
+	err := s.db.Where("delivery_status IN ?", []string{model.DeliveryStatusPending, model.DeliveryStatusFailed}).
+		Where("next_delivery_at IS NULL OR next_delivery_at <= ?", now).
+		Order("id").Limit(limit).Find(&labData).Error
+	if err != nil {
+		return nil, errors.New("failed to get lab data due for delivery")
+	}
+
+	return labData, nil
+}
+
+// UpdateDelivery updates the delivery state of the lab data by IDs.
+func (s *LabDataStore) UpdateDelivery(ids []uint, status string, attempts uint, deliveryError string, nextDeliveryAt, deliveredAt *time.Time) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.LabData{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"delivery_status":   status,
+			"delivery_attempts": attempts,
+			"delivery_error":    deliveryError,
+			"next_delivery_at":  nextDeliveryAt,
+			"delivered_at":      deliveredAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, model.LabDataChangeUpdated, ids...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update delivery of lab data: %v", ids)
+	}
+
+	return nil
+}
+
 // Update updates a lab data.
 func (s *LabDataStore) Update(labData *model.LabData) error {
-	err := s.db.Save(labData).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(labData).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, model.LabDataChangeUpdated, labData.ID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update lab data: %v", labData.ID)
 	}
@@ -158,10 +353,97 @@ func (s *LabDataStore) Update(labData *model.LabData) error {
 
 // Delete deletes a lab data.
 func (s *LabDataStore) Delete(labData *model.LabData) error {
-	err := s.db.Delete(labData).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(labData).Error
+		if err != nil {
+			return err
+		}
+
+		return recordLabDataChanges(tx, model.LabDataChangeDeleted, labData.ID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete lab data: %v", labData.ID)
 	}
 
 	return nil
 }
+
+// GetChanges gets the changes of the lab datas after the cursor with the current state of the lab datas, the deleted ones included.
+// A lab data changed several times in the page is returned once with its last change.
+// The changes are recorded under the lock of the stream, a change after the cursor cannot be committed after the page is read.
+func (s *LabDataStore) GetChanges(since uint, limit int) (*model.LabDataChangeFeed, error) {
+	changes := []*model.LabDataChange{}
+	err := s.db.Where("id > ?", since).Order("id").Limit(limit).Find(&changes).Error
+	if err != nil {
+		return nil, errors.New("failed to get lab data changes")
+	}
+
+	feed := &model.LabDataChangeFeed{
+		Changes:    []*model.LabDataChange{},
+		NextCursor: since,
+		HasMore:    len(changes) == limit,
+	}
+	if len(changes) == 0 {
+		return feed, nil
+	}
+	feed.NextCursor = changes[len(changes)-1].ID
+
+	lastChanges := map[uint]*model.LabDataChange{}
+	ids := []uint{}
+	for _, change := range changes {
+		if _, ok := lastChanges[change.LabDataID]; !ok {
+			ids = append(ids, change.LabDataID)
+		}
+		lastChanges[change.LabDataID] = change
+	}
+
+	labDatas := []*model.LabData{}
+	err = s.db.Unscoped().Where("id IN ?", ids).Find(&labDatas).Error
+	if err != nil {
+		return nil, errors.New("failed to get lab data of the changes")
+	}
+	for _, labData := range labDatas {
+		lastChanges[labData.ID].LabData = labData
+	}
+
+	for _, change := range changes {
+		if lastChanges[change.LabDataID] == change {
+			feed.Changes = append(feed.Changes, change)
+		}
+	}
+
+	return feed, nil
+}
+
+// recordLabDataChanges records the changes of the lab datas by IDs in the transaction.
+// The stream of the changes is locked first, so their IDs are committed in order for the change feed.
+func recordLabDataChanges(tx *gorm.DB, operation string, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := lockStream(tx, streamLabDataChanges)
+	if err != nil {
+		return err
+	}
+
+	changes := make([]*model.LabDataChange, 0, len(ids))
+	for _, id := range ids {
+		changes = append(changes, &model.LabDataChange{
+			LabDataID: id,
+			Operation: operation,
+		})
+	}
+
+	return tx.Create(changes).Error
+}
+
+// labDataIDs returns the IDs of the lab datas.
+func labDataIDs(labDatas []*model.LabData) []uint {
+	ids := make([]uint, 0, len(labDatas))
+	for _, labData := range labDatas {
+		ids = append(ids, labData.ID)
+	}
+
+	return ids
+}
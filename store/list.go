@@ -0,0 +1,187 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limits of the list queries.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// ErrInvalidListQuery is returned when the list query asks for a sort field, a date field or a filter the table does not support.
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// ListQuery represents the pagination, the filters and the sort of a list query.
+// The rows are paged by Offset or, when they are sorted by ID, by Cursor, the ID of the last row of the previous page.
+// From (inclusive) and To (exclusive) filter DateField, BarcodePrefix filters the barcodes starting with it.
+// The zero filters are not applied.
+type ListQuery struct {
+	Limit         int
+	Offset        int
+	Cursor        uint
+	SortField     string
+	SortDesc      bool
+	DateField     string
+	From          *time.Time
+	To            *time.Time
+	DeviceID      uint
+	BarcodePrefix string
+	Param         string
+	Processed     *bool
+}
+
+// Page represents a page of the rows of a list query.
+// Total is the count of the rows matching the filters, NextCursor the cursor of the next page when the rows are sorted by ID and there may be more.
+type Page struct {
+	Total      int64 `json:"total"`
+	Limit      int   `json:"limit"`
+	Offset     int   `json:"offset"`
+	NextCursor uint  `json:"next_cursor,omitempty"`
+}
+
+// listSchema represents the sort fields, the date fields and the filter columns a table supports, the filters with an empty column are not supported.
+// The id, created_at and updated_at fields are supported by every table.
+type listSchema struct {
+	sortFields      []string
+	dateFields      []string
+	deviceIDColumn  string
+	barcodeColumn   string
+	paramColumn     string
+	processedColumn string
+	preloads        []string
+}
+
+// defaultListFields are the sort and date fields of every table.
+var defaultListFields = []string{"id", "created_at", "updated_at"}
+
+// list finds a page of the rows of the query into dest, a pointer to a slice of the models.
+func list(db *gorm.DB, schema listSchema, query *ListQuery, dest interface{}) (*Page, error) {
+	if query == nil {
+		query = &ListQuery{}
+	}
+
+	filtered, err := query.filter(db.Model(dest), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	if page.Limit <= 0 {
+		page.Limit = DefaultListLimit
+	}
+	page.Limit = min(page.Limit, MaxListLimit)
+
+	sortField := query.SortField
+	if sortField == "" {
+		sortField = "id"
+	}
+	if !slices.Contains(defaultListFields, sortField) && !slices.Contains(schema.sortFields, sortField) {
+		return nil, fmt.Errorf("%w: unknown sort field %s", ErrInvalidListQuery, sortField)
+	}
+
+	if query.Cursor != 0 && (sortField != "id" || query.Offset != 0) {
+		return nil, fmt.Errorf("%w: the cursor pages the rows sorted by id only and without an offset", ErrInvalidListQuery)
+	}
+
+	err = filtered.Session(&gorm.Session{}).Count(&page.Total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	paged := filtered.Session(&gorm.Session{})
+	if query.Cursor != 0 {
+		if query.SortDesc {
+			paged = paged.Where("id < ?", query.Cursor)
+		} else {
+			paged = paged.Where("id > ?", query.Cursor)
+		}
+	}
+
+	direction := ""
+	if query.SortDesc {
+		direction = " desc"
+	}
+	order := sortField + direction
+	if sortField != "id" {
+		order += ", id" + direction
+	}
+
+	paged = paged.Order(order).Limit(page.Limit).Offset(query.Offset)
+	for _, preload := range schema.preloads {
+		paged = paged.Preload(preload)
+	}
+
+	err = paged.Find(dest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if sortField == "id" && query.Offset == 0 && rows.Len() == page.Limit {
+		page.NextCursor = uint(reflect.Indirect(rows.Index(rows.Len() - 1)).FieldByName("ID").Uint())
+	}
+
+	return page, nil
+}
+
+// filter applies the filters of the query supported by the schema.
+func (q *ListQuery) filter(db *gorm.DB, schema listSchema) (*gorm.DB, error) {
+	if q.From != nil || q.To != nil {
+		dateField := q.DateField
+		if dateField == "" {
+			dateField = "created_at"
+		}
+		if !slices.Contains(defaultListFields, dateField) && !slices.Contains(schema.dateFields, dateField) {
+			return nil, fmt.Errorf("%w: unknown date field %s", ErrInvalidListQuery, dateField)
+		}
+
+		if q.From != nil {
+			db = db.Where(dateField+" >= ?", *q.From)
+		}
+		if q.To != nil {
+			db = db.Where(dateField+" < ?", *q.To)
+		}
+	}
+
+	filters := []struct {
+		name   string
+		set    bool
+		column string
+		query  string
+		value  interface{}
+	}{
+		{"device_id", q.DeviceID != 0, schema.deviceIDColumn, " = ?", q.DeviceID},
+		{"barcode", q.BarcodePrefix != "", schema.barcodeColumn, " LIKE ? ESCAPE '!'", escapeLike(q.BarcodePrefix) + "%"},
+		{"param", q.Param != "", schema.paramColumn, " = ?", q.Param},
+		{"processed", q.Processed != nil, schema.processedColumn, " = ?", q.Processed},
+	}
+	for _, filter := range filters {
+		if !filter.set {
+			continue
+		}
+		if filter.column == "" {
+			return nil, fmt.Errorf("%w: unsupported filter %s", ErrInvalidListQuery, filter.name)
+		}
+
+		db = db.Where(filter.column+filter.query, filter.value)
+	}
+
+	return db, nil
+}
+
+// escapeLike escapes the wildcards of the LIKE pattern with "!", it is not an escape character of the string literals of any of the databases.
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![").Replace(value)
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestLabDataStore creates a LabDataStore on a new SQLite database with the lab datas of the barcodes, one per barcode on device 1.
+// The test is skipped when SQLite is not available, it needs cgo.
+func newTestLabDataStore(t *testing.T, barcodes ...string) *LabDataStore {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err == nil {
+		err = db.Exec("SELECT 1").Error
+	}
+	if err != nil {
+		t.Skipf("SQLite is not available: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	s, err := NewLabDataStore(db)
+	if err != nil {
+		t.Fatalf("NewLabDataStore() error = %v", err)
+	}
+
+	labDatas := []*model.LabData{}
+	for _, barcode := range barcodes {
+		labDatas = append(labDatas, &model.LabData{RawDataID: 1, DeviceID: 1, Barcode: barcode, Param: "GLU", CompletedDate: time.Now()})
+	}
+	err = s.CreateAll(labDatas)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+
+	return s
+}
+
+// listBarcodes lists the lab datas of the query and returns their barcodes.
+func listBarcodes(t *testing.T, s *LabDataStore, query *ListQuery) ([]string, *Page) {
+	t.Helper()
+
+	labDatas, page, err := s.List(query)
+	if err != nil {
+		t.Fatalf("List(%+v) error = %v", query, err)
+	}
+
+	barcodes := []string{}
+	for _, labData := range labDatas {
+		barcodes = append(barcodes, labData.Barcode)
+	}
+
+	return barcodes, page
+}
+
+func TestListCursor(t *testing.T) {
+	s := newTestLabDataStore(t, "BC1", "BC2", "BC3", "BC4", "BC5")
+
+	tests := []struct {
+		name string
+		desc bool
+		want [][]string
+	}{
+		{name: "ascending", want: [][]string{{"BC1", "BC2"}, {"BC3", "BC4"}, {"BC5"}}},
+		{name: "descending", desc: true, want: [][]string{{"BC5", "BC4"}, {"BC3", "BC2"}, {"BC1"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := &ListQuery{Limit: 2, SortDesc: test.desc}
+			for i, want := range test.want {
+				barcodes, page := listBarcodes(t, s, query)
+				if len(barcodes) != len(want) || barcodes[0] != want[0] {
+					t.Fatalf("page %d = %v, want %v", i, barcodes, want)
+				}
+				if page.Total != 5 {
+					t.Errorf("page %d total = %d, want 5", i, page.Total)
+				}
+
+				// the last page is the one shorter than the limit
+				last := i == len(test.want)-1
+				if last != (page.NextCursor == 0) {
+					t.Fatalf("page %d next cursor = %d, want it on the full pages only", i, page.NextCursor)
+				}
+				query.Cursor = page.NextCursor
+			}
+		})
+	}
+}
+
+func TestListSort(t *testing.T) {
+	s := newTestLabDataStore(t, "BC2", "BC1", "BC2", "BC3")
+
+	// the rows of the same barcode are sorted by ID in the same direction
+	barcodes, page := listBarcodes(t, s, &ListQuery{SortField: "barcode", SortDesc: true, Limit: 3})
+	if want := []string{"BC3", "BC2", "BC2"}; len(barcodes) != 3 || barcodes[0] != want[0] || barcodes[2] != want[2] {
+		t.Errorf("sorted by barcode = %v, want %v", barcodes, want)
+	}
+	if page.NextCursor != 0 {
+		t.Errorf("next cursor = %d, want none when sorted by barcode", page.NextCursor)
+	}
+
+	labDatas, _, err := s.List(&ListQuery{SortField: "barcode", SortDesc: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if labDatas[1].ID < labDatas[2].ID {
+		t.Errorf("the rows of BC2 are sorted by ID %d, %d, want descending", labDatas[1].ID, labDatas[2].ID)
+	}
+
+	// the offset pages the sorted rows
+	barcodes, page = listBarcodes(t, s, &ListQuery{SortField: "barcode", Offset: 3})
+	if len(barcodes) != 1 || barcodes[0] != "BC3" || page.Offset != 3 {
+		t.Errorf("sorted by barcode from 3 = %v, want BC3", barcodes)
+	}
+}
+
+func TestListLimit(t *testing.T) {
+	s := newTestLabDataStore(t, "BC1")
+
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{limit: 0, want: DefaultListLimit},
+		{limit: -1, want: DefaultListLimit},
+		{limit: 10, want: 10},
+		{limit: MaxListLimit + 1, want: MaxListLimit},
+	}
+
+	for _, test := range tests {
+		_, page := listBarcodes(t, s, &ListQuery{Limit: test.limit})
+		if page.Limit != test.want {
+			t.Errorf("limit of %d = %d, want %d", test.limit, page.Limit, test.want)
+		}
+	}
+}
+
+func TestListInvalidQuery(t *testing.T) {
+	s := newTestLabDataStore(t, "BC1")
+	from := time.Now()
+	processed := true
+
+	tests := []struct {
+		name  string
+		query *ListQuery
+	}{
+		{name: "unknown sort field", query: &ListQuery{SortField: "result"}},
+		{name: "sort field of another table", query: &ListQuery{SortField: "status"}},
+		{name: "SQL in the sort field", query: &ListQuery{SortField: "id; DROP TABLE lab_data"}},
+		{name: "unknown date field", query: &ListQuery{DateField: "delivered_at", From: &from}},
+		{name: "unsupported filter", query: &ListQuery{Processed: &processed}},
+		{name: "cursor sorted by another field", query: &ListQuery{Cursor: 1, SortField: "barcode"}},
+		{name: "cursor with an offset", query: &ListQuery{Cursor: 1, Offset: 10}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := s.List(test.query)
+			if !errors.Is(err, ErrInvalidListQuery) {
+				t.Errorf("List() error = %v, want ErrInvalidListQuery", err)
+			}
+		})
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	s := newTestLabDataStore(t, "A_1", "AB1", "A%2", "A!3", "B_1")
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "A_", want: []string{"A_1"}},
+		{prefix: "A%", want: []string{"A%2"}},
+		{prefix: "A!", want: []string{"A!3"}},
+		{prefix: "A", want: []string{"A_1", "AB1", "A%2", "A!3"}},
+		{prefix: "C", want: []string{}},
+	}
+
+	for _, test := range tests {
+		barcodes, page := listBarcodes(t, s, &ListQuery{BarcodePrefix: test.prefix})
+		if len(barcodes) != len(test.want) || int(page.Total) != len(test.want) {
+			t.Errorf("barcodes starting with %q = %v, want %v", test.prefix, barcodes, test.want)
+		}
+	}
+
+	future := time.Now().Add(time.Hour)
+	barcodes, _ := listBarcodes(t, s, &ListQuery{DateField: "completed_date", From: &future})
+	if len(barcodes) != 0 {
+		t.Errorf("lab datas completed from %s = %v, want none", future, barcodes)
+	}
+	barcodes, _ = listBarcodes(t, s, &ListQuery{DeviceID: 1, Param: "GLU", To: &future})
+	if len(barcodes) != 5 {
+		t.Errorf("lab datas of device 1 and GLU = %v, want all", barcodes)
+	}
+}
+
+func TestGetByVerificationStatus(t *testing.T) {
+	s := newTestLabDataStore(t)
+
+	labDatas := []*model.LabData{}
+	for _, status := range []string{model.VerificationStatusHeld, model.VerificationStatusAutoVerified, model.VerificationStatusHeld, model.VerificationStatusHeld} {
+		labDatas = append(labDatas, &model.LabData{RawDataID: 1, DeviceID: 1, Barcode: "BC1", Param: "GLU", VerificationStatus: status, CompletedDate: time.Now()})
+	}
+	err := s.CreateAll(labDatas)
+	if err != nil {
+		t.Fatalf("CreateAll() error = %v", err)
+	}
+
+	held, page, err := s.GetByVerificationStatus(model.VerificationStatusHeld, &ListQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetByVerificationStatus() error = %v", err)
+	}
+	if len(held) != 2 || page.Total != 3 || page.NextCursor != held[1].ID {
+		t.Fatalf("GetByVerificationStatus() = %d lab datas of %d, next cursor %d, want the first 2 of 3 held", len(held), page.Total, page.NextCursor)
+	}
+
+	held, _, err = s.GetByVerificationStatus(model.VerificationStatusHeld, &ListQuery{Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("GetByVerificationStatus() error = %v", err)
+	}
+	if len(held) != 1 || held[0].ID != labDatas[3].ID {
+		t.Errorf("second page = %d lab datas, want the last held one", len(held))
+	}
+
+	_, _, err = s.GetByVerificationStatus(model.VerificationStatusHeld, &ListQuery{SortField: "result"})
+	if !errors.Is(err, ErrInvalidListQuery) {
+		t.Errorf("GetByVerificationStatus() error = %v, want ErrInvalidListQuery", err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "BC1", want: "BC1"},
+		{value: "BC_1", want: "BC!_1"},
+		{value: "100%", want: "100!%"},
+		{value: "A!B", want: "A!!B"},
+		{value: "[AB]", want: "![AB]"},
+		{value: "", want: ""},
+	}
+
+	for _, test := range tests {
+		if got := escapeLike(test.value); got != test.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
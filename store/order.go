@@ -0,0 +1,149 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// OrderStore is the store for the Order and OrderTest models.
+type OrderStore struct {
+	db *gorm.DB
+}
+
+// NewOrderStore creates a new OrderStore.
+func NewOrderStore(db *gorm.DB) (*OrderStore, error) {
+	store := &OrderStore{db: db}
+	err := store.db.AutoMigrate(&model.Order{}, &model.OrderTest{})
+	if err != nil {
+		return nil, errors.New("failed to migrate Order model")
+	}
+
+	return store, nil
+}
+
+// Transaction runs fn with an OrderStore of a single transaction, the changes of fn are rolled back if it fails.
+func (s *OrderStore) Transaction(fn func(orders *OrderStore) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&OrderStore{db: tx})
+	})
+}
+
+// Create creates a new order with its tests.
+func (s *OrderStore) Create(order *model.Order) error {
+	err := s.db.Create(order).Error
+	if err != nil {
+		return fmt.Errorf("failed to create order for barcode: %v", order.Barcode)
+	}
+
+	return nil
+}
+
+// GetByID gets an order by ID with its tests.
+func (s *OrderStore) GetByID(id uint) (*model.Order, error) {
+	order := &model.Order{}
+	err := s.db.Preload("Tests").First(order, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order by ID: %v", id)
+	}
+
+	return order, nil
+}
+
+// GetByBarcode gets the orders of a barcode with their tests.
+func (s *OrderStore) GetByBarcode(barcode string) ([]*model.Order, error) {
+	orders := []*model.Order{}
+	err := s.db.Preload("Tests").Where("barcode = ?", barcode).Order("id").Find(&orders).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders by barcode: %v", barcode)
+	}
+
+	return orders, nil
+}
+
+// GetOpenByBarcode gets the orders of a barcode which are not completed with their tests.
+func (s *OrderStore) GetOpenByBarcode(barcode string) ([]*model.Order, error) {
+	orders := []*model.Order{}
+	err := s.db.Preload("Tests").Where("barcode = ? AND status <> ?", barcode, model.OrderStatusCompleted).Order("id").Find(&orders).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders by barcode: %v", barcode)
+	}
+
+	return orders, nil
+}
+
+// GetOutstanding gets a page of the orders of the query with pending or missing tests with their tests.
+func (s *OrderStore) GetOutstanding(query *ListQuery) ([]*model.Order, *Page, error) {
+	orders := []*model.Order{}
+	page, err := list(s.db.Where("status <> ?", model.OrderStatusCompleted), orderListSchema, query, &orders)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to get outstanding orders")
+	}
+
+	return orders, page, nil
+}
+
+// GetAll gets all orders with their tests.
+func (s *OrderStore) GetAll() ([]*model.Order, error) {
+	orders := []*model.Order{}
+	err := s.db.Preload("Tests").Order("id").Find(&orders).Error
+	if err != nil {
+		return nil, errors.New("failed to get all orders")
+	}
+
+	return orders, nil
+}
+
+// orderListSchema is the list schema of the orders.
+var orderListSchema = listSchema{
+	sortFields:     []string{"device_id", "barcode", "status"},
+	deviceIDColumn: "device_id",
+	barcodeColumn:  "barcode",
+	preloads:       []string{"Tests"},
+}
+
+// List gets a page of the orders of the query.
+func (s *OrderStore) List(query *ListQuery) ([]*model.Order, *Page, error) {
+	orders := []*model.Order{}
+	page, err := list(s.db, orderListSchema, query, &orders)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list orders")
+	}
+
+	return orders, page, nil
+}
+
+// Update updates an order with its tests.
+func (s *OrderStore) Update(order *model.Order) error {
+	err := s.db.Session(&gorm.Session{FullSaveAssociations: true}).Save(order).Error
+	if err != nil {
+		return fmt.Errorf("failed to update order: %v", order.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes an order with its tests.
+func (s *OrderStore) Delete(order *model.Order) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("order_id = ?", order.ID).Delete(&model.OrderTest{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(order).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete order: %v", order.ID)
+	}
+
+	return nil
+}
@@ -3,11 +3,26 @@ package store
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/voidmaindev/doctra_lis_middleware/model"
 	"gorm.io/gorm"
 )
 
+// RawDataFilter selects the raw datas, the unset fields do not restrict the selection.
+type RawDataFilter struct {
+	IDs             []uint     `json:"ids"`
+	DeviceID        uint       `json:"device_id"`
+	From            *time.Time `json:"from"`
+	To              *time.Time `json:"to"`
+	UnprocessedOnly bool       `json:"unprocessed_only"`
+}
+
+// IsEmpty checks if the filter selects all raw datas.
+func (f *RawDataFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.DeviceID == 0 && f.From == nil && f.To == nil && !f.UnprocessedOnly
+}
+
 // RawDataStore is the store for the RawData model.
 type RawDataStore struct {
 	db *gorm.DB
@@ -56,6 +71,27 @@ func (s *RawDataStore) GetAll() ([]*model.RawData, error) {
 	return rawData, nil
 }
 
+// rawDataListSchema is the list schema of the raw data.
+var rawDataListSchema = listSchema{
+	sortFields:      []string{"device_id"},
+	deviceIDColumn:  "device_id",
+	processedColumn: "processed",
+}
+
+// List gets a page of the raw data of the query.
+func (s *RawDataStore) List(query *ListQuery) ([]*model.RawData, *Page, error) {
+	rawData := []*model.RawData{}
+	page, err := list(s.db, rawDataListSchema, query, &rawData)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list raw data")
+	}
+
+	return rawData, page, nil
+}
+
 // GetByDeviceID gets a raw data by device ID.
 func (s *RawDataStore) GetByDeviceID(deviceID uint) ([]*model.RawData, error) {
 	rawData := []*model.RawData{}
@@ -67,6 +103,50 @@ func (s *RawDataStore) GetByDeviceID(deviceID uint) ([]*model.RawData, error) {
 	return rawData, nil
 }
 
+// GetByFilter gets the raw datas selected by the filter ordered by ID.
+func (s *RawDataStore) GetByFilter(filter *RawDataFilter) ([]*model.RawData, error) {
+	rawData := []*model.RawData{}
+	err := s.filterQuery(filter).Order("id").Find(&rawData).Error
+	if err != nil {
+		return nil, errors.New("failed to get raw data by filter")
+	}
+
+	return rawData, nil
+}
+
+// CountByFilter counts the raw datas selected by the filter.
+func (s *RawDataStore) CountByFilter(filter *RawDataFilter) (int64, error) {
+	var count int64
+	err := s.filterQuery(filter).Model(&model.RawData{}).Count(&count).Error
+	if err != nil {
+		return 0, errors.New("failed to count raw data by filter")
+	}
+
+	return count, nil
+}
+
+// filterQuery returns the query of the raw datas selected by the filter.
+func (s *RawDataStore) filterQuery(filter *RawDataFilter) *gorm.DB {
+	query := s.db
+	if len(filter.IDs) > 0 {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.DeviceID != 0 {
+		query = query.Where("device_id = ?", filter.DeviceID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.UnprocessedOnly {
+		query = query.Where("processed = ?", false)
+	}
+
+	return query
+}
+
 // Update updates a raw data.
 func (s *RawDataStore) Update(rawData *model.RawData) error {
 	err := s.db.Save(rawData).Error
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// ReferenceRangeStore is the store for the ReferenceRange model.
+type ReferenceRangeStore struct {
+	db *gorm.DB
+}
+
+// NewReferenceRangeStore creates a new ReferenceRangeStore.
+func NewReferenceRangeStore(db *gorm.DB) (*ReferenceRangeStore, error) {
+	store := &ReferenceRangeStore{db: db}
+	err := store.db.AutoMigrate(&model.ReferenceRange{})
+	if err != nil {
+		return nil, errors.New("failed to migrate ReferenceRange model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new reference range.
+func (s *ReferenceRangeStore) Create(referenceRange *model.ReferenceRange) error {
+	err := s.db.Create(referenceRange).Error
+	if err != nil {
+		return fmt.Errorf("failed to create reference range for param: %v", referenceRange.Param)
+	}
+
+	return nil
+}
+
+// GetByID gets a reference range by ID.
+func (s *ReferenceRangeStore) GetByID(id uint) (*model.ReferenceRange, error) {
+	referenceRange := &model.ReferenceRange{}
+	err := s.db.First(referenceRange, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference range by ID: %v", id)
+	}
+
+	return referenceRange, nil
+}
+
+// GetByParams gets the reference ranges of the params.
+func (s *ReferenceRangeStore) GetByParams(params []string) (model.ReferenceRanges, error) {
+	referenceRanges := model.ReferenceRanges{}
+	err := s.db.Where("param IN ?", params).Order("id").Find(&referenceRanges).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference ranges by params: %v", params)
+	}
+
+	return referenceRanges, nil
+}
+
+// GetAll gets all reference ranges.
+func (s *ReferenceRangeStore) GetAll() (model.ReferenceRanges, error) {
+	referenceRanges := model.ReferenceRanges{}
+	err := s.db.Order("id").Find(&referenceRanges).Error
+	if err != nil {
+		return nil, errors.New("failed to get all reference ranges")
+	}
+
+	return referenceRanges, nil
+}
+
+// referenceRangeListSchema is the list schema of the reference ranges.
+var referenceRangeListSchema = listSchema{
+	sortFields:  []string{"param"},
+	paramColumn: "param",
+}
+
+// List gets a page of the reference ranges of the query.
+func (s *ReferenceRangeStore) List(query *ListQuery) ([]*model.ReferenceRange, *Page, error) {
+	referenceRanges := []*model.ReferenceRange{}
+	page, err := list(s.db, referenceRangeListSchema, query, &referenceRanges)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list reference ranges")
+	}
+
+	return referenceRanges, page, nil
+}
+
+// Update updates a reference range.
+func (s *ReferenceRangeStore) Update(referenceRange *model.ReferenceRange) error {
+	err := s.db.Save(referenceRange).Error
+	if err != nil {
+		return fmt.Errorf("failed to update reference range: %v", referenceRange.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a reference range.
+func (s *ReferenceRangeStore) Delete(referenceRange *model.ReferenceRange) error {
+	err := s.db.Delete(referenceRange).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete reference range: %v", referenceRange.ID)
+	}
+
+	return nil
+}
@@ -9,12 +9,22 @@ import (
 
 // Store is the store for the application.
 type Store struct {
-	db               *gorm.DB
-	UserStore        *UserStore
-	DeviceModelStore *DeviceModelStore
-	DeviceStore      *DeviceStore
-	LabDataStore     *LabDataStore
-	RawDataStore     *RawDataStore
+	db                    *gorm.DB
+	UserStore             *UserStore
+	DeviceModelStore      *DeviceModelStore
+	DeviceStore           *DeviceStore
+	LabDataStore          *LabDataStore
+	RawDataStore          *RawDataStore
+	TestCodeMappingStore  *TestCodeMappingStore
+	ReferenceRangeStore   *ReferenceRangeStore
+	UnitStore             *UnitStore
+	UnitConversionStore   *UnitConversionStore
+	CanonicalUnitStore    *CanonicalUnitStore
+	VerificationRuleStore *VerificationRuleStore
+	CriticalAlertStore    *CriticalAlertStore
+	OrderStore            *OrderStore
+	EventStore            *EventStore
+	DeviceStatusStore     *DeviceStatusStore
 }
 
 // NewStore creates a new Store.
@@ -32,6 +42,11 @@ func NewStore(log *log.Logger) (*Store, error) {
 		return nil, err
 	}
 
+	return NewStoreWithDB(log, db)
+}
+
+// NewStoreWithDB creates a new Store on the connected DB, migrating the models.
+func NewStoreWithDB(log *log.Logger, db *gorm.DB) (*Store, error) {
 	userStore, err := NewUserStore(db)
 	if err != nil {
 		log.Err(err, "failed to create UserStore")
@@ -62,13 +77,83 @@ func NewStore(log *log.Logger) (*Store, error) {
 		return nil, err
 	}
 
+	testCodeMappingStore, err := NewTestCodeMappingStore(db)
+	if err != nil {
+		log.Err(err, "failed to create TestCodeMappingStore")
+		return nil, err
+	}
+
+	referenceRangeStore, err := NewReferenceRangeStore(db)
+	if err != nil {
+		log.Err(err, "failed to create ReferenceRangeStore")
+		return nil, err
+	}
+
+	unitStore, err := NewUnitStore(db)
+	if err != nil {
+		log.Err(err, "failed to create UnitStore")
+		return nil, err
+	}
+
+	unitConversionStore, err := NewUnitConversionStore(db)
+	if err != nil {
+		log.Err(err, "failed to create UnitConversionStore")
+		return nil, err
+	}
+
+	canonicalUnitStore, err := NewCanonicalUnitStore(db)
+	if err != nil {
+		log.Err(err, "failed to create CanonicalUnitStore")
+		return nil, err
+	}
+
+	verificationRuleStore, err := NewVerificationRuleStore(db)
+	if err != nil {
+		log.Err(err, "failed to create VerificationRuleStore")
+		return nil, err
+	}
+
+	criticalAlertStore, err := NewCriticalAlertStore(db)
+	if err != nil {
+		log.Err(err, "failed to create CriticalAlertStore")
+		return nil, err
+	}
+
+	orderStore, err := NewOrderStore(db)
+	if err != nil {
+		log.Err(err, "failed to create OrderStore")
+		return nil, err
+	}
+
+	eventStore, err := NewEventStore(db)
+	if err != nil {
+		log.Err(err, "failed to create EventStore")
+		return nil, err
+	}
+
+	deviceStatusStore, err := NewDeviceStatusStore(db)
+	if err != nil {
+		log.Err(err, "failed to create DeviceStatusStore")
+		return nil, err
+	}
+
 	store := &Store{
-		db:               db,
-		UserStore:        userStore,
-		DeviceModelStore: deviceModelStore,
-		DeviceStore:      deviceStore,
-		LabDataStore:     labDataStore,
-		RawDataStore:     rawDataStore,
+		db:                    db,
+		UserStore:             userStore,
+		DeviceModelStore:      deviceModelStore,
+		DeviceStore:           deviceStore,
+		LabDataStore:          labDataStore,
+		RawDataStore:          rawDataStore,
+		TestCodeMappingStore:  testCodeMappingStore,
+		ReferenceRangeStore:   referenceRangeStore,
+		UnitStore:             unitStore,
+		UnitConversionStore:   unitConversionStore,
+		CanonicalUnitStore:    canonicalUnitStore,
+		VerificationRuleStore: verificationRuleStore,
+		CriticalAlertStore:    criticalAlertStore,
+		OrderStore:            orderStore,
+		EventStore:            eventStore,
+		DeviceStatusStore:     deviceStatusStore,
 	}
 
 	return store, nil
@@ -78,3 +163,13 @@ func NewStore(log *log.Logger) (*Store, error) {
 func (s *Store) CreateTransaction() *gorm.DB {
 	return s.db.Begin()
 }
+
+// Close closes the connections to the DB.
+func (s *Store) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
@@ -0,0 +1,72 @@
+// Package storetest provides a store on a temporary SQLite database for the tests.
+package storetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"github.com/voidmaindev/doctra_lis_middleware/store"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// New creates a store on a new SQLite database in the temporary directory of the test.
+// The test is skipped when SQLite is not available, it needs cgo.
+func New(t testing.TB) *store.Store {
+	t.Helper()
+
+	return NewWithDB(t, Open(t))
+}
+
+// Open opens a new SQLite database in the temporary directory of the test.
+// The test is skipped when SQLite is not available, it needs cgo.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err == nil {
+		err = db.Exec("SELECT 1").Error
+	}
+	if err != nil {
+		t.Skipf("SQLite is not available: %v", err)
+	}
+
+	return db
+}
+
+// NewWithDB creates a store on the database opened by Open, the tests may register their callbacks on it.
+func NewWithDB(t testing.TB, db *gorm.DB) *store.Store {
+	t.Helper()
+
+	s, err := store.NewStoreWithDB(&log.Logger{Disabled: true}, db)
+	if err != nil {
+		t.Fatalf("NewStoreWithDB() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// CreateDevice creates a device with the name and the serial and its device model.
+func CreateDevice(t testing.TB, s *store.Store, name, serial string) *model.Device {
+	t.Helper()
+
+	deviceModel := &model.DeviceModel{Name: name + " model", Driver: "astm"}
+	err := s.DeviceModelStore.Create(deviceModel)
+	if err != nil {
+		t.Fatalf("DeviceModelStore.Create() error = %v", err)
+	}
+
+	device := &model.Device{Name: name, DeviceModelID: deviceModel.ID, Serial: serial}
+	err = s.DeviceStore.Create(device)
+	if err != nil {
+		t.Fatalf("DeviceStore.Create() error = %v", err)
+	}
+
+	return device
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Names of the streams read by cursor.
+const (
+	streamLabDataChanges = "lab_data_changes"
+	streamEvents         = "events"
+)
+
+// migrateStreamLock migrates the StreamLock model and creates the lock of the stream if it does not exist.
+func migrateStreamLock(db *gorm.DB, stream string) error {
+	err := db.AutoMigrate(&model.StreamLock{})
+	if err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.StreamLock{Name: stream}).Error
+}
+
+// lockStream locks the stream in the transaction until it is committed or rolled back.
+// It must be called before the rows of the stream are created, the concurrent writers then take their IDs one after the other.
+func lockStream(tx *gorm.DB, stream string) error {
+	return tx.Model(&model.StreamLock{}).Where("name = ?", stream).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
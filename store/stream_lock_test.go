@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+// dryRunConnPool is the connection pool of a dry run DB, it only begins the transactions, the statements are never run.
+type dryRunConnPool struct {
+	gorm.ConnPool
+}
+
+// BeginTx begins a dry run transaction.
+func (p *dryRunConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{}, nil
+}
+
+// dryRunTx is a transaction of a dry run DB.
+type dryRunTx struct {
+	gorm.ConnPool
+}
+
+// Commit commits nothing.
+func (tx *dryRunTx) Commit() error {
+	return nil
+}
+
+// Rollback rolls back nothing.
+func (tx *dryRunTx) Rollback() error {
+	return nil
+}
+
+// newDryRunDB opens a DB building the statements without running them and returns the SQL of its queries, updates and creates.
+func newDryRunDB(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, ConnPool: &dryRunConnPool{}})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+
+	statements := []string{}
+	record := func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	}
+	err = db.Callback().Update().After("gorm:update").Register("test:record", record)
+	if err == nil {
+		err = db.Callback().Create().After("gorm:create").Register("test:record", record)
+	}
+	if err == nil {
+		err = db.Callback().Query().After("gorm:query").Register("test:record", record)
+	}
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	return db, &statements
+}
+
+func TestRecordLabDataChangesLocksStream(t *testing.T) {
+	db, statements := newDryRunDB(t)
+
+	err := recordLabDataChanges(db, model.LabDataChangeUpdated, 1, 2)
+	if err != nil {
+		t.Fatalf("recordLabDataChanges() error = %v", err)
+	}
+
+	// the lock is taken before the IDs of the changes, they are committed in their order
+	if len(*statements) != 2 {
+		t.Fatalf("statements = %q, want the lock and the insert", *statements)
+	}
+	lock, insert := (*statements)[0], (*statements)[1]
+	if !strings.HasPrefix(lock, "UPDATE") || !strings.Contains(lock, "stream_locks") {
+		t.Errorf("first statement = %s, want the update of the stream lock", lock)
+	}
+	if !strings.HasPrefix(insert, "INSERT") || !strings.Contains(insert, "lab_data_changes") {
+		t.Errorf("second statement = %s, want the insert of the changes", insert)
+	}
+}
+
+func TestRecordLabDataChangesWithoutIDs(t *testing.T) {
+	db, statements := newDryRunDB(t)
+
+	err := recordLabDataChanges(db, model.LabDataChangeDeleted)
+	if err != nil {
+		t.Fatalf("recordLabDataChanges() error = %v", err)
+	}
+	if len(*statements) != 0 {
+		t.Errorf("statements = %q, want none without changes", *statements)
+	}
+}
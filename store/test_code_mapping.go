@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// TestCodeMappingStore is the store for the TestCodeMapping model.
+type TestCodeMappingStore struct {
+	db *gorm.DB
+}
+
+// NewTestCodeMappingStore creates a new TestCodeMappingStore.
+func NewTestCodeMappingStore(db *gorm.DB) (*TestCodeMappingStore, error) {
+	store := &TestCodeMappingStore{db: db}
+	err := store.db.AutoMigrate(&model.TestCodeMapping{})
+	if err != nil {
+		return nil, errors.New("failed to migrate TestCodeMapping model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new test code mapping.
+func (s *TestCodeMappingStore) Create(mapping *model.TestCodeMapping) error {
+	err := s.db.Create(mapping).Error
+	if err != nil {
+		return fmt.Errorf("failed to create test code mapping for device model: %v and device code: %v", mapping.DeviceModelID, mapping.DeviceCode)
+	}
+
+	return nil
+}
+
+// GetByID gets a test code mapping by ID.
+func (s *TestCodeMappingStore) GetByID(id uint) (*model.TestCodeMapping, error) {
+	mapping := &model.TestCodeMapping{}
+	err := s.db.First(mapping, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test code mapping by ID: %v", id)
+	}
+
+	return mapping, nil
+}
+
+// GetByDeviceModelID gets the test code mappings of a device model.
+func (s *TestCodeMappingStore) GetByDeviceModelID(deviceModelID uint) (model.TestCodeMappings, error) {
+	mappings := model.TestCodeMappings{}
+	err := s.db.Where("device_model_id = ?", deviceModelID).Order("id").Find(&mappings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test code mappings by device model ID: %v", deviceModelID)
+	}
+
+	return mappings, nil
+}
+
+// GetAll gets all test code mappings.
+func (s *TestCodeMappingStore) GetAll() (model.TestCodeMappings, error) {
+	mappings := model.TestCodeMappings{}
+	err := s.db.Order("id").Find(&mappings).Error
+	if err != nil {
+		return nil, errors.New("failed to get all test code mappings")
+	}
+
+	return mappings, nil
+}
+
+// testCodeMappingListSchema is the list schema of the test code mappings.
+var testCodeMappingListSchema = listSchema{
+	sortFields:  []string{"device_model_id", "device_code", "his_code"},
+	paramColumn: "his_code",
+}
+
+// List gets a page of the test code mappings of the query.
+func (s *TestCodeMappingStore) List(query *ListQuery) ([]*model.TestCodeMapping, *Page, error) {
+	mappings := []*model.TestCodeMapping{}
+	page, err := list(s.db, testCodeMappingListSchema, query, &mappings)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list test code mappings")
+	}
+
+	return mappings, page, nil
+}
+
+// Update updates a test code mapping.
+func (s *TestCodeMappingStore) Update(mapping *model.TestCodeMapping) error {
+	err := s.db.Save(mapping).Error
+	if err != nil {
+		return fmt.Errorf("failed to update test code mapping: %v", mapping.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a test code mapping.
+func (s *TestCodeMappingStore) Delete(mapping *model.TestCodeMapping) error {
+	err := s.db.Delete(mapping).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete test code mapping: %v", mapping.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// UnitStore is the store for the Unit model.
+type UnitStore struct {
+	db *gorm.DB
+}
+
+// NewUnitStore creates a new UnitStore.
+func NewUnitStore(db *gorm.DB) (*UnitStore, error) {
+	store := &UnitStore{db: db}
+	err := store.db.AutoMigrate(&model.Unit{})
+	if err != nil {
+		return nil, errors.New("failed to migrate Unit model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new unit.
+func (s *UnitStore) Create(unit *model.Unit) error {
+	err := s.db.Create(unit).Error
+	if err != nil {
+		return fmt.Errorf("failed to create unit: %v", unit.Code)
+	}
+
+	return nil
+}
+
+// GetByID gets a unit by ID.
+func (s *UnitStore) GetByID(id uint) (*model.Unit, error) {
+	unit := &model.Unit{}
+	err := s.db.First(unit, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit by ID: %v", id)
+	}
+
+	return unit, nil
+}
+
+// GetAll gets all units.
+func (s *UnitStore) GetAll() (model.Units, error) {
+	units := model.Units{}
+	err := s.db.Order("id").Find(&units).Error
+	if err != nil {
+		return nil, errors.New("failed to get all units")
+	}
+
+	return units, nil
+}
+
+// unitListSchema is the list schema of the units.
+var unitListSchema = listSchema{
+	sortFields: []string{"code"},
+}
+
+// List gets a page of the units of the query.
+func (s *UnitStore) List(query *ListQuery) ([]*model.Unit, *Page, error) {
+	units := []*model.Unit{}
+	page, err := list(s.db, unitListSchema, query, &units)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list units")
+	}
+
+	return units, page, nil
+}
+
+// Update updates a unit.
+func (s *UnitStore) Update(unit *model.Unit) error {
+	err := s.db.Save(unit).Error
+	if err != nil {
+		return fmt.Errorf("failed to update unit: %v", unit.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a unit.
+func (s *UnitStore) Delete(unit *model.Unit) error {
+	err := s.db.Delete(unit).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete unit: %v", unit.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// UnitConversionStore is the store for the UnitConversion model.
+type UnitConversionStore struct {
+	db *gorm.DB
+}
+
+// NewUnitConversionStore creates a new UnitConversionStore.
+func NewUnitConversionStore(db *gorm.DB) (*UnitConversionStore, error) {
+	store := &UnitConversionStore{db: db}
+	err := store.db.AutoMigrate(&model.UnitConversion{})
+	if err != nil {
+		return nil, errors.New("failed to migrate UnitConversion model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new unit conversion.
+func (s *UnitConversionStore) Create(unitConversion *model.UnitConversion) error {
+	err := s.db.Create(unitConversion).Error
+	if err != nil {
+		return fmt.Errorf("failed to create unit conversion from: %v to: %v", unitConversion.FromUnit, unitConversion.ToUnit)
+	}
+
+	return nil
+}
+
+// GetByID gets a unit conversion by ID.
+func (s *UnitConversionStore) GetByID(id uint) (*model.UnitConversion, error) {
+	unitConversion := &model.UnitConversion{}
+	err := s.db.First(unitConversion, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit conversion by ID: %v", id)
+	}
+
+	return unitConversion, nil
+}
+
+// GetByParams gets the unit conversions of the params and the unit conversions for every param.
+func (s *UnitConversionStore) GetByParams(params []string) (model.UnitConversions, error) {
+	unitConversions := model.UnitConversions{}
+	err := s.db.Where("param IN ? OR param = ?", params, "").Order("id").Find(&unitConversions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit conversions by params: %v", params)
+	}
+
+	return unitConversions, nil
+}
+
+// GetAll gets all unit conversions.
+func (s *UnitConversionStore) GetAll() (model.UnitConversions, error) {
+	unitConversions := model.UnitConversions{}
+	err := s.db.Order("id").Find(&unitConversions).Error
+	if err != nil {
+		return nil, errors.New("failed to get all unit conversions")
+	}
+
+	return unitConversions, nil
+}
+
+// unitConversionListSchema is the list schema of the unit conversions.
+var unitConversionListSchema = listSchema{
+	sortFields:  []string{"param", "from_unit", "to_unit"},
+	paramColumn: "param",
+}
+
+// List gets a page of the unit conversions of the query.
+func (s *UnitConversionStore) List(query *ListQuery) ([]*model.UnitConversion, *Page, error) {
+	unitConversions := []*model.UnitConversion{}
+	page, err := list(s.db, unitConversionListSchema, query, &unitConversions)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list unit conversions")
+	}
+
+	return unitConversions, page, nil
+}
+
+// Update updates a unit conversion.
+func (s *UnitConversionStore) Update(unitConversion *model.UnitConversion) error {
+	err := s.db.Save(unitConversion).Error
+	if err != nil {
+		return fmt.Errorf("failed to update unit conversion: %v", unitConversion.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a unit conversion.
+func (s *UnitConversionStore) Delete(unitConversion *model.UnitConversion) error {
+	err := s.db.Delete(unitConversion).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete unit conversion: %v", unitConversion.ID)
+	}
+
+	return nil
+}
@@ -78,6 +78,25 @@ func (s *UserStore) GetAll() ([]model.User, error) {
 	return users, nil
 }
 
+// userListSchema is the list schema of the users.
+var userListSchema = listSchema{
+	sortFields: []string{"username", "role"},
+}
+
+// List gets a page of the users of the query.
+func (s *UserStore) List(query *ListQuery) ([]model.User, *Page, error) {
+	users := []model.User{}
+	page, err := list(s.db, userListSchema, query, &users)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list users")
+	}
+
+	return users, page, nil
+}
+
 // Update updates a user.
 func (s *UserStore) Update(user *model.User) error {
 	err := s.db.Save(user).Error
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidmaindev/doctra_lis_middleware/model"
+	"gorm.io/gorm"
+)
+
+// VerificationRuleStore is the store for the VerificationRule model.
+type VerificationRuleStore struct {
+	db *gorm.DB
+}
+
+// NewVerificationRuleStore creates a new VerificationRuleStore.
+func NewVerificationRuleStore(db *gorm.DB) (*VerificationRuleStore, error) {
+	store := &VerificationRuleStore{db: db}
+	err := store.db.AutoMigrate(&model.VerificationRule{})
+	if err != nil {
+		return nil, errors.New("failed to migrate VerificationRule model")
+	}
+
+	return store, nil
+}
+
+// Create creates a new verification rule.
+func (s *VerificationRuleStore) Create(verificationRule *model.VerificationRule) error {
+	err := s.db.Create(verificationRule).Error
+	if err != nil {
+		return fmt.Errorf("failed to create verification rule: %v", verificationRule.Name)
+	}
+
+	return nil
+}
+
+// GetByID gets a verification rule by ID.
+func (s *VerificationRuleStore) GetByID(id uint) (*model.VerificationRule, error) {
+	verificationRule := &model.VerificationRule{}
+	err := s.db.First(verificationRule, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification rule by ID: %v", id)
+	}
+
+	return verificationRule, nil
+}
+
+// GetByParams gets the verification rules of the params and the verification rules for every param.
+func (s *VerificationRuleStore) GetByParams(params []string) (model.VerificationRules, error) {
+	verificationRules := model.VerificationRules{}
+	err := s.db.Where("param IN ? OR param = ?", params, "").Order("id").Find(&verificationRules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification rules by params: %v", params)
+	}
+
+	return verificationRules, nil
+}
+
+// GetAll gets all verification rules.
+func (s *VerificationRuleStore) GetAll() (model.VerificationRules, error) {
+	verificationRules := model.VerificationRules{}
+	err := s.db.Order("id").Find(&verificationRules).Error
+	if err != nil {
+		return nil, errors.New("failed to get all verification rules")
+	}
+
+	return verificationRules, nil
+}
+
+// verificationRuleListSchema is the list schema of the verification rules.
+var verificationRuleListSchema = listSchema{
+	sortFields:  []string{"param", "name", "type"},
+	paramColumn: "param",
+}
+
+// List gets a page of the verification rules of the query.
+func (s *VerificationRuleStore) List(query *ListQuery) ([]*model.VerificationRule, *Page, error) {
+	verificationRules := []*model.VerificationRule{}
+	page, err := list(s.db, verificationRuleListSchema, query, &verificationRules)
+	if err != nil {
+		if errors.Is(err, ErrInvalidListQuery) {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("failed to list verification rules")
+	}
+
+	return verificationRules, page, nil
+}
+
+// Update updates a verification rule.
+func (s *VerificationRuleStore) Update(verificationRule *model.VerificationRule) error {
+	err := s.db.Save(verificationRule).Error
+	if err != nil {
+		return fmt.Errorf("failed to update verification rule: %v", verificationRule.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a verification rule.
+func (s *VerificationRuleStore) Delete(verificationRule *model.VerificationRule) error {
+	err := s.db.Delete(verificationRule).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete verification rule: %v", verificationRule.ID)
+	}
+
+	return nil
+}
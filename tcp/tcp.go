@@ -1,28 +1,49 @@
 package tcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/voidmaindev/doctra_lis_middleware/log"
+	"github.com/voidmaindev/doctra_lis_middleware/transport"
 )
 
 // HL7BufferSize is the size of the HL7 buffer.
 const hl7BufferSize = 1 << 15
 
+// Reconnect delays of the outbound connections and the serial ports.
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	dialTimeout       = 10 * time.Second
+)
+
 // TCP is the struct that represents the TCP connection.
+// Besides the main listener it serves the additional listeners of the devices with own ports
+// and keeps the outbound connections to the devices acting as TCP servers and the serial ports of the devices.
 type TCP struct {
-	Log        *log.Logger
-	Listener   net.Listener
-	RcvChannel chan RcvData
-	Conns      map[string]*ConnData
+	Log          *log.Logger
+	Listener     net.Listener
+	Listeners    []net.Listener
+	RcvChannel   chan RcvData
+	Conns        map[string]*ConnData
+	OnConnect    func(*ConnData)
+	OnDisconnect func(*ConnData)
+
+	connsMu  sync.RWMutex
+	stop     chan struct{}
+	stopping bool
+	readers  sync.WaitGroup
 }
 
 // RcvData is the struct that represents the received data.
 type RcvData struct {
-	Conn       net.Conn
+	Conn       transport.Conn
 	ConnString string
 	Data       []byte
 	Wg         *sync.WaitGroup
@@ -30,15 +51,22 @@ type RcvData struct {
 
 // PrevData is the struct that represents the previous data of the connection.
 type PrevData struct {
-	Data    string
-	Started bool
+	Data        string
+	Started     bool
+	Frame       string // incomplete low-level frame waiting for the next read
+	FrameNumber int    // expected number of the next low-level frame
+	RecordOpen  bool   // the last frame ended in the middle of a record
 }
 
 // ConnData is the struct that represents the connection data.
 type ConnData struct {
-	Conn       net.Conn
+	Conn       transport.Conn
 	ConnString string
-	PrevData   *PrevData
+	RemoteIP   string
+	RemotePort string
+	LocalPort  string
+	DeviceID   uint  // set for outbound connections and serial ports, their device is known before connecting
+	Err        error // the read error which closed the connection, nil if it was closed normally
 	Wg         *sync.WaitGroup
 }
 
@@ -51,17 +79,37 @@ func NewTCP(log *log.Logger, listener net.Listener) *TCP {
 
 	tcp.Conns = map[string]*ConnData{}
 	tcp.RcvChannel = make(chan RcvData)
+	tcp.stop = make(chan struct{})
 
 	return tcp
 }
 
 // Accept accepts a connection.
 func (t *TCP) AcceptConnections() {
+	t.Serve(t.Listener)
+}
+
+// Listen opens an additional listener on the address.
+func (t *TCP) Listen(address string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	t.connsMu.Lock()
+	t.Listeners = append(t.Listeners, listener)
+	t.connsMu.Unlock()
+
+	return listener, nil
+}
+
+// Serve accepts the connections of the listener until it is closed.
+func (t *TCP) Serve(listener net.Listener) {
 	for {
-		conn, err := t.Listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
-			if err == net.ErrClosed || err == io.EOF || err.Error() == "EOF" {
-				t.Log.Info("TCP listener closed")
+			if errors.Is(err, net.ErrClosed) || err == io.EOF || err.Error() == "EOF" {
+				t.Log.Info("TCP listener closed: " + listener.Addr().String())
 				return //TODO: return or recreate listener and continue
 			}
 			t.Log.Err(err, "failed to accept a connection")
@@ -70,46 +118,218 @@ func (t *TCP) AcceptConnections() {
 
 		connString := getConnString(conn)
 
+		connData := t.addConn(conn, connString, 0)
+		if connData == nil {
+			t.Log.Info("refused a connection from " + connString + ", the server is stopping")
+			conn.Close()
+			continue
+		}
 		t.Log.Info("accepted a connection from " + connString)
-		t.Conns[connString] = newConnData(conn, connString)
 
-		go t.ReadMessages(conn, t.Conns[connString])
+		go t.ReadMessages(conn, connData)
+	}
+}
+
+// ConnectTo keeps an outbound connection to the device listening on the address.
+// The connection is reopened with an increasing delay whenever it fails or is closed, until the TCP is closed.
+func (t *TCP) ConnectTo(address string, deviceID uint) {
+	t.keepConnected(address, deviceID, func() (transport.Conn, error) {
+		return net.DialTimeout("tcp", address, dialTimeout)
+	})
+}
+
+// ConnectSerial keeps the serial port of the device open.
+// The port is reopened with an increasing delay whenever it fails, e.g. a USB adapter is unplugged, until the TCP is closed.
+func (t *TCP) ConnectSerial(config transport.SerialConfig, deviceID uint) {
+	t.keepConnected(config.String(), deviceID, func() (transport.Conn, error) {
+		return transport.OpenSerial(config)
+	})
+}
+
+// keepConnected opens the connection and reads its messages, reopening it after a failure or a close.
+func (t *TCP) keepConnected(name string, deviceID uint, open func() (transport.Conn, error)) {
+	delay := minReconnectDelay
+
+	for {
+		conn, err := open()
+		if err != nil {
+			t.Log.Err(err, fmt.Sprintf("failed to connect to %s, retrying in %s", name, delay))
+		} else {
+			delay = minReconnectDelay
+			connString := getConnString(conn)
+
+			connData := t.addConn(conn, connString, deviceID)
+			if connData == nil {
+				conn.Close()
+				return
+			}
+			t.Log.Info("connected to " + connString)
+
+			t.ReadMessages(conn, connData)
+		}
+
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(delay):
+		}
+
+		if err != nil {
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}
+}
+
+// Conn gets the connection data by the connection string.
+func (t *TCP) Conn(connString string) *ConnData {
+	t.connsMu.RLock()
+	defer t.connsMu.RUnlock()
+
+	return t.Conns[connString]
+}
+
+// Shutdown stops accepting and reopening the connections, waits for the messages in progress to be processed,
+// then closes the connections and waits for their readers to finish.
+// The receive channel is closed once no reader can send to it anymore.
+// When the context is done first, the connections are closed without waiting any longer and its error is returned.
+func (t *TCP) Shutdown(ctx context.Context) error {
+	t.connsMu.Lock()
+	if t.stopping {
+		t.connsMu.Unlock()
+		return nil
+	}
+	t.stopping = true
+	close(t.stop)
+
+	var errs []error
+	for _, listener := range append([]net.Listener{t.Listener}, t.Listeners...) {
+		err := listener.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	conns := make([]*ConnData, 0, len(t.Conns))
+	for _, connData := range t.Conns {
+		conns = append(conns, connData)
+	}
+	t.connsMu.Unlock()
+
+	err := waitContext(ctx, func() {
+		for _, connData := range conns {
+			connData.Wg.Wait()
+		}
+	})
+
+	for _, connData := range conns {
+		connData.Conn.Close()
+	}
+
+	if err != nil {
+		t.Log.Warn("stopped waiting for the messages in progress")
+		return errors.Join(append(errs, err)...)
+	}
+
+	err = waitContext(ctx, t.readers.Wait)
+	if err != nil {
+		t.Log.Warn("stopped waiting for the connections to close")
+		return errors.Join(append(errs, err)...)
+	}
+
+	close(t.RcvChannel)
+
+	return errors.Join(errs...)
+}
+
+// waitContext runs the wait function until it returns or the context is done.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// addConn registers the new connection and notifies about it, it returns nil when the TCP is shutting down.
+func (t *TCP) addConn(conn transport.Conn, connString string, deviceID uint) *ConnData {
+	connData := newConnData(conn, connString)
+	connData.DeviceID = deviceID
+
+	t.connsMu.Lock()
+	if t.stopping {
+		t.connsMu.Unlock()
+		return nil
+	}
+	t.Conns[connString] = connData
+	t.readers.Add(1)
+	t.connsMu.Unlock()
+
+	if t.OnConnect != nil {
+		t.OnConnect(connData)
+	}
+
+	return connData
+}
+
+// removeConn unregisters the closed connection unless a newer connection took its connection string.
+func (t *TCP) removeConn(connData *ConnData) {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+
+	if t.Conns[connData.ConnString] == connData {
+		delete(t.Conns, connData.ConnString)
 	}
 }
 
 // newConnData creates a new connection data.
-func newConnData(conn net.Conn, connString string) *ConnData {
-	return &ConnData{
+// The addresses are set only for the network connections.
+func newConnData(conn transport.Conn, connString string) *ConnData {
+	connData := &ConnData{
 		Conn:       conn,
 		ConnString: connString,
-		PrevData:   &PrevData{},
 		Wg:         &sync.WaitGroup{},
 	}
+
+	if netConn, ok := conn.(net.Conn); ok {
+		connData.RemoteIP, connData.RemotePort, _ = net.SplitHostPort(netConn.RemoteAddr().String())
+		_, connData.LocalPort, _ = net.SplitHostPort(netConn.LocalAddr().String())
+	}
+
+	return connData
 }
 
 // ReadMessages reads messages from the connection.
-func (t *TCP) ReadMessages(conn net.Conn, connData *ConnData) {
-	// connString := getConnString(conn)
+// The closed connection is unregistered before the disconnection is notified.
+// The data read while the TCP is shutting down is dropped, the device sends it again as it is not acknowledged.
+func (t *TCP) ReadMessages(conn transport.Conn, connData *ConnData) {
 	buf := make([]byte, hl7BufferSize) // Allocate buffer once
 
 	defer func() {
 		conn.Close()
-		// delete(t.Conns, connData.connString)
+		t.removeConn(connData)
+		if t.OnDisconnect != nil {
+			t.OnDisconnect(connData)
+		}
 		t.Log.Info(fmt.Sprintf("connection from %s closed", connData.ConnString))
+		t.readers.Done()
 	}()
 
 	for {
 		connData.Wg.Wait()
 		n, err := conn.Read(buf)
 		if err != nil {
-			// if err == net.ErrClosed || err == io.EOF || err.Error() == "EOF" {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				connData.Err = err
+			}
 			t.Log.Err(err, fmt.Sprintf("connection from %s closed", connData.ConnString))
-			// t.Log.Info(fmt.Sprintf("connection from %s closed", connString))
 			return
-			// }
-
-			// t.Log.Err(err, "failed to read from connection")
-			// continue
 		}
 
 		rcvData := RcvData{
@@ -118,13 +338,38 @@ func (t *TCP) ReadMessages(conn net.Conn, connData *ConnData) {
 			Data:       buf[:n],
 			Wg:         connData.Wg,
 		}
-		connData.Wg.Add(1)
+		if !t.startMessage(connData) {
+			t.Log.Warn(fmt.Sprintf("dropped a message from %s, the server is stopping", connData.ConnString))
+			return
+		}
 		t.RcvChannel <- rcvData
 	}
 }
 
+// startMessage counts the message of the connection in progress, it returns false when the TCP is shutting down.
+// The count is taken under the lock, so Shutdown waits for every message started before it.
+func (t *TCP) startMessage(connData *ConnData) bool {
+	t.connsMu.RLock()
+	defer t.connsMu.RUnlock()
+
+	if t.stopping {
+		return false
+	}
+	connData.Wg.Add(1)
+
+	return true
+}
+
 // getConnString gets the connection string.
-func getConnString(conn net.Conn) string {
-	ip := conn.RemoteAddr().(*net.TCPAddr).IP.String()
-	return ip
+// The remote port is part of it, so several devices behind the same IP get their own connections.
+// A serial port is named by its path.
+func getConnString(conn transport.Conn) string {
+	if netConn, ok := conn.(net.Conn); ok {
+		return netConn.RemoteAddr().String()
+	}
+	if file, ok := conn.(interface{ Name() string }); ok {
+		return file.Name()
+	}
+
+	return fmt.Sprintf("%p", conn)
 }
@@ -0,0 +1,137 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/voidmaindev/doctra_lis_middleware/log"
+)
+
+// newTestTCP creates a TCP accepting the connections on a loopback port and a client connected to it.
+func newTestTCP(t *testing.T) (*TCP, net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	tcp := NewTCP(&log.Logger{Disabled: true}, listener)
+	go tcp.AcceptConnections()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return tcp, client
+}
+
+// receive sends the message from the client and receives it from the TCP, the message stays in progress until its Wg is done.
+func receive(t *testing.T, tcp *TCP, client net.Conn, message string) RcvData {
+	t.Helper()
+
+	_, err := client.Write([]byte(message))
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	select {
+	case rcv := <-tcp.RcvChannel:
+		if string(rcv.Data) != message {
+			t.Fatalf("received %q, want %q", rcv.Data, message)
+		}
+		return rcv
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the message %q was not received", message)
+	}
+
+	return RcvData{}
+}
+
+// shutdown runs Shutdown in the background and returns its error when it finishes.
+func shutdown(ctx context.Context, tcp *TCP) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- tcp.Shutdown(ctx)
+	}()
+
+	return done
+}
+
+func TestShutdownWaitsForMessageInProgress(t *testing.T) {
+	tcp, client := newTestTCP(t)
+	rcv := receive(t, tcp, client, "message 1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := shutdown(ctx, tcp)
+
+	// the data sent while stopping is read after the message in progress and dropped
+	_, err := client.Write([]byte("message 2"))
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown() = %v before the message in progress was processed", err)
+	case data, ok := <-tcp.RcvChannel:
+		t.Fatalf("received %q (open %v) before the message in progress was processed", data.Data, ok)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	rcv.Wg.Done()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown() did not finish after the message in progress was processed")
+	}
+
+	data, ok := <-tcp.RcvChannel
+	if ok {
+		t.Errorf("received %q after Shutdown(), want the channel closed", data.Data)
+	}
+
+	// the connection is closed
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = client.Read(make([]byte, 1))
+	if err == nil {
+		t.Error("the client connection is still open after Shutdown()")
+	}
+}
+
+func TestShutdownContextDone(t *testing.T) {
+	tcp, client := newTestTCP(t)
+	rcv := receive(t, tcp, client, "message 1")
+	defer rcv.Wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := tcp.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want the error of the context", err)
+	}
+
+	// the message in progress may still be processed, the channel is left open
+	select {
+	case data, ok := <-tcp.RcvChannel:
+		t.Errorf("received %q (open %v), want the channel left open without messages", data.Data, ok)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// a second shutdown does nothing
+	err = tcp.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("second Shutdown() error = %v, want nil", err)
+	}
+}
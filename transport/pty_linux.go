@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenPTY opens a pseudo-terminal pair and returns its master side and the path of its slave side.
+// The slave path can be opened with OpenSerial like a real serial port, which allows running
+// a serial device without hardware, e.g. with the simulator writing to the master side.
+func OpenPTY() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open a pseudo-terminal: %v", err)
+	}
+
+	rawConn, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+
+	var ptyNumber int
+	var ctrlErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ctrlErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0)
+		if ctrlErr != nil {
+			return
+		}
+		ptyNumber, ctrlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPTN)
+	})
+	if err == nil {
+		err = ctrlErr
+	}
+	if err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("failed to unlock the pseudo-terminal: %v", err)
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNumber), nil
+}
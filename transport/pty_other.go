@@ -0,0 +1,13 @@
+//go:build !linux
+
+package transport
+
+import (
+	"errors"
+	"os"
+)
+
+// OpenPTY reports that the pseudo-terminals are not supported on the platform.
+func OpenPTY() (*os.File, string, error) {
+	return nil, "", errors.New("pseudo-terminals are supported on linux only")
+}
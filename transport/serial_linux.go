@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// baudRates maps the baud rates to the termios speeds.
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// OpenSerial opens the serial port in raw mode with the settings of the config.
+// The port is opened non-blocking, so closing it interrupts a pending read.
+func OpenSerial(config SerialConfig) (Conn, error) {
+	config = config.withDefaults()
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	speed, ok := baudRates[config.BaudRate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate: %v", config.BaudRate)
+	}
+
+	fd, err := unix.Open(config.Port, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %v", config.Port, err)
+	}
+
+	err = setTermios(fd, config, speed)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to configure serial port %s: %v", config.Port, err)
+	}
+
+	return os.NewFile(uintptr(fd), config.Port), nil
+}
+
+// setTermios sets the raw mode and the line settings of the port.
+func setTermios(fd int, config SerialConfig, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	configureTermios(t, config, speed)
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+// configureTermios sets the raw mode and the line settings of the config in the termios.
+func configureTermios(t *unix.Termios, config SerialConfig, speed uint32) {
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL |
+		unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CLOCAL | unix.CREAD | speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+
+	switch config.DataBits {
+	case 5:
+		t.Cflag |= unix.CS5
+	case 6:
+		t.Cflag |= unix.CS6
+	case 7:
+		t.Cflag |= unix.CS7
+	default:
+		t.Cflag |= unix.CS8
+	}
+
+	switch config.Parity {
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+		t.Iflag |= unix.INPCK
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+		t.Iflag |= unix.INPCK
+	}
+
+	if config.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+
+	switch config.FlowControl {
+	case FlowControlRTSCTS:
+		t.Cflag |= unix.CRTSCTS
+	case FlowControlXONXOFF:
+		t.Iflag |= unix.IXON | unix.IXOFF
+	}
+
+	// a read returns as soon as a byte is available
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+}
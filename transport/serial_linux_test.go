@@ -0,0 +1,261 @@
+package transport
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTestSerial opens the slave side of a pseudo-terminal with OpenSerial and returns the master side too.
+func openTestSerial(t *testing.T, config SerialConfig) (*os.File, *os.File) {
+	t.Helper()
+
+	master, slavePath, err := OpenPTY()
+	if err != nil {
+		t.Skipf("pseudo-terminals are not available: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	config.Port = slavePath
+	conn, err := OpenSerial(config)
+	if err != nil {
+		t.Fatalf("OpenSerial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	port, ok := conn.(*os.File)
+	if !ok {
+		t.Fatalf("OpenSerial() = %T, want *os.File", conn)
+	}
+
+	return master, port
+}
+
+// getTermios returns the termios of the port.
+func getTermios(t *testing.T, port *os.File) *unix.Termios {
+	t.Helper()
+
+	rawConn, err := port.SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn() error = %v", err)
+	}
+
+	var termios *unix.Termios
+	var termiosErr error
+	err = rawConn.Control(func(fd uintptr) {
+		termios, termiosErr = unix.IoctlGetTermios(int(fd), unix.TCGETS)
+	})
+	if err == nil {
+		err = termiosErr
+	}
+	if err != nil {
+		t.Fatalf("IoctlGetTermios() error = %v", err)
+	}
+
+	return termios
+}
+
+func TestConfigureTermios(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SerialConfig
+		speed  uint32
+		cflag  uint32
+		iflag  uint32
+	}{
+		{
+			name:   "9600 8N1",
+			config: SerialConfig{},
+			speed:  unix.B9600,
+			cflag:  unix.CS8,
+		},
+		{
+			name:   "19200 7E2",
+			config: SerialConfig{BaudRate: 19200, DataBits: 7, Parity: ParityEven, StopBits: 2},
+			speed:  unix.B19200,
+			cflag:  unix.CS7 | unix.PARENB | unix.CSTOPB,
+			iflag:  unix.INPCK,
+		},
+		{
+			name:   "115200 8O1 with RTS/CTS",
+			config: SerialConfig{BaudRate: 115200, Parity: ParityOdd, FlowControl: FlowControlRTSCTS},
+			speed:  unix.B115200,
+			cflag:  unix.CS8 | unix.PARENB | unix.PARODD | unix.CRTSCTS,
+			iflag:  unix.INPCK,
+		},
+		{
+			name:   "4800 5N1 with XON/XOFF",
+			config: SerialConfig{BaudRate: 4800, DataBits: 5, FlowControl: FlowControlXONXOFF},
+			speed:  unix.B4800,
+			cflag:  unix.CS5,
+			iflag:  unix.IXON | unix.IXOFF,
+		},
+	}
+
+	lineFlags := uint32(unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS)
+	inputFlags := uint32(unix.INPCK | unix.IXON | unix.IXOFF | unix.IXANY | unix.ICRNL)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// a cooked terminal with the opposite settings, they must all be replaced
+			termios := &unix.Termios{
+				Iflag: inputFlags,
+				Oflag: unix.OPOST,
+				Lflag: unix.ICANON | unix.ECHO | unix.ISIG,
+				Cflag: lineFlags | unix.B300,
+			}
+
+			configureTermios(termios, test.config.withDefaults(), test.speed)
+
+			if speed := termios.Cflag & unix.CBAUD; speed != test.speed || termios.Ispeed != test.speed || termios.Ospeed != test.speed {
+				t.Errorf("speed = %#o, %#o, %#o, want %#o", speed, termios.Ispeed, termios.Ospeed, test.speed)
+			}
+			if cflag := termios.Cflag & lineFlags; cflag != test.cflag {
+				t.Errorf("line flags = %#o, want %#o", cflag, test.cflag)
+			}
+			if iflag := termios.Iflag & inputFlags; iflag != test.iflag {
+				t.Errorf("input flags = %#o, want %#o", iflag, test.iflag)
+			}
+			if termios.Cflag&(unix.CLOCAL|unix.CREAD) != unix.CLOCAL|unix.CREAD {
+				t.Errorf("cflag = %#o, want CLOCAL and CREAD", termios.Cflag)
+			}
+			if termios.Lflag != 0 || termios.Oflag != 0 {
+				t.Errorf("termios is not raw: lflag = %#o, oflag = %#o", termios.Lflag, termios.Oflag)
+			}
+			if termios.Cc[unix.VMIN] != 1 || termios.Cc[unix.VTIME] != 0 {
+				t.Errorf("VMIN, VTIME = %d, %d, want 1, 0", termios.Cc[unix.VMIN], termios.Cc[unix.VTIME])
+			}
+		})
+	}
+}
+
+// TestOpenSerialSettings checks the settings applied to a pseudo-terminal.
+// The pseudo-terminals of recent kernels force CS8 without parity, those are checked by TestConfigureTermios.
+func TestOpenSerialSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SerialConfig
+		speed   uint32
+		stopBit uint32
+	}{
+		{
+			name:   "defaults 9600 8N1",
+			config: SerialConfig{},
+			speed:  unix.B9600,
+		},
+		{
+			name:    "19200 7E2",
+			config:  SerialConfig{BaudRate: 19200, DataBits: 7, Parity: ParityEven, StopBits: 2},
+			speed:   unix.B19200,
+			stopBit: unix.CSTOPB,
+		},
+		{
+			name:   "115200 8O1",
+			config: SerialConfig{BaudRate: 115200, Parity: ParityOdd},
+			speed:  unix.B115200,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, port := openTestSerial(t, test.config)
+			termios := getTermios(t, port)
+
+			if speed := termios.Cflag & unix.CBAUD; speed != test.speed {
+				t.Errorf("speed = %#o, want %#o", speed, test.speed)
+			}
+			if stopBit := termios.Cflag & unix.CSTOPB; stopBit != test.stopBit {
+				t.Errorf("stop bits = %#o, want %#o", stopBit, test.stopBit)
+			}
+			if termios.Lflag&(unix.ICANON|unix.ECHO) != 0 || termios.Oflag&unix.OPOST != 0 {
+				t.Errorf("termios is not raw: lflag = %#o, oflag = %#o", termios.Lflag, termios.Oflag)
+			}
+		})
+	}
+}
+
+func TestOpenSerialInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SerialConfig
+	}{
+		{name: "no port", config: SerialConfig{}},
+		{name: "unsupported baud rate", config: SerialConfig{Port: "/dev/null", BaudRate: 1234}},
+		{name: "invalid stop bits", config: SerialConfig{Port: "/dev/null", StopBits: 3}},
+		{name: "invalid parity", config: SerialConfig{Port: "/dev/null", Parity: "mark"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, err := OpenSerial(test.config)
+			if err == nil {
+				conn.Close()
+				t.Fatal("OpenSerial() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestOpenSerialRoundTrip(t *testing.T) {
+	master, port := openTestSerial(t, SerialConfig{})
+
+	// the raw mode passes the control characters of the protocols through unchanged
+	fromDevice := []byte("\x05\x021H|\\^&\r\x0317\r\n\x04")
+	_, err := master.Write(fromDevice)
+	if err != nil {
+		t.Fatalf("master Write() error = %v", err)
+	}
+
+	received := make([]byte, len(fromDevice))
+	err = port.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	_, err = io.ReadFull(port, received)
+	if err != nil {
+		t.Fatalf("port Read() error = %v", err)
+	}
+	if string(received) != string(fromDevice) {
+		t.Errorf("port received %q, want %q", received, fromDevice)
+	}
+
+	toDevice := []byte("\x06\x15\r\n")
+	_, err = port.Write(toDevice)
+	if err != nil {
+		t.Fatalf("port Write() error = %v", err)
+	}
+
+	received = make([]byte, len(toDevice))
+	_, err = io.ReadFull(master, received)
+	if err != nil {
+		t.Fatalf("master Read() error = %v", err)
+	}
+	if string(received) != string(toDevice) {
+		t.Errorf("master received %q, want %q", received, toDevice)
+	}
+}
+
+func TestOpenSerialCloseInterruptsRead(t *testing.T) {
+	_, port := openTestSerial(t, SerialConfig{})
+
+	done := make(chan error)
+	go func() {
+		_, err := port.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	port.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Read() error = nil after Close()")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not interrupt the pending Read()")
+	}
+}
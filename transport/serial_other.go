@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package transport
+
+import "errors"
+
+// OpenSerial reports that the serial ports are not supported on the platform.
+func OpenSerial(config SerialConfig) (Conn, error) {
+	return nil, errors.New("serial ports are supported on linux and windows only")
+}
@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DCB flags, see https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-dcb.
+const (
+	dcbBinary       = 0x00000001
+	dcbParity       = 0x00000002
+	dcbOutxCtsFlow  = 0x00000004
+	dcbOutX         = 0x00000100
+	dcbInX          = 0x00000200
+	dcbDtrControlOn = windows.DTR_CONTROL_ENABLE
+)
+
+// serialPort is the serial port opened with the Windows API.
+type serialPort struct {
+	handle windows.Handle
+	name   string
+}
+
+// OpenSerial opens the serial port with the settings of the config.
+func OpenSerial(config SerialConfig) (Conn, error) {
+	config = config.withDefaults()
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.Port
+	if !strings.HasPrefix(name, `\\.\`) {
+		name = `\\.\` + name
+	}
+
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := windows.CreateFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %v", config.Port, err)
+	}
+
+	err = setCommState(handle, config)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("failed to configure serial port %s: %v", config.Port, err)
+	}
+
+	return &serialPort{handle: handle, name: config.Port}, nil
+}
+
+// setCommState sets the line settings and the timeouts of the port.
+func setCommState(handle windows.Handle, config SerialConfig) error {
+	dcb := &windows.DCB{}
+	dcb.DCBlength = uint32(unsafe.Sizeof(*dcb))
+	err := windows.GetCommState(handle, dcb)
+	if err != nil {
+		return err
+	}
+
+	dcb.BaudRate = uint32(config.BaudRate)
+	dcb.ByteSize = uint8(config.DataBits)
+	dcb.Flags = dcbBinary | dcbDtrControlOn | windows.RTS_CONTROL_ENABLE
+
+	switch config.Parity {
+	case ParityOdd:
+		dcb.Parity = windows.ODDPARITY
+		dcb.Flags |= dcbParity
+	case ParityEven:
+		dcb.Parity = windows.EVENPARITY
+		dcb.Flags |= dcbParity
+	default:
+		dcb.Parity = windows.NOPARITY
+	}
+
+	dcb.StopBits = windows.ONESTOPBIT
+	if config.StopBits == 2 {
+		dcb.StopBits = windows.TWOSTOPBITS
+	}
+
+	switch config.FlowControl {
+	case FlowControlRTSCTS:
+		dcb.Flags = dcb.Flags&^windows.RTS_CONTROL_ENABLE | windows.RTS_CONTROL_HANDSHAKE | dcbOutxCtsFlow
+	case FlowControlXONXOFF:
+		dcb.Flags |= dcbOutX | dcbInX
+		dcb.XonChar = 0x11
+		dcb.XoffChar = 0x13
+	}
+
+	err = windows.SetCommState(handle, dcb)
+	if err != nil {
+		return err
+	}
+
+	// a read returns as soon as a byte is available and otherwise waits as long as possible
+	return windows.SetCommTimeouts(handle, &windows.CommTimeouts{
+		ReadIntervalTimeout:        windows.INFINITE,
+		ReadTotalTimeoutMultiplier: windows.INFINITE,
+		ReadTotalTimeoutConstant:   windows.INFINITE - 1,
+	})
+}
+
+// Read reads from the port, waiting until at least one byte is received.
+func (p *serialPort) Read(b []byte) (int, error) {
+	for {
+		var n uint32
+		err := windows.ReadFile(p.handle, b, &n, nil)
+		if err != nil {
+			return 0, err
+		}
+		if n > 0 || len(b) == 0 {
+			return int(n), nil
+		}
+	}
+}
+
+// Write writes to the port.
+func (p *serialPort) Write(b []byte) (int, error) {
+	var n uint32
+	err := windows.WriteFile(p.handle, b, &n, nil)
+
+	return int(n), err
+}
+
+// Name returns the name of the port.
+func (p *serialPort) Name() string {
+	return p.name
+}
+
+// Close cancels the pending reads and closes the port.
+func (p *serialPort) Close() error {
+	windows.CancelIoEx(p.handle, nil)
+
+	return windows.CloseHandle(p.handle)
+}
@@ -0,0 +1,98 @@
+// Package transport provides the connections to the devices the drivers read from and write to.
+package transport
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Conn is the connection to a device, a TCP connection or a serial port.
+type Conn interface {
+	io.ReadWriteCloser
+}
+
+// Parities of the serial port.
+const (
+	ParityNone = "none"
+	ParityOdd  = "odd"
+	ParityEven = "even"
+)
+
+// Flow controls of the serial port.
+const (
+	FlowControlNone    = "none"
+	FlowControlRTSCTS  = "rtscts"
+	FlowControlXONXOFF = "xonxoff"
+)
+
+// Default settings of the serial port, 9600 8N1 without flow control.
+const (
+	defaultBaudRate = 9600
+	defaultDataBits = 8
+	defaultStopBits = 1
+)
+
+// SerialConfig is the configuration of a serial port.
+// Port is the device path on Linux ("/dev/ttyS0", "/dev/ttyUSB0") and the port name on Windows ("COM3").
+type SerialConfig struct {
+	Port        string
+	BaudRate    int
+	DataBits    int
+	Parity      string
+	StopBits    int
+	FlowControl string
+}
+
+// withDefaults returns the configuration with the unset settings replaced by the defaults.
+func (c SerialConfig) withDefaults() SerialConfig {
+	if c.BaudRate == 0 {
+		c.BaudRate = defaultBaudRate
+	}
+	if c.DataBits == 0 {
+		c.DataBits = defaultDataBits
+	}
+	if c.Parity == "" {
+		c.Parity = ParityNone
+	}
+	if c.StopBits == 0 {
+		c.StopBits = defaultStopBits
+	}
+	if c.FlowControl == "" {
+		c.FlowControl = FlowControlNone
+	}
+
+	return c
+}
+
+// validate checks the settings which do not depend on the platform.
+func (c SerialConfig) validate() error {
+	if c.Port == "" {
+		return fmt.Errorf("serial port is not set")
+	}
+	if c.DataBits < 5 || c.DataBits > 8 {
+		return fmt.Errorf("invalid data bits: %v", c.DataBits)
+	}
+	if c.StopBits != 1 && c.StopBits != 2 {
+		return fmt.Errorf("invalid stop bits: %v", c.StopBits)
+	}
+	switch c.Parity {
+	case ParityNone, ParityOdd, ParityEven:
+	default:
+		return fmt.Errorf("invalid parity: %v", c.Parity)
+	}
+	switch c.FlowControl {
+	case FlowControlNone, FlowControlRTSCTS, FlowControlXONXOFF:
+	default:
+		return fmt.Errorf("invalid flow control: %v", c.FlowControl)
+	}
+
+	return nil
+}
+
+// String returns the settings in the usual notation, e.g. "/dev/ttyS0 9600 8N1".
+func (c SerialConfig) String() string {
+	c = c.withDefaults()
+
+	return fmt.Sprintf("%s %d %d%s%d", c.Port, c.BaudRate, c.DataBits, strings.ToUpper(c.Parity[:1]), c.StopBits)
+}